@@ -6,6 +6,7 @@ import (
 	"gb-api/track/bigdata"
 	"gb-api/track/bigdata/bigbed"
 	"gb-api/track/bigdata/bigwig"
+	"gb-api/track/transcript"
 	"net/http"
 	"runtime"
 	"unsafe"
@@ -83,6 +84,9 @@ func CacheSizeHandler(w http.ResponseWriter, r *http.Request) {
 	)
 	stats = append(stats, bedHeaderStats)
 
+	// Annotations held in memory
+	stats = append(stats, calculateTranscriptIndexSize("transcript-index", includeKeys))
+
 	// Calculate totals
 	var totalKB int64
 	for _, stat := range stats {
@@ -119,6 +123,29 @@ func CacheSizeHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// calculateTranscriptIndexSize reports the annotations preloaded into memory,
+// one entry per annotation file
+func calculateTranscriptIndexSize(name string, includeKeys bool) CacheStats {
+	indexes := transcript.GetIndexStats()
+	stats := CacheStats{
+		Name:       name,
+		EntryCount: len(indexes),
+	}
+
+	var totalBytes int64
+	for _, idx := range indexes {
+		if includeKeys {
+			stats.Keys = append(stats.Keys, idx.Path)
+		}
+		totalBytes += idx.Bytes
+	}
+
+	stats.ApproxSizeKB = totalBytes / 1024
+	stats.ApproxSizeMB = stats.ApproxSizeKB / 1024
+
+	return stats
+}
+
 // calculateHeaderCacheSize calculates the exact memory size of a BigData header cache
 func calculateHeaderCacheSize(
 	name string,
@@ -47,16 +47,17 @@ func TestCacheSizeHandler(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(response.Caches) != 4 {
-		t.Errorf("Expected 4 cache entries, got %d", len(response.Caches))
+	if len(response.Caches) != 5 {
+		t.Errorf("Expected 5 cache entries, got %d", len(response.Caches))
 	}
 
 	// Verify cache names
 	expectedNames := map[string]bool{
-		"bigwig-data":    false,
-		"bigwig-headers": false,
-		"bigbed-data":    false,
-		"bigbed-headers": false,
+		"bigwig-data":      false,
+		"bigwig-headers":   false,
+		"bigbed-data":      false,
+		"bigbed-headers":   false,
+		"transcript-index": false,
 	}
 	for _, cache := range response.Caches {
 		if _, ok := expectedNames[cache.Name]; !ok {
@@ -3,12 +3,26 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"gb-api/track/bam"
+	"gb-api/track/bam/bamtest"
 	"gb-api/track/bigdata/bigbed"
+	"gb-api/track/bigdata/bigdatatest"
 	"gb-api/track/bigdata/bigwig"
+	"gb-api/track/hic"
+	"gb-api/track/hic/hictest"
+	"gb-api/track/liftover"
+	"gb-api/track/tabix"
+	"gb-api/track/tabix/tabixtest"
+	"gb-api/track/transcript"
+	"gb-api/track/vcf"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -91,3 +105,1106 @@ func TestBigBed(t *testing.T) {
 		t.Error(err.Error())
 	}
 }
+
+func TestBigWigHandlerOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"unknown normalization", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"normalization":"tpm"}`, "normalization"},
+		{"scale without factor", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"normalization":"scale"}`, "scaleFactor"},
+		{"unknown transform", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"transform":"log10"}`, "transform"},
+		{"clip below the median", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"clipPercentile":20}`, "clipPercentile"},
+		{"unknown smoothing", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"smoothing":"loess","smoothingWindow":50}`, "smoothing"},
+		{"smoothing without window", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"smoothing":"gaussian"}`, "smoothingWindow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, BigWigHandler, tt.body, tt.field)
+		})
+	}
+}
+
+func TestTrackHandlerMeta(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewBufferString(`{"query":"APOE"}`))
+	w := httptest.NewRecorder()
+	TrackHandler(w, req, slog.Default(), "test", func(req *SearchRequest) (any, error) {
+		normalization := bigwig.Normalization{Method: bigwig.NormCPM, Scale: 2.5}
+		return withMeta{data: []int{1}, meta: BigWigMeta{Normalization: &normalization}}, nil
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	want := `{"data":[1],"meta":{"normalization":{"method":"cpm","scale":2.5}}}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestCompositeHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"no urls", `{"urls":[],"operation":"sum","chrom":"chr1","start":0,"end":1000}`, "urls"},
+		{"unknown operation", `{"urls":["https://example.org/a.bw"],"operation":"median","chrom":"chr1","start":0,"end":1000}`, "operation"},
+		{"ratio of three", `{"urls":["https://example.org/a.bw","https://example.org/b.bw","https://example.org/c.bw"],"operation":"log2ratio","chrom":"chr1","start":0,"end":1000}`, "urls"},
+		{"negative pseudocount", `{"urls":["https://example.org/a.bw","https://example.org/b.bw"],"operation":"ratio","pseudocount":-1,"chrom":"chr1","start":0,"end":1000}`, "pseudocount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, CompositeHandler, tt.body, tt.field)
+		})
+	}
+}
+
+func TestSignalRegionsHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"no threshold", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000}`, "threshold"},
+		{"threshold and percentile", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"threshold":0,"percentile":95}`, "threshold"},
+		{"percentile of 100", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"percentile":100}`, "percentile"},
+		{"negative min length", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"threshold":2,"minLength":-1}`, "minLength"},
+		{"negative merge distance", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":1000,"threshold":2,"mergeDistance":-5}`, "mergeDistance"},
+		{"region too wide", `{"url":"https://example.org/a.bw","chrom":"chr1","start":0,"end":20000000,"threshold":2}`, "end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, SignalRegionsHandler, tt.body, tt.field)
+		})
+	}
+}
+
+func TestMatrixHandlerValidation(t *testing.T) {
+	features := `"features":[{"chrom":"chr1","start":100,"end":200,"strand":"-"}]`
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"no urls", `{"urls":[],` + features + `,"upstream":100}`, "urls"},
+		{"no features", `{"urls":["https://example.org/a.bw"],"upstream":100}`, "features"},
+		{"two sources", `{"urls":["https://example.org/a.bw"],` + features + `,"tss":true,"chrom":"chr1","start":0,"end":1000,"upstream":100}`, "features"},
+		{"bad feature", `{"urls":["https://example.org/a.bw"],"features":[{"chrom":"chr1","start":200,"end":100}],"upstream":100}`, "features"},
+		{"bad strand", `{"urls":["https://example.org/a.bw"],"features":[{"chrom":"chr1","start":100,"end":200,"strand":"x"}],"upstream":100}`, "features"},
+		{"bigbed without region", `{"urls":["https://example.org/a.bw"],"bigBedUrl":"https://example.org/a.bb","upstream":100}`, "chrom"},
+		{"unknown mode", `{"urls":["https://example.org/a.bw"],` + features + `,"mode":"profile","upstream":100}`, "mode"},
+		{"unknown reference point", `{"urls":["https://example.org/a.bw"],` + features + `,"referencePoint":"TSS","upstream":100}`, "referencePoint"},
+		{"no flanks", `{"urls":["https://example.org/a.bw"],` + features + `}`, "upstream"},
+		{"scale-regions without body", `{"urls":["https://example.org/a.bw"],` + features + `,"mode":"scale-regions","upstream":100}`, "bodyLength"},
+		{"flank off the bin grid", `{"urls":["https://example.org/a.bw"],` + features + `,"upstream":105}`, "binSize"},
+		{"too many bins", `{"urls":["https://example.org/a.bw"],` + features + `,"upstream":50000,"binSize":1}`, "binSize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, MatrixHandler, tt.body, tt.field)
+		})
+	}
+}
+
+func TestMatrixFeaturesTSS(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr1\tTEST\tgene\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; gene_type \"protein_coding\";",
+		"chr1\tTEST\ttranscript\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\";",
+		"chr1\tTEST\texon\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; exon_number \"1\";",
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	features, err := matrixFeatures(&MatrixRequest{TSS: true, Chrom: "chr1", Start: 0, End: 5000})
+	if err != nil {
+		t.Fatalf("matrixFeatures() error = %v", err)
+	}
+	want := bigwig.MatrixFeature{Chrom: "chr1", Start: 1999, End: 2000, Strand: "-", Name: "GENE1-201"}
+	if len(features) != 1 || features[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, features)
+	}
+}
+
+// assertValidationError posts body to handler and checks it is rejected
+// with an error on field
+func assertValidationError(t *testing.T, handler http.HandlerFunc, body, field string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"field":"`+field+`"`) {
+		t.Errorf("Expected an error on %s, got %s", field, w.Body.String())
+	}
+}
+
+func TestComparativeHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		field   string
+	}{
+		{"bigchain without url", BigChainHandler, `{"chrom":"chr1","start":0,"end":1000}`, "url"},
+		{"bigchain bad link url", BigChainHandler, `{"url":"https://example.org/a.bb","linkUrl":"links.bb","chrom":"chr1","start":0,"end":1000}`, "linkUrl"},
+		{"bigmaf bad region", BigMafHandler, `{"url":"https://example.org/a.bb","chrom":"chr1","start":1000,"end":1000}`, "end"},
+		{"bigmaf region too wide", BigMafHandler, `{"url":"https://example.org/a.bb","chrom":"chr1","start":0,"end":1000000}`, "end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidationError(t, tt.handler, tt.body, tt.field)
+		})
+	}
+}
+
+func TestTabixHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_DATA_DIR", dir)
+	tabixtest.WriteFile(t, dir, "signal.bedgraph.gz", nil, []string{
+		"chr19\t100\t200\t1.5",
+		"chr19\t200\t300\t2.5",
+	}, tabixtest.BED)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCount  int
+	}{
+		{
+			name:       "bedgraph from local path",
+			body:       `{"url":"signal.bedgraph.gz","chrom":"chr19","start":150,"end":250,"format":"bedgraph"}`,
+			wantStatus: http.StatusOK,
+			wantCount:  2,
+		},
+		{
+			name:       "path outside data directory",
+			body:       `{"url":"../signal.bedgraph.gz","chrom":"chr19","start":150,"end":250}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown format",
+			body:       `{"url":"signal.bedgraph.gz","chrom":"chr19","start":150,"end":250,"format":"wig"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tabix", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			TabixHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []tabix.TabixFeature `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.wantCount {
+				t.Fatalf("Expected %d features, got %d", tt.wantCount, len(response.Data))
+			}
+			if response.Data[0].Fields["value"] != 1.5 {
+				t.Errorf("Expected value 1.5, got %v", response.Data[0].Fields["value"])
+			}
+		})
+	}
+}
+
+func TestVCFHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_DATA_DIR", dir)
+	tabixtest.WriteFile(t, dir, "calls.vcf.gz", []string{
+		"##fileformat=VCFv4.2",
+		`##INFO=<ID=DP,Number=1,Type=Integer,Description="Total depth">`,
+		`##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">`,
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tNA1\tNA2",
+	}, []string{
+		"chr19\t101\trs1\tA\tG\t50\tPASS\tDP=20\tGT\t0|1\t1|1",
+	}, tabixtest.VCF)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantGT     string
+	}{
+		{
+			name:       "variants without genotypes",
+			body:       `{"url":"calls.vcf.gz","chrom":"chr19","start":0,"end":1000}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "genotypes for a sample subset",
+			body:       `{"url":"calls.vcf.gz","chrom":"chr19","start":0,"end":1000,"genotypes":true,"samples":["NA2"]}`,
+			wantStatus: http.StatusOK,
+			wantGT:     "1|1",
+		},
+		{
+			name:       "samples without genotypes",
+			body:       `{"url":"calls.vcf.gz","chrom":"chr19","start":0,"end":1000,"samples":["NA2"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/vcf", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			VCFHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data vcf.VCFData `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data.Variants) != 1 {
+				t.Fatalf("Expected 1 variant, got %d", len(response.Data.Variants))
+			}
+			variant := response.Data.Variants[0]
+			if variant.Ref != "A" || variant.Alt[0] != "G" {
+				t.Errorf("Unexpected alleles %s>%v", variant.Ref, variant.Alt)
+			}
+			if tt.wantGT == "" {
+				if len(variant.Genotypes) != 0 {
+					t.Errorf("Expected no genotypes, got %v", variant.Genotypes)
+				}
+				return
+			}
+			if len(variant.Genotypes) != 1 || variant.Genotypes[0].GT != tt.wantGT {
+				t.Errorf("Expected genotype %s, got %+v", tt.wantGT, variant.Genotypes)
+			}
+		})
+	}
+}
+
+func TestBAMHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_DATA_DIR", dir)
+	bamtest.WriteFile(t, dir, "reads.bam", []bamtest.Reference{{Name: "chr19", Length: 10_000_000}}, []bamtest.Alignment{
+		{Name: "r1", Chrom: "chr19", Pos: 100, Cigar: "50M", MapQ: 60},
+		{Name: "r2", Chrom: "chr19", Pos: 120, Cigar: "50M", MapQ: 60},
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantReads  int
+	}{
+		{
+			name:       "reads in a narrow window",
+			body:       `{"url":"reads.bam","chrom":"chr19","start":0,"end":1000}`,
+			wantStatus: http.StatusOK,
+			wantReads:  2,
+		},
+		{
+			name:       "coverage only",
+			body:       `{"url":"reads.bam","chrom":"chr19","start":0,"end":1000,"mode":"coverage","bins":10}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reads requested for a wide window",
+			body:       `{"url":"reads.bam","chrom":"chr19","start":0,"end":1000000,"mode":"reads"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown mode",
+			body:       `{"url":"reads.bam","chrom":"chr19","start":0,"end":1000,"mode":"squished"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/bam", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			BAMHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data bam.BAMData `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data.Reads) != tt.wantReads {
+				t.Errorf("Expected %d reads, got %d", tt.wantReads, len(response.Data.Reads))
+			}
+			if response.Data.TotalReads != 2 {
+				t.Errorf("Expected 2 total reads, got %d", response.Data.TotalReads)
+			}
+			if len(response.Data.Coverage) == 0 {
+				t.Error("Expected coverage bins")
+			}
+		})
+	}
+}
+
+func TestJunctionHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_DATA_DIR", dir)
+	bamtest.WriteFile(t, dir, "rna.bam", []bamtest.Reference{{Name: "chr19", Length: 10_000_000}}, []bamtest.Alignment{
+		{Name: "r1", Chrom: "chr19", Pos: 100, Cigar: "10M90N10M", MapQ: 60, XS: '+'},
+		{Name: "r2", Chrom: "chr19", Pos: 102, Cigar: "8M90N10M", MapQ: 60, XS: '+'},
+		{Name: "r3", Chrom: "chr19", Pos: 150, Cigar: "10M40N10M", MapQ: 60, XS: '-'},
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCount  int
+	}{
+		{
+			name:       "all junctions",
+			body:       `{"url":"rna.bam","chrom":"chr19","start":0,"end":1000}`,
+			wantStatus: http.StatusOK,
+			wantCount:  2,
+		},
+		{
+			name:       "minimum count",
+			body:       `{"url":"rna.bam","chrom":"chr19","start":0,"end":1000,"minCount":2}`,
+			wantStatus: http.StatusOK,
+			wantCount:  1,
+		},
+		{
+			name:       "negative minimum count",
+			body:       `{"url":"rna.bam","chrom":"chr19","start":0,"end":1000,"minCount":-1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown assembly",
+			body:       `{"url":"rna.bam","chrom":"chr19","start":0,"end":1000,"annotate":true,"assembly":"hg19"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/junctions", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			JunctionHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []bam.Junction `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.wantCount {
+				t.Fatalf("Expected %d junctions, got %d", tt.wantCount, len(response.Data))
+			}
+			if response.Data[0].Start != 110 || response.Data[0].End != 200 || response.Data[0].Count != 2 {
+				t.Errorf("Unexpected first junction %+v", response.Data[0])
+			}
+		})
+	}
+}
+
+func TestTranscriptHandlerAssembly(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(`{"chrom":"chr19","start":0,"end":1000,"assembly":"hg19"}`))
+	w := httptest.NewRecorder()
+
+	TranscriptHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	for _, assembly := range transcript.Assemblies() {
+		if !strings.Contains(w.Body.String(), assembly) {
+			t.Errorf("Expected the error to list %s, got %s", assembly, w.Body.String())
+		}
+	}
+}
+
+func TestTranscriptHandlerFilter(t *testing.T) {
+	exon := "chr1\tTEST\texon\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"%s\"; transcript_name \"%s\"; exon_number \"1\";"
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr1\tTEST\tgene\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; gene_type \"protein_coding\";",
+		"chr1\tTEST\ttranscript\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; tag \"basic\"; tag \"MANE_Select\";",
+		fmt.Sprintf(exon, "T1", "GENE1-201"),
+		"chr1\tTEST\ttranscript\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T2\"; transcript_name \"GENE1-202\"; tag \"basic\";",
+		fmt.Sprintf(exon, "T2", "GENE1-202"),
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRows   int
+	}{
+		{name: "unfiltered", body: `{"chrom":"chr1","start":0,"end":5000}`, wantStatus: http.StatusOK, wantRows: 2},
+		{name: "MANE only", body: `{"chrom":"chr1","start":0,"end":5000,"tags":["MANE_Select"]}`, wantStatus: http.StatusOK, wantRows: 1},
+		{name: "one per gene", body: `{"chrom":"chr1","start":0,"end":5000,"onePerGene":true}`, wantStatus: http.StatusOK, wantRows: 1},
+		{name: "collapsed", body: `{"chrom":"chr1","start":0,"end":5000,"mode":"collapsed"}`, wantStatus: http.StatusOK, wantRows: 1},
+		{name: "unknown mode", body: `{"chrom":"chr1","start":0,"end":5000,"mode":"squished"}`, wantStatus: http.StatusBadRequest},
+		{name: "other gene type", body: `{"chrom":"chr1","start":0,"end":5000,"geneTypes":["lncRNA"]}`, wantStatus: http.StatusOK, wantRows: 0},
+		{name: "invalid support level", body: `{"chrom":"chr1","start":0,"end":5000,"supportLevel":6}`, wantStatus: http.StatusBadRequest},
+		{name: "empty tag", body: `{"chrom":"chr1","start":0,"end":5000,"tags":[""]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			TranscriptHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Data transcript.LegacyDataWithLayout `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Data.TotalRows != tt.wantRows {
+				t.Errorf("Expected %d rows, got %d", tt.wantRows, response.Data.TotalRows)
+			}
+		})
+	}
+}
+
+func TestTranscriptHandlerVersion(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr1\tTEST\tgene\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; gene_type \"protein_coding\";",
+		"chr1\tTEST\ttranscript\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; transcript_type \"protein_coding\"; tag \"MANE_Select\";",
+		"chr1\tTEST\texon\t1001\t2000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; exon_number \"1\";",
+		"chr1\tTEST\tCDS\t1101\t1900\t.\t+\t0\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; exon_number \"1\";",
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"version":2}`))
+	w := httptest.NewRecorder()
+	TranscriptHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data transcript.TranscriptData `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.Version != transcript.VERSION_MODEL || len(response.Data.Genes) != 1 {
+		t.Fatalf("Expected one gene in a v2 response, got %+v", response.Data)
+	}
+	tx := response.Data.Genes[0].Transcripts[0]
+	if tx.Type != "protein_coding" || !tx.Canonical || tx.CDS == nil || tx.CDS.Start != 1100 || tx.CDS.End != 1900 {
+		t.Errorf("Unexpected transcript %+v", tx)
+	}
+	if strings.Contains(w.Body.String(), "__typename") {
+		t.Errorf("Expected no legacy fields in %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"version":3}`))
+	w = httptest.NewRecorder()
+	TranscriptHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown version, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTranscriptHandlerMaxRows(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 3; i++ {
+		gene := fmt.Sprintf("gene_id \"G%d\"; gene_name \"GENE%d\";", i, i)
+		tx := fmt.Sprintf("%s transcript_id \"T%d\"; transcript_name \"GENE%d-201\";", gene, i, i)
+		lines = append(lines,
+			"chr1\tTEST\tgene\t1001\t2000\t.\t+\t.\t"+gene,
+			"chr1\tTEST\ttranscript\t1001\t2000\t.\t+\t.\t"+tx,
+			"chr1\tTEST\texon\t1001\t2000\t.\t+\t.\t"+tx+" exon_number \"1\";",
+		)
+	}
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, lines, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"width":500,"charWidth":7,"maxRows":2}`))
+	w := httptest.NewRecorder()
+	TranscriptHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data transcript.LegacyDataWithLayout `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.TotalRows != 2 || response.Data.Hidden != 1 || len(response.Data.Overflow) != 1 || response.Data.Overflow[0] != 1 {
+		t.Errorf("Expected 2 rows and 1 hidden transcript, got totalRows=%d hidden=%d overflow=%v",
+			response.Data.TotalRows, response.Data.Hidden, response.Data.Overflow)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/transcript", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"charWidth":7}`))
+	w = httptest.NewRecorder()
+	TranscriptHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for charWidth without width, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestDerivedHandler(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr1\tTEST\tgene\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; gene_type \"protein_coding\";",
+		"chr1\tTEST\ttranscript\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\";",
+		"chr1\tTEST\texon\t1001\t2000\t.\t-\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; exon_number \"1\";",
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/derived", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"kind":"promoter","upstream":500,"downstream":100}`))
+	w := httptest.NewRecorder()
+	DerivedHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data []transcript.DerivedFeature `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// Minus strand: the TSS is the last base, 1999, and upstream lies to its right
+	if len(response.Data) != 1 || response.Data[0].Start != 1900 || response.Data[0].End != 2500 || response.Data[0].Name != "GENE1-201" {
+		t.Errorf("Expected one promoter at 1900-2500, got %+v", response.Data)
+	}
+
+	// Zero upstream keeps a one-sided window from the TSS
+	req = httptest.NewRequest(http.MethodPost, "/derived", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"kind":"promoter","upstream":0,"downstream":100}`))
+	w = httptest.NewRecorder()
+	DerivedHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	response.Data = nil
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Start != 1900 || response.Data[0].End != 2000 {
+		t.Errorf("Expected one promoter at 1900-2000, got %+v", response.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/derived", bytes.NewBufferString(`{"chrom":"chr1","start":0,"end":5000,"kind":"exon"}`))
+	w = httptest.NewRecorder()
+	DerivedHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown kind, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr19\tTEST\tgene\t44905754\t44909393\t.\t+\t.\tgene_id \"ENSG00000130203.10\"; gene_name \"APOE\"; gene_type \"protein_coding\";",
+		"chr19\tTEST\ttranscript\t44905754\t44909393\t.\t+\t.\tgene_id \"ENSG00000130203.10\"; gene_name \"APOE\"; transcript_id \"ENST00000252486.9\"; transcript_name \"APOE-201\"; tag \"MANE_Select\";",
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFirst  string
+	}{
+		{
+			name:       "gene name",
+			body:       `{"query":"apoe"}`,
+			wantStatus: http.StatusOK,
+			wantFirst:  "APOE",
+		},
+		{
+			name:       "version-less transcript ID",
+			body:       `{"query":"ENST00000252486","assembly":"test"}`,
+			wantStatus: http.StatusOK,
+			wantFirst:  "APOE-201",
+		},
+		{
+			name:       "missing query",
+			body:       `{"query":" "}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown assembly",
+			body:       `{"query":"APOE","assembly":"hg19"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			SearchHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []transcript.SearchResult `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) == 0 || response.Data[0].Name != tt.wantFirst {
+				t.Fatalf("Expected %s first, got %+v", tt.wantFirst, response.Data)
+			}
+			if r := response.Data[0]; r.Chrom != "chr19" || r.Start != 44905753 || r.End != 44909393 {
+				t.Errorf("Unexpected locus %+v", r)
+			}
+		})
+	}
+}
+
+func TestHiCHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_DATA_DIR", dir)
+	hictest.WriteFile(t, dir, "contacts.hic", hictest.File{
+		Version:          9,
+		Genome:           "hg38",
+		Chromosomes:      []hictest.Chromosome{{Name: "All", Length: 3000}, {Name: "chr1", Length: 2000}, {Name: "chr2", Length: 1000}},
+		Resolutions:      []int32{100},
+		BlockBinCount:    5,
+		BlockColumnCount: 4,
+		Matrices: []hictest.Matrix{
+			{Chr1: 1, Chr2: 1, Resolution: 100, Records: []hictest.Record{{BinX: 0, BinY: 0, Counts: 10}, {BinX: 1, BinY: 3, Counts: 4}}},
+			{Chr1: 1, Chr2: 2, Resolution: 100, Records: []hictest.Record{{BinX: 3, BinY: 1, Counts: 5}}},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, data hic.HiCData)
+	}{
+		{
+			name:       "intrachromosomal records",
+			body:       `{"url":"contacts.hic","chrom":"chr1","start":0,"end":500}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, data hic.HiCData) {
+				if data.Resolution != 100 || len(data.Contacts) != 2 {
+					t.Errorf("Unexpected contacts %+v", data)
+				}
+			},
+		},
+		{
+			name:       "interchromosomal matrix",
+			body:       `{"url":"contacts.hic","chrom":"chr2","start":0,"end":200,"chrom2":"chr1","start2":0,"end2":500,"format":"matrix"}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, data hic.HiCData) {
+				if data.Matrix == nil || data.Matrix.Rows != 2 || data.Matrix.Cols != 5 || data.Matrix.Values[1][3] != 5 {
+					t.Errorf("Unexpected matrix %+v", data.Matrix)
+				}
+			},
+		},
+		{
+			name:       "unavailable resolution",
+			body:       `{"url":"contacts.hic","chrom":"chr1","start":0,"end":500,"resolution":5000}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "unknown normalization",
+			body:       `{"url":"contacts.hic","chrom":"chr1","start":0,"end":500,"normalization":"SCALE"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid second region",
+			body:       `{"url":"contacts.hic","chrom":"chr1","start":0,"end":500,"chrom2":"chr2","start2":100,"end2":100}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hic", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			HiCHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.check == nil {
+				return
+			}
+
+			var response struct {
+				Data hic.HiCData `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			tt.check(t, response.Data)
+		})
+	}
+}
+
+// configureTestChain registers a chain lifting chr1:0-5000 of hg19 to
+// chr1:10000-15000 of test
+func configureTestChain(t *testing.T) {
+	dir := t.TempDir()
+	chain := "chain 5000 chr1 249250621 + 0 5000 chr1 248956422 + 10000 15000 1\n5000\n"
+	if err := os.WriteFile(filepath.Join(dir, "hg19ToTest.over.chain"), []byte(chain), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { liftover.Configure("", nil) })
+	if err := liftover.Configure(dir, []liftover.Chain{{From: "hg19", To: "test", Path: "hg19ToTest.over.chain"}}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+}
+
+func TestLiftoverHandler(t *testing.T) {
+	configureTestChain(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, results []liftover.Result)
+	}{
+		{
+			name:       "positions and regions",
+			body:       `{"from":"hg19","to":"test","regions":[{"chrom":"chr1","start":100},{"chrom":"chr1","start":4900,"end":5100,"name":"edge"},{"chrom":"chr2","start":0,"end":10}]}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, results []liftover.Result) {
+				if len(results) != 3 {
+					t.Fatalf("Expected 3 results, got %d", len(results))
+				}
+				if r := results[0].Region; r == nil || r.Start != 10100 || r.End != 10101 {
+					t.Errorf("Expected the position at 10100, got %+v", results[0])
+				}
+				if results[1].Mapped || results[1].Reason != liftover.ReasonPartiallyDeleted || len(results[1].Unmapped) != 1 {
+					t.Errorf("Expected a partially deleted region, got %+v", results[1])
+				}
+				if results[2].Mapped || results[2].Reason != liftover.ReasonDeleted {
+					t.Errorf("Expected a deleted region, got %+v", results[2])
+				}
+			},
+		},
+		{
+			name:       "lower minimum match",
+			body:       `{"from":"hg19","to":"test","minMatch":0.5,"regions":[{"chrom":"chr1","start":4900,"end":5100}]}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, results []liftover.Result) {
+				if r := results[0].Region; r == nil || r.Start != 14900 || r.End != 15000 {
+					t.Errorf("Expected the region at 14900-15000, got %+v", results[0])
+				}
+			},
+		},
+		{
+			name:       "unknown chain",
+			body:       `{"from":"test","to":"hg19","regions":[{"chrom":"chr1","start":100}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "end before start",
+			body:       `{"from":"hg19","to":"test","regions":[{"chrom":"chr1","start":100,"end":50}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no regions",
+			body:       `{"from":"hg19","to":"test","regions":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/liftover", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			LiftoverHandler(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.check == nil {
+				return
+			}
+			var response struct {
+				Data []liftover.Result `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			tt.check(t, response.Data)
+		})
+	}
+}
+
+// browserTrack posts a browser request for one track over chr1:0-1000 and
+// returns its response
+func browserTrack(t *testing.T, track string) TrackResponse {
+	t.Helper()
+	body := `{"chrom":"chr1","start":0,"end":1000,"tracks":[` + track + `]}`
+	req := httptest.NewRequest(http.MethodPost, "/browser", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	BrowserHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response BrowserResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 {
+		t.Fatalf("Expected one track, got %+v", response.Data)
+	}
+	return response.Data[0]
+}
+
+// serveBigData writes the files of the browser track tests and serves them
+// over HTTP
+func serveBigData(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	chroms := []bigdatatest.Chrom{{Name: "chr1", Size: 100000}}
+	bigdatatest.WriteBigWig(t, dir, "a.bw", chroms, []bigdatatest.Record{
+		{Chrom: "chr1", Start: 100, End: 200, Value: 5},
+		{Chrom: "chr1", Start: 900, End: 1200, Value: 8},
+	})
+	bigdatatest.WriteBigWig(t, dir, "b.bw", chroms, []bigdatatest.Record{
+		{Chrom: "chr1", Start: 100, End: 200, Value: 1},
+	})
+	bigdatatest.WriteBigBed(t, dir, "chain.bb", chroms, []bigdatatest.Record{
+		{Chrom: "chr1", Start: 100, End: 500, Rest: "7\t1000\t-\t248956422\tchr2\t242193529\t200\t600\t35000.5"},
+	}, bigbed.BIGCHAIN_AS)
+	bigdatatest.WriteBigBed(t, dir, "maf.bb", chroms, []bigdatatest.Record{
+		{Chrom: "chr1", Start: 100, End: 110, Rest: "a score=2500.0;s hg38.chr1 100 10 + 248956422 ACGT--ACGTAC;s mm10.chr4 50 11 - 156508116 ACGTTTAC-TAC;"},
+	}, bigbed.BIGMAF_AS)
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestBrowserHandlerBigDataTracks(t *testing.T) {
+	url := serveBigData(t)
+	tests := []struct {
+		name  string
+		track string
+		data  string // Expected data as JSON
+		meta  string // Expected meta, null when there is none
+	}{
+		{
+			"composite",
+			`{"id":"c","type":"composite","config":{"urls":["` + url + `/a.bw","` + url + `/b.bw"],"operation":"sum"}}`,
+			`[{"chr":"chr1","start":100,"end":200,"value":6},{"chr":"chr1","start":900,"end":1000,"value":8}]`,
+			`null`,
+		},
+		{
+			"signalregions",
+			`{"id":"s","type":"signalregions","config":{"url":"` + url + `/a.bw","threshold":6}}`,
+			`[{"chr":"chr1","start":900,"end":1200,"max":8,"mean":8}]`,
+			`{"threshold":6}`,
+		},
+		{
+			"bigchain",
+			`{"id":"ch","type":"bigchain","config":{"url":"` + url + `/chain.bb"}}`,
+			`[{"chr":"chr1","start":100,"end":500,"name":"7","score":1000,"strand":"-","qName":"chr2","qSize":242193529,"qStart":200,"qEnd":600,"chainScore":35000.5}]`,
+			`null`,
+		},
+		{
+			"bigmaf",
+			`{"id":"m","type":"bigmaf","config":{"url":"` + url + `/maf.bb","species":["rn6"]}}`,
+			`[{"chr":"chr1","start":100,"end":110,"score":2500,"sequences":[{"species":"hg38","chrom":"chr1","start":100,"size":10,"strand":"+","srcSize":248956422,"text":"ACGT--ACGTAC"}]}]`,
+			`null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := browserTrack(t, tt.track)
+			if track.Error != "" {
+				t.Fatalf("Unexpected error %s", track.Error)
+			}
+			if data := compactJSON(t, track.Data); data != compactJSON(t, json.RawMessage(tt.data)) {
+				t.Errorf("Expected data %s, got %s", tt.data, data)
+			}
+			if meta := compactJSON(t, track.Meta); meta != compactJSON(t, json.RawMessage(tt.meta)) {
+				t.Errorf("Expected meta %s, got %s", tt.meta, meta)
+			}
+		})
+	}
+}
+
+// compactJSON encodes v with object keys sorted, as decoded responses have them
+func compactJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode %v: %v", v, err)
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Failed to decode %s: %v", b, err)
+	}
+	b, _ = json.Marshal(decoded)
+	return string(b)
+}
+
+func TestBrowserHandlerBigDataTrackErrors(t *testing.T) {
+	url := serveBigData(t)
+	tests := []struct {
+		name  string
+		track string
+		want  string
+	}{
+		{"bigwig bad url", `{"id":"w","type":"bigwig","config":{"url":"not a url"}}`, "Invalid BigWig config, invalid url"},
+		{"invalid composite", `{"id":"c","type":"composite","config":{"urls":[],"operation":"sum"}}`, "Invalid Composite config"},
+		{"composite read failure", `{"id":"c","type":"composite","config":{"urls":["` + url + `/missing.bw"],"operation":"sum"}}`, "Failed to create"},
+		{"invalid signalregions", `{"id":"s","type":"signalregions","config":{"url":"` + url + `/a.bw"}}`, "Invalid SignalRegions config"},
+		{"signalregions bad url", `{"id":"s","type":"signalregions","config":{"url":"not a url","threshold":1}}`, "Invalid SignalRegions config, invalid url"},
+		{"signalregions read failure", `{"id":"s","type":"signalregions","config":{"url":"` + url + `/missing.bw","threshold":1}}`, "Failed to create"},
+		{"invalid bigchain", `{"id":"ch","type":"bigchain","config":{"url":""}}`, "Invalid BigChain config"},
+		{"bigchain read failure", `{"id":"ch","type":"bigchain","config":{"url":"` + url + `/missing.bb"}}`, "Failed to create"},
+		{"invalid bigmaf", `{"id":"m","type":"bigmaf","config":{"url":""}}`, "Invalid BigMaf config"},
+		{"bigmaf bad url", `{"id":"m","type":"bigmaf","config":{"url":"not a url"}}`, "Invalid BigMaf config, invalid url"},
+		{"bigmaf read failure", `{"id":"m","type":"bigmaf","config":{"url":"` + url + `/missing.bb"}}`, "Failed to create"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := browserTrack(t, tt.track)
+			if !strings.Contains(track.Error, tt.want) {
+				t.Errorf("Expected an error containing %q, got %+v", tt.want, track)
+			}
+		})
+	}
+}
+
+func TestBrowserHandlerBigBedErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		track string
+		want  string
+	}{
+		{"invalid config", `{"id":"bb","type":"bigbed","config":{"url":"","maxRows":-5}}`, "Invalid BigBed config"},
+		{"invalid url", `{"id":"bb","type":"bigbed","config":{"url":"not a url"}}`, "Invalid BigBed config, invalid url"},
+		{"read failure", `{"id":"bb","type":"bigbed","config":{"url":"http://127.0.0.1:1/missing.bb"}}`, "missing.bb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := browserTrack(t, tt.track)
+			if !strings.Contains(track.Error, tt.want) {
+				t.Errorf("Expected an error containing %q, got %+v", tt.want, track)
+			}
+		})
+	}
+}
+
+func TestBrowserHandlerLiftover(t *testing.T) {
+	configureTestChain(t)
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, []string{
+		"chr1\tTEST\tgene\t11001\t12000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; gene_type \"protein_coding\";",
+		"chr1\tTEST\ttranscript\t11001\t12000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\";",
+		"chr1\tTEST\texon\t11001\t12000\t.\t+\t.\tgene_id \"G1\"; gene_name \"GENE1\"; transcript_id \"T1\"; transcript_name \"GENE1-201\"; exon_number \"1\";",
+	}, tabixtest.GFF)
+	t.Cleanup(func() {
+		transcript.Configure(transcript.DEFAULT_DATA_DIR, transcript.DefaultAnnotations, transcript.DEFAULT_ASSEMBLY)
+	})
+	if err := transcript.Configure("", []transcript.Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	body := `{"chrom":"chr1","start":500,"end":2500,"assembly":"hg19","trackAssembly":"test","tracks":[{"id":"tss","type":"derived","config":{"assembly":"test","kind":"tss"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/browser", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	BrowserHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data []struct {
+			Data  []transcript.DerivedFeature `json:"data"`
+			Error string                      `json:"error"`
+		} `json:"data"`
+		Liftover *liftover.Result `json:"liftover"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Liftover == nil || response.Liftover.Region.Start != 10500 || response.Liftover.Region.End != 12500 {
+		t.Errorf("Expected the region lifted to 10500-12500, got %+v", response.Liftover)
+	}
+	if len(response.Data) != 1 || len(response.Data[0].Data) != 1 || response.Data[0].Data[0].Start != 11000 {
+		t.Errorf("Expected one TSS at 11000 in the lifted region, got %+v", response.Data)
+	}
+
+	// Regions outside the chain cannot be lifted
+	body = `{"chrom":"chr1","start":6000,"end":7000,"assembly":"hg19","trackAssembly":"test","tracks":[{"id":"tss","type":"derived","config":{"assembly":"test","kind":"tss"}}]}`
+	req = httptest.NewRequest(http.MethodPost, "/browser", bytes.NewBufferString(body))
+	w = httptest.NewRecorder()
+	BrowserHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a deleted region, got %d", http.StatusBadRequest, w.Code)
+	}
+}
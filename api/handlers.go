@@ -3,11 +3,18 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"gb-api/track/bam"
 	"gb-api/track/bigdata/bigbed"
 	"gb-api/track/bigdata/bigwig"
+	"gb-api/track/hic"
+	"gb-api/track/layout"
+	"gb-api/track/liftover"
+	"gb-api/track/tabix"
 	"gb-api/track/transcript"
+	"gb-api/track/vcf"
 	"log/slog"
 	"net/http"
+	"strings"
 )
 
 func BigWigHandler(w http.ResponseWriter, r *http.Request) {
@@ -15,19 +22,154 @@ func BigWigHandler(w http.ResponseWriter, r *http.Request) {
 	l := slog.With("ID", uuid)
 	l.Info("Handling bigwig request")
 	TrackHandler(w, r, l, uuid, func(req *BigWigRequest) (any, error) {
-		l.Info("Reading bigwig", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "preRenderedWidth", req.PreRenderedWidth)
-		data, err := bigwig.GetCachedWigData(req.URL, req.Chrom, req.Start, req.End, req.PreRenderedWidth)
+		l.Info("Reading bigwig", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "preRenderedWidth", req.PreRenderedWidth, "smoothing", req.Smoothing, "normalization", req.Normalization, "transform", req.Transform)
+		return bigWigSignal(req.URL, req.Chrom, req.Start, req.End, req.PreRenderedWidth, req.Smooth(), req.Normalize())
+	})
+	l.Info("Finished bigwig request")
+}
+
+// bigWigSignal reads a bigWig region, smooths and normalises it when asked
+// and resamples it to the prerendered width if specified. Smoothed or
+// normalised data carries how its values were changed as metadata.
+func bigWigSignal(url, chrom string, start, end int, preRenderedWidth int, smooth bigwig.SmoothOptions, norm bigwig.NormalizeOptions) (any, error) {
+	var data []bigwig.BigWigData
+	var meta BigWigMeta
+	var err error
+	if smooth.Enabled() {
+		var smoothing bigwig.Smoothing
+		data, smoothing, err = bigwig.GetSmoothedWigData(url, chrom, start, end, preRenderedWidth, smooth)
+		meta.Smoothing = &smoothing
+	} else {
+		data, err = bigwig.GetCachedWigData(url, chrom, start, end, preRenderedWidth)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if norm.Enabled() {
+		summary, err := bigwig.GetTotalSummary(url)
+		if err != nil {
+			return nil, err
+		}
+		var normalization bigwig.Normalization
+		data, normalization, err = bigwig.Normalize(data, summary, norm)
+		if err != nil {
+			return nil, err
+		}
+		meta.Normalization = &normalization
+	}
+
+	var out any = data
+	if preRenderedWidth > 0 {
+		out = bigwig.ResampleToWidth(data, preRenderedWidth)
+	}
+	if meta == (BigWigMeta{}) {
+		return out, nil
+	}
+	return withMeta{data: out, meta: meta}, nil
+}
+
+func CompositeHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling composite request")
+	TrackHandler(w, r, l, uuid, func(req *CompositeRequest) (any, error) {
+		l.Info("Combining bigwigs", "urls", req.URLs, "operation", req.Operation, "chrom", req.Chrom, "start", req.Start, "end", req.End, "preRenderedWidth", req.PreRenderedWidth)
+		data, err := bigwig.GetCompositeWigData(req.URLs, req.Chrom, req.Start, req.End, req.PreRenderedWidth, req.Options())
 		if err != nil {
 			return nil, err
 		}
 
-		// Resample to prerendered width if specified
+		// Resample the combined signal to prerendered width if specified
 		if req.PreRenderedWidth > 0 {
 			return bigwig.ResampleToWidth(data, req.PreRenderedWidth), nil
 		}
 		return data, nil
 	})
-	l.Info("Finished bigwig request")
+	l.Info("Finished composite request")
+}
+
+func SignalRegionsHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling signal regions request")
+	TrackHandler(w, r, l, uuid, func(req *SignalRegionsRequest) (any, error) {
+		l.Info("Calling signal regions", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "threshold", req.Options().Threshold, "percentile", req.Percentile)
+		return signalRegions(req.URL, req.Chrom, req.Start, req.End, req.Percentile, req.Options())
+	})
+	l.Info("Finished signal regions request")
+}
+
+// signalRegions calls the regions of a bigWig above a threshold, set from
+// the file's summary when a percentile is given, and returns them with the
+// threshold used as metadata
+func signalRegions(url, chrom string, start, end int, percentile float64, opts bigwig.RegionOptions) (any, error) {
+	if percentile > 0 {
+		summary, err := bigwig.GetTotalSummary(url)
+		if err != nil {
+			return nil, err
+		}
+		opts.Threshold, err = bigwig.SummaryPercentile(summary, percentile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	regions, err := bigwig.GetSignalRegions(url, chrom, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+	return withMeta{data: regions, meta: SignalRegionsMeta{Threshold: opts.Threshold, Percentile: percentile}}, nil
+}
+
+func MatrixHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling matrix request")
+	TrackHandler(w, r, l, uuid, func(req *MatrixRequest) (any, error) {
+		features, err := matrixFeatures(req)
+		if err != nil {
+			return nil, err
+		}
+		l.Info("Computing matrix", "urls", req.URLs, "features", len(features), "mode", req.Mode, "referencePoint", req.ReferencePoint, "upstream", req.Upstream, "downstream", req.Downstream, "binSize", req.BinSize)
+		return bigwig.ComputeMatrix(req.URLs, features, req.Options())
+	})
+	l.Info("Finished matrix request")
+}
+
+// matrixFeatures returns the rows of a matrix: the features given, those of
+// a bigBed in the region, or the TSSs of transcripts in the region
+func matrixFeatures(req *MatrixRequest) ([]bigwig.MatrixFeature, error) {
+	switch {
+	case req.BigBedURL != "":
+		data, err := bigbed.GetCachedBedData(req.BigBedURL, req.Chrom, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
+		features := make([]bigwig.MatrixFeature, len(data))
+		for i, d := range data {
+			features[i] = bigwig.MatrixFeature{Chrom: d.Chr, Start: int(d.Start), End: int(d.End)}
+			// Name and strand are the BED columns after the coordinates
+			fields := strings.Split(d.Rest, "\t")
+			if fields[0] != "" {
+				features[i].Name = fields[0]
+			}
+			if len(fields) >= 3 {
+				features[i].Strand = fields[2]
+			}
+		}
+		return features, nil
+	case req.TSS:
+		tss, err := transcript.GetDerivedFeatures(req.Assembly, req.Chrom, req.Start, req.End, req.Filter(), transcript.DeriveOptions{Kind: transcript.DerivedTSS})
+		if err != nil {
+			return nil, err
+		}
+		features := make([]bigwig.MatrixFeature, len(tss))
+		for i, f := range tss {
+			features[i] = bigwig.MatrixFeature{Chrom: f.Chrom, Start: f.Start, End: f.End, Strand: f.Strand, Name: f.Name}
+		}
+		return features, nil
+	}
+	return req.Features, nil
 }
 
 func BigBedHandler(w http.ResponseWriter, r *http.Request) {
@@ -40,31 +182,210 @@ func BigBedHandler(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			return nil, err
 		}
-
-		switch req.Type {
-		case "ccre":
-			return bigbed.ParseCCRE(data)
-		default:
-			return data, err
-		}
+		return bigBedFeatures(data, req.Type, req.Packing())
 	})
 	l.Info("Finished bigbed request")
 }
 
+func BigChainHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling bigchain request")
+	TrackHandler(w, r, l, uuid, func(req *BigChainRequest) (any, error) {
+		l.Info("Reading bigchain", "url", req.URL, "linkUrl", req.LinkURL, "chrom", req.Chrom, "start", req.Start, "end", req.End)
+		return bigbed.ReadBigChain(req.URL, req.LinkURL, req.Chrom, req.Start, req.End)
+	})
+	l.Info("Finished bigchain request")
+}
+
+func BigMafHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling bigmaf request")
+	TrackHandler(w, r, l, uuid, func(req *BigMafRequest) (any, error) {
+		l.Info("Reading bigmaf", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "species", req.Species)
+		return bigbed.ReadBigMaf(req.URL, req.Chrom, req.Start, req.End, req.Species)
+	})
+	l.Info("Finished bigmaf request")
+}
+
+func TabixHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling tabix request")
+	TrackHandler(w, r, l, uuid, func(req *TabixRequest) (any, error) {
+		l.Info("Reading tabix", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "format", req.Format)
+		return getTabixFeatures(req.URL, req.Index, req.Format, req.Columns, req.Chrom, req.Start, req.End)
+	})
+	l.Info("Finished tabix request")
+}
+
+func VCFHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling VCF request")
+	TrackHandler(w, r, l, uuid, func(req *VCFRequest) (any, error) {
+		l.Info("Reading VCF", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "genotypes", req.Genotypes)
+		opts := vcf.Options{Info: req.Info, Genotypes: req.Genotypes, Samples: req.Samples}
+		return vcf.GetCachedVariants(resolveSource(req.URL), resolveSource(req.Index), req.Chrom, req.Start, req.End, opts)
+	})
+	l.Info("Finished VCF request")
+}
+
+func BAMHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling BAM request")
+	TrackHandler(w, r, l, uuid, func(req *BAMRequest) (any, error) {
+		l.Info("Reading BAM", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "mode", req.Mode)
+		opts := bam.Options{Mode: req.Mode, Bins: req.Bins, MinMapQ: req.MinMapQ, SamplingWindow: req.SamplingWindow, SamplingDepth: req.SamplingDepth}
+		return bam.GetCachedAlignments(resolveSource(req.URL), resolveSource(req.Index), req.Chrom, req.Start, req.End, opts)
+	})
+	l.Info("Finished BAM request")
+}
+
+func JunctionHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling junction request")
+	TrackHandler(w, r, l, uuid, func(req *JunctionRequest) (any, error) {
+		l.Info("Reading junctions", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End, "annotate", req.Annotate)
+		opts := bam.JunctionOptions{MinCount: req.MinCount, MinMapQ: req.MinMapQ}
+		return getJunctions(req.URL, req.Index, req.Chrom, req.Start, req.End, opts, req.Annotate, req.Assembly)
+	})
+	l.Info("Finished junction request")
+}
+
+func HiCHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling Hi-C request")
+	TrackHandler(w, r, l, uuid, func(req *HiCRequest) (any, error) {
+		chrom2, start2, end2 := req.Region2()
+		l.Info("Reading Hi-C", "url", req.URL, "chrom", req.Chrom, "start", req.Start, "end", req.End,
+			"chrom2", chrom2, "start2", start2, "end2", end2, "resolution", req.Resolution, "normalization", req.Normalization)
+		opts := hic.Options{Resolution: req.Resolution, Normalization: req.Normalization, Format: req.Format}
+		return hic.GetCachedContacts(resolveSource(req.URL), req.Chrom, req.Start, req.End, chrom2, start2, end2, opts)
+	})
+	l.Info("Finished Hi-C request")
+}
+
+// getJunctions collects splice junctions from a BAM and optionally marks
+// them as known or novel against the transcript annotation of an assembly
+func getJunctions(source, indexSource, chrom string, start, end int, opts bam.JunctionOptions, annotate bool, assembly string) ([]bam.Junction, error) {
+	junctions, err := bam.GetCachedJunctions(resolveSource(source), resolveSource(indexSource), chrom, start, end, opts)
+	if err != nil || !annotate || len(junctions) == 0 {
+		return junctions, err
+	}
+
+	// Junctions may extend past the region, so fetch genes over their full span
+	spanStart, spanEnd := start, end
+	for _, j := range junctions {
+		spanStart = min(spanStart, int(j.Start))
+		spanEnd = max(spanEnd, int(j.End))
+	}
+	genes, err := transcript.GetTranscripts(assembly, chrom, spanStart, spanEnd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read transcripts, %w", err)
+	}
+	bam.AnnotateJunctions(junctions, genes)
+	return junctions, nil
+}
+
+// getTabixFeatures reads a tabix-indexed source and parses its lines with the requested schema
+func getTabixFeatures(source, indexSource, format string, columns []tabix.Column, chrom string, start, end int) ([]tabix.TabixFeature, error) {
+	schema, err := tabix.SchemaFor(format, columns)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tabix.GetCachedTabixData(resolveSource(source), resolveSource(indexSource), chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return tabix.ParseFeatures(data, schema)
+}
+
 func TranscriptHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := UUID()
 	l := slog.With("ID", uuid)
 	l.Info("Handling transcript request")
 	TrackHandler(w, r, l, uuid, func(req *TranscriptRequest) (any, error) {
-		l.Info("Getting transcripts", "chrom", req.Chrom, "start", req.Start, "end", req.End)
-		data, err := transcript.GetTranscripts(req.Chrom, req.Start, req.End)
+		l.Info("Getting transcripts", "assembly", req.Assembly, "chrom", req.Chrom, "start", req.Start, "end", req.End)
+		data, err := transcript.GetTranscripts(req.Assembly, req.Chrom, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
 
-		const defaultPaddingBp = 100
-		return transcript.LegacyWithLayout(data, defaultPaddingBp, err)
+		return layoutTranscripts(data, req.Filter(), req.Mode, req.Version, req.Packing())
 	})
 	l.Info("Finished transcript request")
 }
 
+func DerivedHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling derived request")
+	TrackHandler(w, r, l, uuid, func(req *DerivedRequest) (any, error) {
+		l.Info("Deriving features", "assembly", req.Assembly, "kind", req.Kind, "chrom", req.Chrom, "start", req.Start, "end", req.End)
+		return transcript.GetDerivedFeatures(req.Assembly, req.Chrom, req.Start, req.End, req.Filter(), req.Options())
+	})
+	l.Info("Finished derived request")
+}
+
+// bigBedFeatures parses bigBed data by type and, when requested, packs it
+// into rows
+func bigBedFeatures(data []bigbed.BigBedData, bedType string, packing *layout.Options) (any, error) {
+	switch bedType {
+	case "ccre":
+		ccres, err := bigbed.ParseCCRE(data)
+		if err != nil || packing == nil {
+			return ccres, err
+		}
+		return bigbed.PackCCRE(ccres, *packing), nil
+	default:
+		if packing == nil {
+			return data, nil
+		}
+		return bigbed.PackBedData(data, *packing), nil
+	}
+}
+
+// layoutTranscripts filters genes, collapses them in collapsed mode and lays
+// them out in the requested response version, the legacy shape by default.
+// Filtering and collapsing come first so hidden transcripts do not take up rows.
+func layoutTranscripts(genes []transcript.Gene, filter transcript.Filter, mode string, version int, packing layout.Options) (any, error) {
+	genes = transcript.FilterGenes(genes, filter)
+	if mode == transcript.ModeCollapsed {
+		genes = transcript.CollapseGenes(genes)
+	}
+	if version == transcript.VERSION_MODEL {
+		return transcript.ModelWithLayout(genes, packing), nil
+	}
+	return transcript.LegacyWithPacking(genes, packing), nil
+}
+
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling search request")
+	TrackHandler(w, r, l, uuid, func(req *SearchRequest) (any, error) {
+		l.Info("Searching annotation", "assembly", req.Assembly, "query", req.Query, "limit", req.Limit)
+		return transcript.Search(req.Assembly, req.Query, req.Limit)
+	})
+	l.Info("Finished search request")
+}
+
+func LiftoverHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := UUID()
+	l := slog.With("ID", uuid)
+	l.Info("Handling liftover request")
+	TrackHandler(w, r, l, uuid, func(req *LiftoverRequest) (any, error) {
+		l.Info("Lifting regions", "from", req.From, "to", req.To, "regions", len(req.Regions), "minMatch", req.MinMatch)
+		return liftover.Lift(req.From, req.To, req.LiftRegions(), liftover.Options{MinMatch: req.MinMatch})
+	})
+	l.Info("Finished liftover request")
+}
+
 func BrowserHandler(w http.ResponseWriter, r *http.Request) {
 	uuid := UUID()
 	logger := slog.With("ID", uuid)
@@ -93,6 +414,28 @@ func BrowserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Move the region to the assembly of the tracks
+	var lifted *liftover.Result
+	if request.NeedsLiftover() {
+		region := liftover.Region{Chrom: request.Chrom, Start: request.Start, End: request.End}
+		lifts, err := liftover.Lift(request.Assembly, request.TrackAssembly, []liftover.Region{region}, liftover.Options{MinMatch: request.LiftMinMatch})
+		if err != nil {
+			WriteJSONError(w, uuid, http.StatusInternalServerError,
+				APIError{Code: ErrCodeInternalError, Message: "Failed to lift region", Details: err.Error()})
+			logger.Error("Failed to lift region", "error", err)
+			return
+		}
+		lifted = &lifts[0]
+		if !lifted.Mapped {
+			validationErr := NewValidationError("assembly", fmt.Sprintf("region could not be lifted from %s to %s: %s", request.Assembly, request.TrackAssembly, lifted.Reason))
+			WriteJSONError(w, uuid, http.StatusBadRequest, validationErr)
+			logger.Error("Validation failed", "field", validationErr.Field, "message", validationErr.Message)
+			return
+		}
+		logger.Info("Lifted region", "from", request.Assembly, "to", request.TrackAssembly, "chrom", lifted.Region.Chrom, "start", lifted.Region.Start, "end", lifted.Region.End)
+		request.Chrom, request.Start, request.End = lifted.Region.Chrom, lifted.Region.Start, lifted.Region.End
+	}
+
 	var results = make(chan TrackResponse, len(request.Tracks))
 
 	for _, track := range request.Tracks {
@@ -105,7 +448,8 @@ func BrowserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := BrowserResponse{
-		Data: responses,
+		Data:     responses,
+		Liftover: lifted,
 	}
 
 	// Set headers before streaming response (headers cannot be changed after writing body)
@@ -129,46 +473,206 @@ func getTrackData(t Track, request BrowserRequest, results chan TrackResponse) {
 	// Track data fetchers
 	switch t.Type {
 	case "bigwig":
-		cfg, err := t.GetBigWigConfig()
+		var cfg BigWigConfig
+		cfg, err = t.GetBigWigConfig()
 		if err != nil {
 			err = fmt.Errorf("Could not get BigWig config, %w", err)
 			break
 		}
-		logger.Info("Reading bigWig", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "preRenderedWidth", cfg.PreRenderedWidth)
-		wigData, err := bigwig.GetCachedWigData(cfg.URL, request.Chrom, request.Start, request.End, cfg.PreRenderedWidth)
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid BigWig config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading bigWig", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "preRenderedWidth", cfg.PreRenderedWidth, "smoothing", cfg.Smoothing, "normalization", cfg.Normalization, "transform", cfg.Transform)
+		data, err = bigWigSignal(cfg.URL, request.Chrom, request.Start, request.End, cfg.PreRenderedWidth, cfg.Smooth(), cfg.Normalize())
+	case "composite":
+		var cfg CompositeConfig
+		cfg, err = t.GetCompositeConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get Composite config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid Composite config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Combining bigWigs", "urls", cfg.URLs, "operation", cfg.Operation, "chrom", request.Chrom, "start", request.Start, "end", request.End, "preRenderedWidth", cfg.PreRenderedWidth)
+		var wigData []bigwig.BigWigData
+		wigData, err = bigwig.GetCompositeWigData(cfg.URLs, request.Chrom, request.Start, request.End, cfg.PreRenderedWidth, cfg.Options())
 		if err != nil {
 			break
 		}
-
-		// Resample to prerendered width if specified
 		if cfg.PreRenderedWidth > 0 {
 			data = bigwig.ResampleToWidth(wigData, cfg.PreRenderedWidth)
 		} else {
 			data = wigData
 		}
+	case "signalregions":
+		var cfg SignalRegionsConfig
+		cfg, err = t.GetSignalRegionsConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get SignalRegions config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid SignalRegions config, %s", validationErr.Message)
+			break
+		}
+		if validationErr := validateRegionWindow(request.End - request.Start); validationErr != nil {
+			err = fmt.Errorf("Invalid SignalRegions config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Calling signal regions", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "threshold", cfg.Options().Threshold, "percentile", cfg.Percentile)
+		data, err = signalRegions(cfg.URL, request.Chrom, request.Start, request.End, cfg.Percentile, cfg.Options())
 	case "bigbed":
-		cfg, err := t.GetBigBedConfig()
+		var cfg BigBedConfig
+		cfg, err = t.GetBigBedConfig()
 		if err != nil {
-			err = fmt.Errorf("Could not get BigBedconfig, %w", err)
+			err = fmt.Errorf("Could not get BigBed config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid BigBed config, %s", validationErr.Message)
 			break
 		}
 		logger.Info("Reading bigBed", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End)
-		data, err = bigbed.GetCachedBedData(cfg.URL, request.Chrom, request.Start, request.End)
+		var bedData []bigbed.BigBedData
+		bedData, err = bigbed.GetCachedBedData(cfg.URL, request.Chrom, request.Start, request.End)
+		if err != nil {
+			break
+		}
+		data, err = bigBedFeatures(bedData, cfg.Type, cfg.Packing(request.Start, request.End))
+	case "bigchain":
+		var cfg BigChainConfig
+		cfg, err = t.GetBigChainConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get BigChain config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid BigChain config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading bigChain", "url", cfg.URL, "linkUrl", cfg.LinkURL, "chrom", request.Chrom, "start", request.Start, "end", request.End)
+		data, err = bigbed.ReadBigChain(cfg.URL, cfg.LinkURL, request.Chrom, request.Start, request.End)
+	case "bigmaf":
+		var cfg BigMafConfig
+		cfg, err = t.GetBigMafConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get BigMaf config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid BigMaf config, %s", validationErr.Message)
+			break
+		}
+		if validationErr := validateMafWindow(request.End - request.Start); validationErr != nil {
+			err = fmt.Errorf("Invalid BigMaf config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading bigMaf", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End)
+		data, err = bigbed.ReadBigMaf(cfg.URL, request.Chrom, request.Start, request.End, cfg.Species)
+	case "tabix":
+		var cfg TabixConfig
+		cfg, err = t.GetTabixConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get Tabix config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid Tabix config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading tabix", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "format", cfg.Format)
+		data, err = getTabixFeatures(cfg.URL, cfg.Index, cfg.Format, cfg.Columns, request.Chrom, request.Start, request.End)
+	case "vcf":
+		var cfg VCFConfig
+		cfg, err = t.GetVCFConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get VCF config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid VCF config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading VCF", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "genotypes", cfg.Genotypes)
+		opts := vcf.Options{Info: cfg.Info, Genotypes: cfg.Genotypes, Samples: cfg.Samples}
+		data, err = vcf.GetCachedVariants(resolveSource(cfg.URL), resolveSource(cfg.Index), request.Chrom, request.Start, request.End, opts)
+	case "bam":
+		var cfg BAMConfig
+		cfg, err = t.GetBAMConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get BAM config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(request.End - request.Start); validationErr != nil {
+			err = fmt.Errorf("Invalid BAM config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading BAM", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "mode", cfg.Mode)
+		opts := bam.Options{Mode: cfg.Mode, Bins: cfg.Bins, MinMapQ: cfg.MinMapQ, SamplingWindow: cfg.SamplingWindow, SamplingDepth: cfg.SamplingDepth}
+		data, err = bam.GetCachedAlignments(resolveSource(cfg.URL), resolveSource(cfg.Index), request.Chrom, request.Start, request.End, opts)
+	case "junctions":
+		var cfg JunctionConfig
+		cfg, err = t.GetJunctionConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get Junction config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(request.End - request.Start); validationErr != nil {
+			err = fmt.Errorf("Invalid Junction config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading junctions", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "annotate", cfg.Annotate)
+		opts := bam.JunctionOptions{MinCount: cfg.MinCount, MinMapQ: cfg.MinMapQ}
+		data, err = getJunctions(cfg.URL, cfg.Index, request.Chrom, request.Start, request.End, opts, cfg.Annotate, cfg.Assembly)
+	case "hic":
+		var cfg HiCConfig
+		cfg, err = t.GetHiCConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get Hi-C config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid Hi-C config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Reading Hi-C", "url", cfg.URL, "chrom", request.Chrom, "start", request.Start, "end", request.End, "resolution", cfg.Resolution)
+		opts := hic.Options{Resolution: cfg.Resolution, Normalization: cfg.Normalization, Format: cfg.Format}
+		data, err = hic.GetCachedContacts(resolveSource(cfg.URL), request.Chrom, request.Start, request.End, request.Chrom, request.Start, request.End, opts)
 	case "transcript":
-		_, err := t.GetTranscriptConfig()
+		var cfg TranscriptConfig
+		cfg, err = t.GetTranscriptConfig()
 		if err != nil {
 			err = fmt.Errorf("Could not get Transcript config, %w", err)
 			break
 		}
-		logger.Info("Getting transcripts", "chrom", request.Chrom, "start", request.Start, "end", request.End)
-		genes, err := transcript.GetTranscripts(request.Chrom, request.Start, request.End)
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid Transcript config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Getting transcripts", "assembly", cfg.Assembly, "chrom", request.Chrom, "start", request.Start, "end", request.End)
+		var genes []transcript.Gene
+		genes, err = transcript.GetTranscripts(cfg.Assembly, request.Chrom, request.Start, request.End)
 		if err != nil {
 			break
 		}
 
-		// Use default padding of 100bp for layout
-		const defaultPaddingBp = 100
-		data, err = transcript.LegacyWithLayout(genes, defaultPaddingBp, nil)
+		data, err = layoutTranscripts(genes, cfg.Filter(), cfg.Mode, cfg.Version, cfg.Packing(request.Start, request.End))
+	case "derived":
+		var cfg DerivedConfig
+		cfg, err = t.GetDerivedConfig()
+		if err != nil {
+			err = fmt.Errorf("Could not get Derived config, %w", err)
+			break
+		}
+		if validationErr := cfg.Validate(); validationErr != nil {
+			err = fmt.Errorf("Invalid Derived config, %s", validationErr.Message)
+			break
+		}
+		logger.Info("Deriving features", "assembly", cfg.Assembly, "kind", cfg.Kind, "chrom", request.Chrom, "start", request.Start, "end", request.End)
+		data, err = transcript.GetDerivedFeatures(cfg.Assembly, request.Chrom, request.Start, request.End, cfg.Filter(), cfg.Options())
 	default:
 		err = fmt.Errorf("Invalid track type %s", t.Type)
 	}
@@ -183,9 +687,13 @@ func getTrackData(t Track, request BrowserRequest, results chan TrackResponse) {
 		return
 	}
 
-	results <- TrackResponse{
+	response := TrackResponse{
 		ID:   t.ID,
 		Type: t.Type,
 		Data: data,
 	}
+	if m, ok := data.(withMeta); ok {
+		response.Data, response.Meta = m.data, m.meta
+	}
+	results <- response
 }
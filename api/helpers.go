@@ -4,8 +4,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"gb-api/config"
+	"gb-api/track/bigdata"
 	"log/slog"
 	"net/http"
+	"path/filepath"
 )
 
 func UUID() string {
@@ -20,6 +23,14 @@ func UUID() string {
 	return str[0:4] + "-" + str[4:8] + "-" + str[8:12] + "-" + str[12:]
 }
 
+// resolveSource maps a validated track source to a URL or a path inside the local data directory
+func resolveSource(source string) string {
+	if source == "" || bigdata.IsRemote(source) {
+		return source
+	}
+	return filepath.Join(config.GetLocalDataDir(), source)
+}
+
 // WriteJSONError writes a standardized JSON error response
 func WriteJSONError(w http.ResponseWriter, requestID string, statusCode int, apiErr APIError) {
 	w.Header().Set("Content-Type", "application/json")
@@ -63,6 +74,9 @@ func TrackHandler[Req Validatable, Data any](w http.ResponseWriter, r *http.Requ
 	response := TrackResponse{
 		Data: data,
 	}
+	if m, ok := any(data).(withMeta); ok {
+		response.Data, response.Meta = m.data, m.meta
+	}
 
 	// Set headers before streaming response (headers cannot be changed after writing body)
 	w.Header().Set("Content-Type", "application/json")
@@ -3,8 +3,20 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"gb-api/track/bam"
+	"gb-api/track/bigdata"
+	"gb-api/track/bigdata/bigbed"
+	"gb-api/track/bigdata/bigwig"
+	"gb-api/track/hic"
+	"gb-api/track/layout"
+	"gb-api/track/liftover"
+	"gb-api/track/tabix"
+	"gb-api/track/transcript"
 	"net/url"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 )
 
 // APIError represents a standardized error response
@@ -46,29 +58,980 @@ func NewValidationError(field, message string) APIError {
 // chromRegex validates chromosome format (chr1-22, chrX, chrY, chrM, etc.)
 var chromRegex = regexp.MustCompile(`^chr([1-9]|1[0-9]|2[0-2]|X|Y|M|MT)$`)
 
+// validateSource checks a track source that may be an http(s) URL or a path
+// relative to the local data directory
+func validateSource(field, source string) *APIError {
+	if bigdata.IsRemote(source) {
+		if _, parseErr := url.ParseRequestURI(source); parseErr != nil {
+			err := NewValidationError(field, fmt.Sprintf("invalid url: %s", parseErr.Error()))
+			return &err
+		}
+		return nil
+	}
+	if !filepath.IsLocal(source) {
+		err := NewValidationError(field, "local paths must be relative to the data directory")
+		return &err
+	}
+	return nil
+}
+
+// validateURL checks a required url of a track config
+func validateURL(field, value string) *APIError {
+	if value == "" {
+		err := NewValidationError(field, field+" is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(value); parseErr != nil {
+		err := NewValidationError(field, fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	return nil
+}
+
 // Validatable interface for request validation
 type Validatable interface {
 	Validate() *APIError
 }
 
-// Track requests
-type BigWigRequest struct {
-	URL              string `json:"url"`
-	Chrom            string `json:"chrom"`
-	Start            int    `json:"start"`
-	End              int    `json:"end"`
-	PreRenderedWidth int    `json:"preRenderedWidth,omitempty"` // Number of points to return
+// Track requests
+type BigWigRequest struct {
+	URL              string `json:"url"`
+	Chrom            string `json:"chrom"`
+	Start            int    `json:"start"`
+	End              int    `json:"end"`
+	PreRenderedWidth int    `json:"preRenderedWidth,omitempty"` // Number of points to return
+
+	Smoothing       string `json:"smoothing,omitempty"`       // mean, median or gaussian, applied before normalization and resampling
+	SmoothingWindow int    `json:"smoothingWindow,omitempty"` // Window in bp for mean and median, bandwidth in bp for gaussian
+
+	Normalization  string  `json:"normalization,omitempty"`  // scale, cpm, rpkm or zscore, using the file's total summary
+	ScaleFactor    float64 `json:"scaleFactor,omitempty"`    // Factor of the scale normalization
+	Transform      string  `json:"transform,omitempty"`      // log1p, log2 or sqrt, applied after normalization
+	ClipPercentile float64 `json:"clipPercentile,omitempty"` // Clamp values to this percentile of the region and its mirror, e.g. 99
+}
+
+// Validate checks BigWigRequest fields
+func (r *BigWigRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
+		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if r.PreRenderedWidth < 0 {
+		err := NewValidationError("preRenderedWidth", "preRenderedWidth must be >= 0")
+		return &err
+	}
+	if err := validateSmoothOptions(r.Smoothing, r.SmoothingWindow); err != nil {
+		return err
+	}
+	return validateNormalizeOptions(r.Normalization, r.ScaleFactor, r.Transform, r.ClipPercentile)
+}
+
+// Smooth returns the smoothing options of the request
+func (r *BigWigRequest) Smooth() bigwig.SmoothOptions {
+	return bigwig.SmoothOptions{Method: r.Smoothing, Window: r.SmoothingWindow}
+}
+
+// Normalize returns the normalization options of the request
+func (r *BigWigRequest) Normalize() bigwig.NormalizeOptions {
+	return bigwig.NormalizeOptions{Method: r.Normalization, ScaleFactor: r.ScaleFactor, Transform: r.Transform, ClipPercentile: r.ClipPercentile}
+}
+
+// validateSmoothOptions checks the smoothing options shared by bigWig
+// requests and configs
+func validateSmoothOptions(method string, window int) *APIError {
+	if method == "" {
+		return nil
+	}
+	if !slices.Contains(bigwig.SmoothingMethods, method) {
+		err := NewValidationError("smoothing", fmt.Sprintf("invalid smoothing %q, expected one of %s", method, strings.Join(bigwig.SmoothingMethods, ", ")))
+		return &err
+	}
+	if window <= 0 || window > bigwig.MAX_SMOOTHING_WINDOW {
+		err := NewValidationError("smoothingWindow", fmt.Sprintf("smoothingWindow must be between 1 and %d", bigwig.MAX_SMOOTHING_WINDOW))
+		return &err
+	}
+	return nil
+}
+
+// validateNormalizeOptions checks the normalization options shared by bigWig
+// requests and configs
+func validateNormalizeOptions(method string, scaleFactor float64, transform string, clipPercentile float64) *APIError {
+	if method != "" && !slices.Contains(bigwig.NormalizationMethods, method) {
+		err := NewValidationError("normalization", fmt.Sprintf("invalid normalization %q, expected one of %s", method, strings.Join(bigwig.NormalizationMethods, ", ")))
+		return &err
+	}
+	if method == bigwig.NormScale && scaleFactor <= 0 {
+		err := NewValidationError("scaleFactor", "scaleFactor must be > 0 with scale normalization")
+		return &err
+	}
+	if scaleFactor < 0 {
+		err := NewValidationError("scaleFactor", "scaleFactor must be >= 0")
+		return &err
+	}
+	if transform != "" && !slices.Contains(bigwig.Transforms, transform) {
+		err := NewValidationError("transform", fmt.Sprintf("invalid transform %q, expected one of %s", transform, strings.Join(bigwig.Transforms, ", ")))
+		return &err
+	}
+	if clipPercentile != 0 && (clipPercentile <= 50 || clipPercentile > 100) {
+		err := NewValidationError("clipPercentile", "clipPercentile must be above 50 and at most 100")
+		return &err
+	}
+	return nil
+}
+
+type CompositeRequest struct {
+	URLs             []string `json:"urls"` // bigWigs to combine, treatment first for difference and ratios
+	Chrom            string   `json:"chrom"`
+	Start            int      `json:"start"`
+	End              int      `json:"end"`
+	PreRenderedWidth int      `json:"preRenderedWidth,omitempty"` // Number of points to return
+	Operation        string   `json:"operation"`                  // sum, mean, min, max, difference, ratio or log2ratio
+	Pseudocount      float64  `json:"pseudocount,omitempty"`      // Added to both sides of ratios
+}
+
+// Validate checks CompositeRequest fields
+func (r *CompositeRequest) Validate() *APIError {
+	if err := validateCompositeOptions(r.URLs, r.Operation, r.Pseudocount); err != nil {
+		return err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if r.PreRenderedWidth < 0 {
+		err := NewValidationError("preRenderedWidth", "preRenderedWidth must be >= 0")
+		return &err
+	}
+	return nil
+}
+
+// Options returns the combining options of the request
+func (r *CompositeRequest) Options() bigwig.CompositeOptions {
+	return bigwig.CompositeOptions{Operation: r.Operation, Pseudocount: r.Pseudocount}
+}
+
+// validateCompositeOptions checks the inputs and operation shared by
+// composite bigWig requests and configs
+func validateCompositeOptions(urls []string, operation string, pseudocount float64) *APIError {
+	if len(urls) == 0 {
+		err := NewValidationError("urls", "at least one url is required")
+		return &err
+	}
+	if len(urls) > bigwig.MAX_COMPOSITE_INPUTS {
+		err := NewValidationError("urls", fmt.Sprintf("at most %d urls can be combined", bigwig.MAX_COMPOSITE_INPUTS))
+		return &err
+	}
+	for _, u := range urls {
+		if _, parseErr := url.ParseRequestURI(u); parseErr != nil {
+			err := NewValidationError("urls", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+			return &err
+		}
+	}
+	if !slices.Contains(bigwig.Operations, operation) {
+		err := NewValidationError("operation", fmt.Sprintf("invalid operation %q, expected one of %s", operation, strings.Join(bigwig.Operations, ", ")))
+		return &err
+	}
+	if bigwig.PairOperation(operation) && len(urls) != 2 {
+		err := NewValidationError("urls", fmt.Sprintf("%s needs exactly two urls", operation))
+		return &err
+	}
+	if pseudocount < 0 {
+		err := NewValidationError("pseudocount", "pseudocount must be >= 0")
+		return &err
+	}
+	return nil
+}
+
+type SignalRegionsRequest struct {
+	URL           string   `json:"url"`
+	Chrom         string   `json:"chrom"`
+	Start         int      `json:"start"`
+	End           int      `json:"end"`
+	Threshold     *float64 `json:"threshold,omitempty"`     // Signal must exceed this
+	Percentile    float64  `json:"percentile,omitempty"`    // Or this percentile of the file's values, estimated from its summary
+	MinLength     int      `json:"minLength,omitempty"`     // Shorter regions, after merging, are dropped
+	MergeDistance int      `json:"mergeDistance,omitempty"` // Regions this close or closer are joined
+}
+
+// Validate checks SignalRegionsRequest fields
+func (r *SignalRegionsRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
+		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if err := validateRegionWindow(r.End - r.Start); err != nil {
+		return err
+	}
+	return validateRegionOptions(r.Threshold, r.Percentile, r.MinLength, r.MergeDistance)
+}
+
+// Options returns the region calling options of the request, without the
+// threshold of a percentile
+func (r *SignalRegionsRequest) Options() bigwig.RegionOptions {
+	return regionOptions(r.Threshold, r.MinLength, r.MergeDistance)
+}
+
+// validateRegionWindow checks the region width shared by signal region
+// requests and configs, which read full-resolution data
+func validateRegionWindow(width int) *APIError {
+	if width > bigwig.MAX_REGION_WINDOW {
+		err := NewValidationError("end", fmt.Sprintf("region must be at most %d bp", bigwig.MAX_REGION_WINDOW))
+		return &err
+	}
+	return nil
+}
+
+// validateRegionOptions checks the region calling options shared by signal
+// region requests and configs
+func validateRegionOptions(threshold *float64, percentile float64, minLength, mergeDistance int) *APIError {
+	if (threshold == nil) == (percentile == 0) {
+		err := NewValidationError("threshold", "exactly one of threshold and percentile is required")
+		return &err
+	}
+	if percentile < 0 || percentile >= 100 {
+		err := NewValidationError("percentile", "percentile must be above 0 and below 100")
+		return &err
+	}
+	if minLength < 0 {
+		err := NewValidationError("minLength", "minLength must be >= 0")
+		return &err
+	}
+	if mergeDistance < 0 || mergeDistance > bigwig.MAX_REGION_WINDOW {
+		err := NewValidationError("mergeDistance", fmt.Sprintf("mergeDistance must be between 0 and %d", bigwig.MAX_REGION_WINDOW))
+		return &err
+	}
+	return nil
+}
+
+// regionOptions builds region calling options, leaving the threshold at 0
+// when a percentile sets it
+func regionOptions(threshold *float64, minLength, mergeDistance int) bigwig.RegionOptions {
+	opts := bigwig.RegionOptions{MinLength: minLength, MergeDistance: mergeDistance}
+	if threshold != nil {
+		opts.Threshold = *threshold
+	}
+	return opts
+}
+
+type MatrixRequest struct {
+	URLs      []string               `json:"urls"`                // bigWigs, one block of columns each
+	Features  []bigwig.MatrixFeature `json:"features,omitempty"`  // Features given as coordinates
+	BigBedURL string                 `json:"bigBedUrl,omitempty"` // Or the features of a bigBed in the region
+	TSS       bool                   `json:"tss,omitempty"`       // Or the transcript TSSs in the region
+	Chrom     string                 `json:"chrom,omitempty"`     // Region of bigBed features or TSSs
+	Start     int                    `json:"start,omitempty"`
+	End       int                    `json:"end,omitempty"`
+
+	Assembly     string   `json:"assembly,omitempty"`     // Registered assembly of the TSSs, defaults to the configured default
+	GeneTypes    []string `json:"geneTypes,omitempty"`    // Keep TSSs of genes of these types
+	Tags         []string `json:"tags,omitempty"`         // Keep TSSs of transcripts with any of these tags
+	SupportLevel int      `json:"supportLevel,omitempty"` // Keep TSSs of transcripts with a support level of at most this (1-5)
+	OnePerGene   bool     `json:"onePerGene,omitempty"`   // Keep the TSS of the MANE Select or longest-CDS transcript of each gene
+
+	Mode           string `json:"mode,omitempty"`           // reference-point (default) or scale-regions
+	ReferencePoint string `json:"referencePoint,omitempty"` // start (default), end or center, following the strand
+	Upstream       int    `json:"upstream,omitempty"`       // Flank 5' of the reference point or feature in bp
+	Downstream     int    `json:"downstream,omitempty"`     // Flank 3' of the reference point or feature in bp
+	BodyLength     int    `json:"bodyLength,omitempty"`     // Length features are scaled to in scale-regions mode
+	BinSize        int    `json:"binSize,omitempty"`        // Bin width in bp, defaults to 10
+}
+
+// Validate checks MatrixRequest fields
+func (r *MatrixRequest) Validate() *APIError {
+	if len(r.URLs) == 0 {
+		err := NewValidationError("urls", "at least one url is required")
+		return &err
+	}
+	if len(r.URLs) > bigwig.MAX_MATRIX_INPUTS {
+		err := NewValidationError("urls", fmt.Sprintf("at most %d urls can be read", bigwig.MAX_MATRIX_INPUTS))
+		return &err
+	}
+	for _, u := range r.URLs {
+		if _, parseErr := url.ParseRequestURI(u); parseErr != nil {
+			err := NewValidationError("urls", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+			return &err
+		}
+	}
+
+	sources := 0
+	for _, given := range []bool{len(r.Features) > 0, r.BigBedURL != "", r.TSS} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		err := NewValidationError("features", "exactly one of features, bigBedUrl and tss is required")
+		return &err
+	}
+	if len(r.Features) > 0 {
+		if err := validateMatrixFeatures(r.Features); err != nil {
+			return err
+		}
+	} else {
+		if r.Chrom == "" {
+			err := NewValidationError("chrom", "chrom is required")
+			return &err
+		}
+		if !chromRegex.MatchString(r.Chrom) {
+			err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+			return &err
+		}
+		if r.Start < 0 {
+			err := NewValidationError("start", "start must be >= 0")
+			return &err
+		}
+		if r.End <= r.Start {
+			err := NewValidationError("end", "end must be greater than start")
+			return &err
+		}
+	}
+	if r.BigBedURL != "" {
+		if _, parseErr := url.ParseRequestURI(r.BigBedURL); parseErr != nil {
+			err := NewValidationError("bigBedUrl", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+			return &err
+		}
+	}
+	if r.TSS {
+		if err := validateTranscriptOptions(0, "", r.GeneTypes, r.Tags, r.SupportLevel); err != nil {
+			return err
+		}
+		if err := validateAssembly(r.Assembly); err != nil {
+			return err
+		}
+	}
+	return validateMatrixOptions(r.Options())
+}
+
+// Filter returns the transcript filter of the request's TSSs
+func (r *MatrixRequest) Filter() transcript.Filter {
+	return transcript.Filter{GeneTypes: r.GeneTypes, Tags: r.Tags, SupportLevel: r.SupportLevel, OnePerGene: r.OnePerGene}
+}
+
+// Options returns the binning options of the request
+func (r *MatrixRequest) Options() bigwig.MatrixOptions {
+	return bigwig.MatrixOptions{
+		Mode:           r.Mode,
+		ReferencePoint: r.ReferencePoint,
+		Upstream:       r.Upstream,
+		Downstream:     r.Downstream,
+		BodyLength:     r.BodyLength,
+		BinSize:        r.BinSize,
+	}
+}
+
+// validateMatrixFeatures checks features given as coordinates
+func validateMatrixFeatures(features []bigwig.MatrixFeature) *APIError {
+	if len(features) > bigwig.MAX_MATRIX_FEATURES {
+		err := NewValidationError("features", fmt.Sprintf("at most %d features can be given", bigwig.MAX_MATRIX_FEATURES))
+		return &err
+	}
+	for i, f := range features {
+		if !chromRegex.MatchString(f.Chrom) {
+			err := NewValidationError("features", fmt.Sprintf("feature %d: invalid chromosome format: %s", i, f.Chrom))
+			return &err
+		}
+		if f.Start < 0 || f.End <= f.Start {
+			err := NewValidationError("features", fmt.Sprintf("feature %d: start must be >= 0 and less than end", i))
+			return &err
+		}
+		if f.Strand != "" && f.Strand != "+" && f.Strand != "-" && f.Strand != "." {
+			err := NewValidationError("features", fmt.Sprintf("feature %d: invalid strand %q, expected +, - or .", i, f.Strand))
+			return &err
+		}
+	}
+	return nil
+}
+
+// validateMatrixOptions checks the mode, reference point and bin layout of
+// a matrix
+func validateMatrixOptions(opts bigwig.MatrixOptions) *APIError {
+	if opts.Mode != "" && !slices.Contains(bigwig.MatrixModes, opts.Mode) {
+		err := NewValidationError("mode", fmt.Sprintf("invalid mode %q, expected one of %s", opts.Mode, strings.Join(bigwig.MatrixModes, ", ")))
+		return &err
+	}
+	if opts.ReferencePoint != "" && !slices.Contains(bigwig.ReferencePoints, opts.ReferencePoint) {
+		err := NewValidationError("referencePoint", fmt.Sprintf("invalid referencePoint %q, expected one of %s", opts.ReferencePoint, strings.Join(bigwig.ReferencePoints, ", ")))
+		return &err
+	}
+	for _, length := range []struct {
+		field string
+		value int
+	}{{"upstream", opts.Upstream}, {"downstream", opts.Downstream}, {"bodyLength", opts.BodyLength}} {
+		if length.value < 0 || length.value > bigwig.MAX_MATRIX_FLANK {
+			err := NewValidationError(length.field, fmt.Sprintf("%s must be between 0 and %d", length.field, bigwig.MAX_MATRIX_FLANK))
+			return &err
+		}
+	}
+	if opts.Mode == bigwig.MatrixScaleRegions && opts.BodyLength == 0 {
+		err := NewValidationError("bodyLength", "bodyLength is required with scale-regions")
+		return &err
+	}
+	if opts.Mode != bigwig.MatrixScaleRegions && opts.Upstream+opts.Downstream == 0 {
+		err := NewValidationError("upstream", "upstream or downstream is required with reference-point")
+		return &err
+	}
+	if opts.BinSize < 0 {
+		err := NewValidationError("binSize", "binSize must be >= 0")
+		return &err
+	}
+	if layoutErr := bigwig.ValidateMatrixOptions(opts); layoutErr != nil {
+		err := NewValidationError("binSize", layoutErr.Error())
+		return &err
+	}
+	return nil
+}
+
+type BigBedRequest struct {
+	URL   string `json:"url"`
+	Chrom string `json:"chrom"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Type  string `json:"type,omitempty"` // "ccre", "generic" | used for parsing non-universal columns
+
+	Layout    bool `json:"layout,omitempty"`    // Pack features into rows
+	Width     int  `json:"width,omitempty"`     // View width in pixels, packs rows in pixels rather than base pairs
+	CharWidth int  `json:"charWidth,omitempty"` // Pixels per label character, reserves space for feature names
+	MaxRows   int  `json:"maxRows,omitempty"`   // Most rows, further features are counted as overflow
+}
+
+// Validate checks BigBedRequest fields
+func (r *BigBedRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
+		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	return validateLayoutOptions(r.Width, r.CharWidth, r.MaxRows)
+}
+
+// Packing returns the row layout options of the request, nil without layout
+func (r *BigBedRequest) Packing() *layout.Options {
+	if !r.Layout {
+		return nil
+	}
+	return &layout.Options{Width: r.Width, Start: r.Start, End: r.End, CharWidth: r.CharWidth, MaxRows: r.MaxRows}
+}
+
+type BigChainRequest struct {
+	URL     string `json:"url"`
+	LinkURL string `json:"linkUrl,omitempty"` // bigLink file with the blocks of each chain, none returns chains only
+	Chrom   string `json:"chrom"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// Validate checks BigChainRequest fields
+func (r *BigChainRequest) Validate() *APIError {
+	if err := validateBigChainURLs(r.URL, r.LinkURL); err != nil {
+		return err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	return nil
+}
+
+// validateBigChainURLs checks the chain and link files shared by bigChain
+// requests and configs
+func validateBigChainURLs(chainURL, linkURL string) *APIError {
+	if chainURL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(chainURL); parseErr != nil {
+		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	if linkURL == "" {
+		return nil
+	}
+	if _, parseErr := url.ParseRequestURI(linkURL); parseErr != nil {
+		err := NewValidationError("linkUrl", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	return nil
+}
+
+type BigMafRequest struct {
+	URL     string   `json:"url"`
+	Chrom   string   `json:"chrom"`
+	Start   int      `json:"start"`
+	End     int      `json:"end"`
+	Species []string `json:"species,omitempty"` // Keep rows of these species besides the reference, e.g. mm10
+}
+
+// Validate checks BigMafRequest fields
+func (r *BigMafRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
+		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+		return &err
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	return validateMafWindow(r.End - r.Start)
+}
+
+// validateMafWindow checks the region width shared by bigMaf requests and configs
+func validateMafWindow(width int) *APIError {
+	if width > bigbed.MAX_MAF_WINDOW {
+		err := NewValidationError("end", fmt.Sprintf("region must be at most %d bp", bigbed.MAX_MAF_WINDOW))
+		return &err
+	}
+	return nil
+}
+
+type TranscriptRequest struct {
+	Chrom        string   `json:"chrom"`
+	Start        int      `json:"start"`
+	End          int      `json:"end"`
+	Assembly     string   `json:"assembly,omitempty"`     // Registered assembly, defaults to the configured default
+	GeneTypes    []string `json:"geneTypes,omitempty"`    // Keep genes of these types, e.g. protein_coding, lncRNA
+	Tags         []string `json:"tags,omitempty"`         // Keep transcripts with any of these tags, e.g. MANE_Select, basic, CCDS
+	SupportLevel int      `json:"supportLevel,omitempty"` // Keep transcripts with a support level of at most this (1-5)
+	OnePerGene   bool     `json:"onePerGene,omitempty"`   // Keep the MANE Select or longest-CDS transcript of each gene
+	Mode         string   `json:"mode,omitempty"`         // expanded (default) or collapsed into one model per gene
+	Version      int      `json:"version,omitempty"`      // Response shape: 1 legacy (default) or 2 full transcript models
+	Width        int      `json:"width,omitempty"`        // View width in pixels, packs rows in pixels rather than base pairs
+	CharWidth    int      `json:"charWidth,omitempty"`    // Pixels per label character, reserves label space with width
+	MaxRows      int      `json:"maxRows,omitempty"`      // Most rows, further transcripts are counted as overflow
+}
+
+// Validate checks TranscriptRequest fields
+func (r *TranscriptRequest) Validate() *APIError {
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if err := validateTranscriptOptions(r.Version, r.Mode, r.GeneTypes, r.Tags, r.SupportLevel); err != nil {
+		return err
+	}
+	if err := validateLayoutOptions(r.Width, r.CharWidth, r.MaxRows); err != nil {
+		return err
+	}
+	return validateAssembly(r.Assembly)
+}
+
+// Filter returns the transcript filter of the request
+func (r *TranscriptRequest) Filter() transcript.Filter {
+	return transcript.Filter{GeneTypes: r.GeneTypes, Tags: r.Tags, SupportLevel: r.SupportLevel, OnePerGene: r.OnePerGene}
+}
+
+// Packing returns the row layout options of the request
+func (r *TranscriptRequest) Packing() layout.Options {
+	return layout.Options{Width: r.Width, Start: r.Start, End: r.End, CharWidth: r.CharWidth, MaxRows: r.MaxRows}
+}
+
+// validateLayoutOptions checks the row layout options shared by requests and configs
+func validateLayoutOptions(width, charWidth, maxRows int) *APIError {
+	if width < 0 || width > 20000 {
+		err := NewValidationError("width", "width must be between 0 and 20000")
+		return &err
+	}
+	if charWidth < 0 || charWidth > 100 {
+		err := NewValidationError("charWidth", "charWidth must be between 0 and 100")
+		return &err
+	}
+	if charWidth > 0 && width == 0 {
+		err := NewValidationError("charWidth", "charWidth requires width")
+		return &err
+	}
+	if maxRows < 0 || maxRows > 1000 {
+		err := NewValidationError("maxRows", "maxRows must be between 0 and 1000")
+		return &err
+	}
+	return nil
+}
+
+// validateTranscriptOptions checks the response version, display mode and
+// filters shared by transcript requests and configs
+func validateTranscriptOptions(version int, mode string, geneTypes, tags []string, supportLevel int) *APIError {
+	if version != 0 && version != transcript.VERSION_LEGACY && version != transcript.VERSION_MODEL {
+		err := NewValidationError("version", fmt.Sprintf("invalid version %d, expected %d or %d", version, transcript.VERSION_LEGACY, transcript.VERSION_MODEL))
+		return &err
+	}
+	if mode != "" && mode != transcript.ModeExpanded && mode != transcript.ModeCollapsed {
+		err := NewValidationError("mode", fmt.Sprintf("invalid mode %s, expected %s or %s", mode, transcript.ModeExpanded, transcript.ModeCollapsed))
+		return &err
+	}
+	for _, list := range []struct {
+		field  string
+		values []string
+	}{{"geneTypes", geneTypes}, {"tags", tags}} {
+		field, values := list.field, list.values
+		if len(values) > 50 {
+			err := NewValidationError(field, field+" must have at most 50 entries")
+			return &err
+		}
+		for _, v := range values {
+			if strings.TrimSpace(v) == "" {
+				err := NewValidationError(field, field+" must not contain empty entries")
+				return &err
+			}
+		}
+	}
+	if supportLevel < 0 || supportLevel > 5 {
+		err := NewValidationError("supportLevel", "supportLevel must be between 1 and 5, or 0 for any")
+		return &err
+	}
+	return nil
+}
+
+type DerivedRequest struct {
+	Chrom        string   `json:"chrom"`
+	Start        int      `json:"start"`
+	End          int      `json:"end"`
+	Assembly     string   `json:"assembly,omitempty"`     // Registered assembly, defaults to the configured default
+	Kind         string   `json:"kind"`                   // tss, promoter, gene_body, intron or intergenic
+	Upstream     *int     `json:"upstream,omitempty"`     // Promoter bases 5' of the TSS, defaults to 2000, 0 for none
+	Downstream   *int     `json:"downstream,omitempty"`   // Promoter bases 3' of the TSS, defaults to 2000, 0 for none
+	GeneTypes    []string `json:"geneTypes,omitempty"`    // Keep genes of these types, e.g. protein_coding, lncRNA
+	Tags         []string `json:"tags,omitempty"`         // Keep transcripts with any of these tags, e.g. MANE_Select, basic, CCDS
+	SupportLevel int      `json:"supportLevel,omitempty"` // Keep transcripts with a support level of at most this (1-5)
+	OnePerGene   bool     `json:"onePerGene,omitempty"`   // Keep the MANE Select or longest-CDS transcript of each gene
+}
+
+// Validate checks DerivedRequest fields
+func (r *DerivedRequest) Validate() *APIError {
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if err := validateDerivedOptions(r.Kind, r.Upstream, r.Downstream); err != nil {
+		return err
+	}
+	if err := validateTranscriptOptions(0, "", r.GeneTypes, r.Tags, r.SupportLevel); err != nil {
+		return err
+	}
+	return validateAssembly(r.Assembly)
+}
+
+// Filter returns the transcript filter of the request
+func (r *DerivedRequest) Filter() transcript.Filter {
+	return transcript.Filter{GeneTypes: r.GeneTypes, Tags: r.Tags, SupportLevel: r.SupportLevel, OnePerGene: r.OnePerGene}
+}
+
+// Options returns the derived feature options of the request
+func (r *DerivedRequest) Options() transcript.DeriveOptions {
+	return transcript.DeriveOptions{Kind: r.Kind, Upstream: r.Upstream, Downstream: r.Downstream}
+}
+
+// validateDerivedOptions checks the feature kind and promoter window shared
+// by derived requests and configs
+func validateDerivedOptions(kind string, upstream, downstream *int) *APIError {
+	if !slices.Contains(transcript.DerivedKinds, kind) {
+		err := NewValidationError("kind", fmt.Sprintf("invalid kind %q, expected one of %s", kind, strings.Join(transcript.DerivedKinds, ", ")))
+		return &err
+	}
+	if upstream != nil && (*upstream < 0 || *upstream > 100000) {
+		err := NewValidationError("upstream", "upstream must be between 0 and 100000")
+		return &err
+	}
+	if downstream != nil && (*downstream < 0 || *downstream > 100000) {
+		err := NewValidationError("downstream", "downstream must be between 0 and 100000")
+		return &err
+	}
+	if kind == transcript.DerivedPromoter && upstream != nil && downstream != nil && *upstream+*downstream == 0 {
+		err := NewValidationError("upstream", "upstream or downstream must be > 0 for promoters")
+		return &err
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	Query    string `json:"query"`              // Gene or transcript name or ID, e.g. "APOE" or "ENST00000252486"
+	Assembly string `json:"assembly,omitempty"` // Registered assembly, defaults to the configured default
+	Limit    int    `json:"limit,omitempty"`    // Most results to return, defaults to 10
+}
+
+// Validate checks SearchRequest fields
+func (r *SearchRequest) Validate() *APIError {
+	if strings.TrimSpace(r.Query) == "" {
+		err := NewValidationError("query", "query is required")
+		return &err
+	}
+	if len(r.Query) > 100 {
+		err := NewValidationError("query", "query must be at most 100 characters")
+		return &err
+	}
+	if r.Limit < 0 || r.Limit > transcript.MAX_SEARCH_LIMIT {
+		err := NewValidationError("limit", fmt.Sprintf("limit must be between 0 and %d", transcript.MAX_SEARCH_LIMIT))
+		return &err
+	}
+	return validateAssembly(r.Assembly)
+}
+
+// validateAssembly checks that an assembly, when given, has a registered annotation
+func validateAssembly(assembly string) *APIError {
+	if assembly == "" {
+		return nil
+	}
+	if _, lookupErr := transcript.LookupAnnotation(assembly); lookupErr != nil {
+		err := NewValidationError("assembly", lookupErr.Error())
+		return &err
+	}
+	return nil
+}
+
+type LiftoverRequest struct {
+	From     string            `json:"from"`               // Assembly of the regions, e.g. hg19
+	To       string            `json:"to"`                 // Assembly to convert them to, e.g. grch38
+	Regions  []liftover.Region `json:"regions"`            // Positions (no end), regions or BED-like features
+	MinMatch float64           `json:"minMatch,omitempty"` // Fraction of bases that must align, defaults to 0.95
+}
+
+// Validate checks LiftoverRequest fields
+func (r *LiftoverRequest) Validate() *APIError {
+	if r.From == "" {
+		err := NewValidationError("from", "from is required")
+		return &err
+	}
+	if r.To == "" {
+		err := NewValidationError("to", "to is required")
+		return &err
+	}
+	if !liftover.HasChain(r.From, r.To) {
+		err := NewValidationError("to", liftover.UnknownChainError(r.From, r.To).Error())
+		return &err
+	}
+	if r.MinMatch < 0 || r.MinMatch > 1 {
+		err := NewValidationError("minMatch", "minMatch must be between 0 and 1")
+		return &err
+	}
+	if len(r.Regions) == 0 {
+		err := NewValidationError("regions", "at least one region is required")
+		return &err
+	}
+	if len(r.Regions) > liftover.MAX_REGIONS {
+		err := NewValidationError("regions", fmt.Sprintf("at most %d regions can be lifted at once", liftover.MAX_REGIONS))
+		return &err
+	}
+	for i, region := range r.Regions {
+		field := fmt.Sprintf("regions[%d]", i)
+		if !chromRegex.MatchString(region.Chrom) {
+			err := NewValidationError(field, fmt.Sprintf("invalid chromosome format: %s", region.Chrom))
+			return &err
+		}
+		if region.Start < 0 {
+			err := NewValidationError(field, "start must be >= 0")
+			return &err
+		}
+		if region.End != 0 && region.End <= region.Start {
+			err := NewValidationError(field, "end must be greater than start, or omitted for a position")
+			return &err
+		}
+	}
+	return nil
+}
+
+// LiftRegions returns the regions of the request, positions becoming
+// regions of one base
+func (r *LiftoverRequest) LiftRegions() []liftover.Region {
+	regions := slices.Clone(r.Regions)
+	for i := range regions {
+		if regions[i].End == 0 {
+			regions[i].End = regions[i].Start + 1
+		}
+	}
+	return regions
+}
+
+type TabixRequest struct {
+	URL     string         `json:"url"`             // http(s) URL or path relative to the local data directory
+	Index   string         `json:"index,omitempty"` // Index location, defaults to url + ".tbi" then ".csi"
+	Chrom   string         `json:"chrom"`
+	Start   int            `json:"start"`
+	End     int            `json:"end"`
+	Format  string         `json:"format,omitempty"`  // "bed" (default), "bedgraph", "bedmethyl", "gff", "gtf", "generic"
+	Columns []tabix.Column `json:"columns,omitempty"` // Custom column schema, overrides format
+}
+
+// Validate checks TabixRequest fields
+func (r *TabixRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", r.URL); err != nil {
+		return err
+	}
+	if r.Index != "" {
+		if err := validateSource("index", r.Index); err != nil {
+			return err
+		}
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	if _, schemaErr := tabix.SchemaFor(r.Format, r.Columns); schemaErr != nil {
+		err := NewValidationError("format", schemaErr.Error())
+		return &err
+	}
+	return nil
+}
+
+type VCFRequest struct {
+	URL       string   `json:"url"`             // http(s) URL or path relative to the local data directory
+	Index     string   `json:"index,omitempty"` // Index location, defaults to url + ".tbi" then ".csi"
+	Chrom     string   `json:"chrom"`
+	Start     int      `json:"start"`
+	End       int      `json:"end"`
+	Info      []string `json:"info,omitempty"`      // INFO keys to return, defaults to all
+	Genotypes bool     `json:"genotypes,omitempty"` // Include per-sample genotypes
+	Samples   []string `json:"samples,omitempty"`   // Samples to return genotypes for, defaults to all
 }
 
-// Validate checks BigWigRequest fields
-func (r *BigWigRequest) Validate() *APIError {
+// Validate checks VCFRequest fields
+func (r *VCFRequest) Validate() *APIError {
 	if r.URL == "" {
 		err := NewValidationError("url", "url is required")
 		return &err
 	}
-	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
-		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
-		return &err
+	if err := validateSource("url", r.URL); err != nil {
+		return err
+	}
+	if r.Index != "" {
+		if err := validateSource("index", r.Index); err != nil {
+			return err
+		}
 	}
 	if r.Chrom == "" {
 		err := NewValidationError("chrom", "chrom is required")
@@ -86,31 +1049,113 @@ func (r *BigWigRequest) Validate() *APIError {
 		err := NewValidationError("end", "end must be greater than start")
 		return &err
 	}
-	if r.PreRenderedWidth < 0 {
-		err := NewValidationError("preRenderedWidth", "preRenderedWidth must be >= 0")
+	if len(r.Samples) > 0 && !r.Genotypes {
+		err := NewValidationError("samples", "samples requires genotypes")
 		return &err
 	}
 	return nil
 }
 
-type BigBedRequest struct {
-	URL   string `json:"url"`
-	Chrom string `json:"chrom"`
-	Start int    `json:"start"`
-	End   int    `json:"end"`
-	Type  string `json:"type,omitempty"` // "ccre", "generic" | used for parsing non-universal columns
+type BAMRequest struct {
+	URL            string `json:"url"`             // http(s) URL or path relative to the local data directory
+	Index          string `json:"index,omitempty"` // Index location, defaults to url + ".bai"
+	Chrom          string `json:"chrom"`
+	Start          int    `json:"start"`
+	End            int    `json:"end"`
+	Mode           string `json:"mode,omitempty"`           // "auto" (default), "coverage" or "reads"
+	Bins           int    `json:"bins,omitempty"`           // Number of coverage bins
+	MinMapQ        int    `json:"minMapQ,omitempty"`        // Skip alignments below this mapping quality
+	SamplingWindow int    `json:"samplingWindow,omitempty"` // Downsampling bucket in bp
+	SamplingDepth  int    `json:"samplingDepth,omitempty"`  // Reads kept per downsampling bucket
 }
 
-// Validate checks BigBedRequest fields
-func (r *BigBedRequest) Validate() *APIError {
+// Validate checks BAMRequest fields
+func (r *BAMRequest) Validate() *APIError {
 	if r.URL == "" {
 		err := NewValidationError("url", "url is required")
 		return &err
 	}
-	if _, parseErr := url.ParseRequestURI(r.URL); parseErr != nil {
-		err := NewValidationError("url", fmt.Sprintf("invalid url: %s", parseErr.Error()))
+	if err := validateSource("url", r.URL); err != nil {
+		return err
+	}
+	if r.Index != "" {
+		if err := validateSource("index", r.Index); err != nil {
+			return err
+		}
+	}
+	if r.Chrom == "" {
+		err := NewValidationError("chrom", "chrom is required")
+		return &err
+	}
+	if !chromRegex.MatchString(r.Chrom) {
+		err := NewValidationError("chrom", fmt.Sprintf("invalid chromosome format: %s", r.Chrom))
+		return &err
+	}
+	if r.Start < 0 {
+		err := NewValidationError("start", "start must be >= 0")
+		return &err
+	}
+	if r.End <= r.Start {
+		err := NewValidationError("end", "end must be greater than start")
+		return &err
+	}
+	return validateBAMOptions(r.End-r.Start, r.Mode, r.Bins, r.MinMapQ, r.SamplingWindow, r.SamplingDepth)
+}
+
+// validateBAMOptions checks the display options shared by BAM requests and configs
+func validateBAMOptions(width int, mode string, bins, minMapQ, samplingWindow, samplingDepth int) *APIError {
+	switch mode {
+	case "", bam.ModeAuto, bam.ModeCoverage, bam.ModeReads:
+	default:
+		err := NewValidationError("mode", fmt.Sprintf("unknown mode %s", mode))
+		return &err
+	}
+	if width > bam.MAX_COVERAGE_WINDOW {
+		err := NewValidationError("end", fmt.Sprintf("region must be at most %d bp", bam.MAX_COVERAGE_WINDOW))
+		return &err
+	}
+	if mode == bam.ModeReads && width > bam.MAX_READ_WINDOW {
+		err := NewValidationError("mode", fmt.Sprintf("reads are only available for regions up to %d bp", bam.MAX_READ_WINDOW))
+		return &err
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+	}{{"bins", bins}, {"minMapQ", minMapQ}, {"samplingWindow", samplingWindow}, {"samplingDepth", samplingDepth}} {
+		if f.value < 0 {
+			err := NewValidationError(f.name, fmt.Sprintf("%s must be >= 0", f.name))
+			return &err
+		}
+	}
+	return nil
+}
+
+type JunctionRequest struct {
+	URL      string `json:"url"`             // http(s) URL or path relative to the local data directory
+	Index    string `json:"index,omitempty"` // Index location, defaults to url + ".bai"
+	Chrom    string `json:"chrom"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	MinCount int    `json:"minCount,omitempty"` // Drop junctions with fewer supporting reads
+	MinMapQ  int    `json:"minMapQ,omitempty"`  // Skip alignments below this mapping quality
+	Annotate bool   `json:"annotate,omitempty"` // Mark junctions as known or novel using transcript introns
+	Assembly string `json:"assembly,omitempty"` // Annotation used with annotate, defaults to the configured default
+}
+
+// Validate checks JunctionRequest fields
+func (r *JunctionRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
 		return &err
 	}
+	if err := validateSource("url", r.URL); err != nil {
+		return err
+	}
+	if r.Index != "" {
+		if err := validateSource("index", r.Index); err != nil {
+			return err
+		}
+	}
 	if r.Chrom == "" {
 		err := NewValidationError("chrom", "chrom is required")
 		return &err
@@ -127,17 +1172,51 @@ func (r *BigBedRequest) Validate() *APIError {
 		err := NewValidationError("end", "end must be greater than start")
 		return &err
 	}
+	if err := validateJunctionOptions(r.End-r.Start, r.MinCount, r.MinMapQ); err != nil {
+		return err
+	}
+	return validateAssembly(r.Assembly)
+}
+
+// validateJunctionOptions checks the options shared by junction requests and configs
+func validateJunctionOptions(width int, minCount, minMapQ int) *APIError {
+	if width > bam.MAX_JUNCTION_WINDOW {
+		err := NewValidationError("end", fmt.Sprintf("region must be at most %d bp", bam.MAX_JUNCTION_WINDOW))
+		return &err
+	}
+	if minCount < 0 {
+		err := NewValidationError("minCount", "minCount must be >= 0")
+		return &err
+	}
+	if minMapQ < 0 {
+		err := NewValidationError("minMapQ", "minMapQ must be >= 0")
+		return &err
+	}
 	return nil
 }
 
-type TranscriptRequest struct {
-	Chrom string `json:"chrom"`
-	Start int    `json:"start"`
-	End   int    `json:"end"`
+type HiCRequest struct {
+	URL           string `json:"url"` // http(s) URL or path relative to the local data directory
+	Chrom         string `json:"chrom"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+	Chrom2        string `json:"chrom2,omitempty"` // Second region, defaults to the first
+	Start2        int    `json:"start2,omitempty"`
+	End2          int    `json:"end2,omitempty"`
+	Resolution    int32  `json:"resolution,omitempty"`    // Bin size in bp, defaults to the finest that fits the region
+	Normalization string `json:"normalization,omitempty"` // "NONE" (default), "KR" or "VC"
+	Format        string `json:"format,omitempty"`        // "records" (default) or "matrix"
 }
 
-// Validate checks TranscriptRequest fields
-func (r *TranscriptRequest) Validate() *APIError {
+// Validate checks HiCRequest fields
+func (r *HiCRequest) Validate() *APIError {
+	if r.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", r.URL); err != nil {
+		return err
+	}
 	if r.Chrom == "" {
 		err := NewValidationError("chrom", "chrom is required")
 		return &err
@@ -154,15 +1233,61 @@ func (r *TranscriptRequest) Validate() *APIError {
 		err := NewValidationError("end", "end must be greater than start")
 		return &err
 	}
+	if r.Chrom2 != "" {
+		if !chromRegex.MatchString(r.Chrom2) {
+			err := NewValidationError("chrom2", fmt.Sprintf("invalid chromosome format: %s", r.Chrom2))
+			return &err
+		}
+		if r.Start2 < 0 {
+			err := NewValidationError("start2", "start2 must be >= 0")
+			return &err
+		}
+		if r.End2 <= r.Start2 {
+			err := NewValidationError("end2", "end2 must be greater than start2")
+			return &err
+		}
+	}
+	return validateHiCOptions(r.Resolution, r.Normalization, r.Format)
+}
+
+// Region2 returns the second region of the query, which defaults to the first
+func (r *HiCRequest) Region2() (string, int, int) {
+	if r.Chrom2 == "" {
+		return r.Chrom, r.Start, r.End
+	}
+	return r.Chrom2, r.Start2, r.End2
+}
+
+// validateHiCOptions checks the options shared by Hi-C requests and configs
+func validateHiCOptions(resolution int32, normalization, format string) *APIError {
+	if resolution < 0 {
+		err := NewValidationError("resolution", "resolution must be >= 0")
+		return &err
+	}
+	switch normalization {
+	case "", hic.NormNone, hic.NormKR, hic.NormVC:
+	default:
+		err := NewValidationError("normalization", fmt.Sprintf("unknown normalization %s", normalization))
+		return &err
+	}
+	switch format {
+	case "", hic.FormatRecords, hic.FormatMatrix:
+	default:
+		err := NewValidationError("format", fmt.Sprintf("unknown format %s", format))
+		return &err
+	}
 	return nil
 }
 
 // Browser endpoint
 type BrowserRequest struct {
-	Chrom  string  `json:"chrom"`
-	Start  int     `json:"start"`
-	End    int     `json:"end"`
-	Tracks []Track `json:"tracks"`
+	Chrom         string  `json:"chrom"`
+	Start         int     `json:"start"`
+	End           int     `json:"end"`
+	Tracks        []Track `json:"tracks"`
+	Assembly      string  `json:"assembly,omitempty"`      // Assembly of chrom, start and end when it differs from the tracks'
+	TrackAssembly string  `json:"trackAssembly,omitempty"` // Assembly of the tracks, the region is lifted to it from assembly
+	LiftMinMatch  float64 `json:"liftMinMatch,omitempty"`  // Fraction of the region that must align when lifted, defaults to 0.95
 }
 
 // Validate checks BrowserRequest fields
@@ -187,18 +1312,55 @@ func (r *BrowserRequest) Validate() *APIError {
 		err := NewValidationError("tracks", "at least one track is required")
 		return &err
 	}
+	if !r.NeedsLiftover() {
+		return nil
+	}
+	if !liftover.HasChain(r.Assembly, r.TrackAssembly) {
+		err := NewValidationError("trackAssembly", liftover.UnknownChainError(r.Assembly, r.TrackAssembly).Error())
+		return &err
+	}
+	if r.LiftMinMatch < 0 || r.LiftMinMatch > 1 {
+		err := NewValidationError("liftMinMatch", "liftMinMatch must be between 0 and 1")
+		return &err
+	}
 	return nil
 }
 
+// NeedsLiftover reports whether the region is given in another assembly than
+// the tracks
+func (r *BrowserRequest) NeedsLiftover() bool {
+	return r.Assembly != "" && r.TrackAssembly != "" && r.Assembly != r.TrackAssembly
+}
+
 type TrackResponse struct {
 	ID    string `json:"id,omitempty"`
 	Type  string `json:"type,omitempty"`
 	Data  any    `json:"data"`
+	Meta  any    `json:"meta,omitempty"` // How the data was derived, e.g. BigWigMeta
 	Error string `json:"error,omitempty"`
 }
 
+// BigWigMeta describes how bigWig values were changed before returning them
+type BigWigMeta struct {
+	Smoothing     *bigwig.Smoothing     `json:"smoothing,omitempty"`
+	Normalization *bigwig.Normalization `json:"normalization,omitempty"`
+}
+
+// SignalRegionsMeta describes the threshold regions were called above
+type SignalRegionsMeta struct {
+	Threshold  float64 `json:"threshold"`
+	Percentile float64 `json:"percentile,omitempty"` // Percentile the threshold estimates
+}
+
+// withMeta is track data returned with response metadata
+type withMeta struct {
+	data any
+	meta any
+}
+
 type BrowserResponse struct {
-	Data []TrackResponse `json:"data"`
+	Data     []TrackResponse  `json:"data"`
+	Liftover *liftover.Result `json:"liftover,omitempty"` // How the region was lifted to the track assembly
 }
 
 // Track configurations
@@ -209,24 +1371,318 @@ type Track struct {
 }
 
 type BigWigConfig struct {
-	URL              string `json:"url"`
-	PreRenderedWidth int    `json:"preRenderedWidth,omitempty"`
+	URL              string  `json:"url"`
+	PreRenderedWidth int     `json:"preRenderedWidth,omitempty"`
+	Smoothing        string  `json:"smoothing,omitempty"`
+	SmoothingWindow  int     `json:"smoothingWindow,omitempty"`
+	Normalization    string  `json:"normalization,omitempty"`
+	ScaleFactor      float64 `json:"scaleFactor,omitempty"`
+	Transform        string  `json:"transform,omitempty"`
+	ClipPercentile   float64 `json:"clipPercentile,omitempty"`
+}
+
+// Validate checks BigWigConfig fields
+func (c *BigWigConfig) Validate() *APIError {
+	if err := validateURL("url", c.URL); err != nil {
+		return err
+	}
+	if err := validateSmoothOptions(c.Smoothing, c.SmoothingWindow); err != nil {
+		return err
+	}
+	return validateNormalizeOptions(c.Normalization, c.ScaleFactor, c.Transform, c.ClipPercentile)
+}
+
+// Smooth returns the smoothing options of the config
+func (c *BigWigConfig) Smooth() bigwig.SmoothOptions {
+	return bigwig.SmoothOptions{Method: c.Smoothing, Window: c.SmoothingWindow}
+}
+
+// Normalize returns the normalization options of the config
+func (c *BigWigConfig) Normalize() bigwig.NormalizeOptions {
+	return bigwig.NormalizeOptions{Method: c.Normalization, ScaleFactor: c.ScaleFactor, Transform: c.Transform, ClipPercentile: c.ClipPercentile}
+}
+
+type CompositeConfig struct {
+	URLs             []string `json:"urls"`
+	PreRenderedWidth int      `json:"preRenderedWidth,omitempty"`
+	Operation        string   `json:"operation"`
+	Pseudocount      float64  `json:"pseudocount,omitempty"`
+}
+
+// Validate checks CompositeConfig fields
+func (c *CompositeConfig) Validate() *APIError {
+	return validateCompositeOptions(c.URLs, c.Operation, c.Pseudocount)
+}
+
+// Options returns the combining options of the config
+func (c *CompositeConfig) Options() bigwig.CompositeOptions {
+	return bigwig.CompositeOptions{Operation: c.Operation, Pseudocount: c.Pseudocount}
+}
+
+type SignalRegionsConfig struct {
+	URL           string   `json:"url"`
+	Threshold     *float64 `json:"threshold,omitempty"`
+	Percentile    float64  `json:"percentile,omitempty"`
+	MinLength     int      `json:"minLength,omitempty"`
+	MergeDistance int      `json:"mergeDistance,omitempty"`
+}
+
+// Validate checks SignalRegionsConfig fields
+func (c *SignalRegionsConfig) Validate() *APIError {
+	if err := validateURL("url", c.URL); err != nil {
+		return err
+	}
+	return validateRegionOptions(c.Threshold, c.Percentile, c.MinLength, c.MergeDistance)
+}
+
+// Options returns the region calling options of the config, without the
+// threshold of a percentile
+func (c *SignalRegionsConfig) Options() bigwig.RegionOptions {
+	return regionOptions(c.Threshold, c.MinLength, c.MergeDistance)
 }
 
 type BigBedConfig struct {
-	URL  string `json:"url"`
-	Type string `json:"type,omitempty"`
+	URL       string `json:"url"`
+	Type      string `json:"type,omitempty"`
+	Layout    bool   `json:"layout,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	CharWidth int    `json:"charWidth,omitempty"`
+	MaxRows   int    `json:"maxRows,omitempty"`
 }
 
-type Assembly string
+// Validate checks BigBedConfig fields
+func (c *BigBedConfig) Validate() *APIError {
+	if err := validateURL("url", c.URL); err != nil {
+		return err
+	}
+	return validateLayoutOptions(c.Width, c.CharWidth, c.MaxRows)
+}
 
-const (
-	Human Assembly = "grch38"
-	Mouse Assembly = "mm10"
-)
+// Packing returns the row layout options of the config over a region, nil
+// without layout
+func (c *BigBedConfig) Packing(start, end int) *layout.Options {
+	if !c.Layout {
+		return nil
+	}
+	return &layout.Options{Width: c.Width, Start: start, End: end, CharWidth: c.CharWidth, MaxRows: c.MaxRows}
+}
+
+type BigChainConfig struct {
+	URL     string `json:"url"`
+	LinkURL string `json:"linkUrl,omitempty"`
+}
+
+// Validate checks BigChainConfig fields
+func (c *BigChainConfig) Validate() *APIError {
+	return validateBigChainURLs(c.URL, c.LinkURL)
+}
+
+type BigMafConfig struct {
+	URL     string   `json:"url"`
+	Species []string `json:"species,omitempty"`
+}
+
+// Validate checks BigMafConfig fields
+func (c *BigMafConfig) Validate() *APIError {
+	return validateURL("url", c.URL)
+}
+
+type TabixConfig struct {
+	URL     string         `json:"url"`
+	Index   string         `json:"index,omitempty"`
+	Format  string         `json:"format,omitempty"`
+	Columns []tabix.Column `json:"columns,omitempty"`
+}
+
+// Validate checks TabixConfig fields
+func (c *TabixConfig) Validate() *APIError {
+	if c.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", c.URL); err != nil {
+		return err
+	}
+	if c.Index != "" {
+		if err := validateSource("index", c.Index); err != nil {
+			return err
+		}
+	}
+	if _, schemaErr := tabix.SchemaFor(c.Format, c.Columns); schemaErr != nil {
+		err := NewValidationError("format", schemaErr.Error())
+		return &err
+	}
+	return nil
+}
+
+type VCFConfig struct {
+	URL       string   `json:"url"`
+	Index     string   `json:"index,omitempty"`
+	Info      []string `json:"info,omitempty"`
+	Genotypes bool     `json:"genotypes,omitempty"`
+	Samples   []string `json:"samples,omitempty"`
+}
+
+// Validate checks VCFConfig fields
+func (c *VCFConfig) Validate() *APIError {
+	if c.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", c.URL); err != nil {
+		return err
+	}
+	if c.Index != "" {
+		if err := validateSource("index", c.Index); err != nil {
+			return err
+		}
+	}
+	if len(c.Samples) > 0 && !c.Genotypes {
+		err := NewValidationError("samples", "samples requires genotypes")
+		return &err
+	}
+	return nil
+}
+
+type BAMConfig struct {
+	URL            string `json:"url"`
+	Index          string `json:"index,omitempty"`
+	Mode           string `json:"mode,omitempty"`
+	Bins           int    `json:"bins,omitempty"`
+	MinMapQ        int    `json:"minMapQ,omitempty"`
+	SamplingWindow int    `json:"samplingWindow,omitempty"`
+	SamplingDepth  int    `json:"samplingDepth,omitempty"`
+}
+
+// Validate checks BAMConfig fields for a region of the given width
+func (c *BAMConfig) Validate(width int) *APIError {
+	if c.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", c.URL); err != nil {
+		return err
+	}
+	if c.Index != "" {
+		if err := validateSource("index", c.Index); err != nil {
+			return err
+		}
+	}
+	return validateBAMOptions(width, c.Mode, c.Bins, c.MinMapQ, c.SamplingWindow, c.SamplingDepth)
+}
+
+type JunctionConfig struct {
+	URL      string `json:"url"`
+	Index    string `json:"index,omitempty"`
+	MinCount int    `json:"minCount,omitempty"`
+	MinMapQ  int    `json:"minMapQ,omitempty"`
+	Annotate bool   `json:"annotate,omitempty"`
+	Assembly string `json:"assembly,omitempty"`
+}
+
+// Validate checks JunctionConfig fields for a region of the given width
+func (c *JunctionConfig) Validate(width int) *APIError {
+	if c.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", c.URL); err != nil {
+		return err
+	}
+	if c.Index != "" {
+		if err := validateSource("index", c.Index); err != nil {
+			return err
+		}
+	}
+	if err := validateJunctionOptions(width, c.MinCount, c.MinMapQ); err != nil {
+		return err
+	}
+	return validateAssembly(c.Assembly)
+}
+
+type HiCConfig struct {
+	URL           string `json:"url"`
+	Resolution    int32  `json:"resolution,omitempty"`
+	Normalization string `json:"normalization,omitempty"`
+	Format        string `json:"format,omitempty"`
+}
+
+// Validate checks HiCConfig fields
+func (c *HiCConfig) Validate() *APIError {
+	if c.URL == "" {
+		err := NewValidationError("url", "url is required")
+		return &err
+	}
+	if err := validateSource("url", c.URL); err != nil {
+		return err
+	}
+	return validateHiCOptions(c.Resolution, c.Normalization, c.Format)
+}
 
 type TranscriptConfig struct {
-	Assembly string `json:"assembly"`
+	Assembly     string   `json:"assembly"`
+	GeneTypes    []string `json:"geneTypes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	SupportLevel int      `json:"supportLevel,omitempty"`
+	OnePerGene   bool     `json:"onePerGene,omitempty"`
+	Mode         string   `json:"mode,omitempty"`
+	Version      int      `json:"version,omitempty"`
+	Width        int      `json:"width,omitempty"`
+	CharWidth    int      `json:"charWidth,omitempty"`
+	MaxRows      int      `json:"maxRows,omitempty"`
+}
+
+// Validate checks TranscriptConfig fields
+func (c *TranscriptConfig) Validate() *APIError {
+	if err := validateTranscriptOptions(c.Version, c.Mode, c.GeneTypes, c.Tags, c.SupportLevel); err != nil {
+		return err
+	}
+	if err := validateLayoutOptions(c.Width, c.CharWidth, c.MaxRows); err != nil {
+		return err
+	}
+	return validateAssembly(c.Assembly)
+}
+
+// Filter returns the transcript filter of the config
+func (c *TranscriptConfig) Filter() transcript.Filter {
+	return transcript.Filter{GeneTypes: c.GeneTypes, Tags: c.Tags, SupportLevel: c.SupportLevel, OnePerGene: c.OnePerGene}
+}
+
+// Packing returns the row layout options of the config over a region
+func (c *TranscriptConfig) Packing(start, end int) layout.Options {
+	return layout.Options{Width: c.Width, Start: start, End: end, CharWidth: c.CharWidth, MaxRows: c.MaxRows}
+}
+
+type DerivedConfig struct {
+	Assembly     string   `json:"assembly"`
+	Kind         string   `json:"kind"`
+	Upstream     *int     `json:"upstream,omitempty"`
+	Downstream   *int     `json:"downstream,omitempty"`
+	GeneTypes    []string `json:"geneTypes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	SupportLevel int      `json:"supportLevel,omitempty"`
+	OnePerGene   bool     `json:"onePerGene,omitempty"`
+}
+
+// Validate checks DerivedConfig fields
+func (c *DerivedConfig) Validate() *APIError {
+	if err := validateDerivedOptions(c.Kind, c.Upstream, c.Downstream); err != nil {
+		return err
+	}
+	if err := validateTranscriptOptions(0, "", c.GeneTypes, c.Tags, c.SupportLevel); err != nil {
+		return err
+	}
+	return validateAssembly(c.Assembly)
+}
+
+// Filter returns the transcript filter of the config
+func (c *DerivedConfig) Filter() transcript.Filter {
+	return transcript.Filter{GeneTypes: c.GeneTypes, Tags: c.Tags, SupportLevel: c.SupportLevel, OnePerGene: c.OnePerGene}
+}
+
+// Options returns the derived feature options of the config
+func (c *DerivedConfig) Options() transcript.DeriveOptions {
+	return transcript.DeriveOptions{Kind: c.Kind, Upstream: c.Upstream, Downstream: c.Downstream}
 }
 
 func (t *Track) GetBigWigConfig() (BigWigConfig, error) {
@@ -235,14 +1691,74 @@ func (t *Track) GetBigWigConfig() (BigWigConfig, error) {
 	return config, err
 }
 
+func (t *Track) GetCompositeConfig() (CompositeConfig, error) {
+	var config CompositeConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetSignalRegionsConfig() (SignalRegionsConfig, error) {
+	var config SignalRegionsConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
 func (t *Track) GetBigBedConfig() (BigBedConfig, error) {
 	var config BigBedConfig
 	err := json.Unmarshal(t.Config, &config)
 	return config, err
 }
 
+func (t *Track) GetBigChainConfig() (BigChainConfig, error) {
+	var config BigChainConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetBigMafConfig() (BigMafConfig, error) {
+	var config BigMafConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetTabixConfig() (TabixConfig, error) {
+	var config TabixConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetVCFConfig() (VCFConfig, error) {
+	var config VCFConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetBAMConfig() (BAMConfig, error) {
+	var config BAMConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetJunctionConfig() (JunctionConfig, error) {
+	var config JunctionConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
+func (t *Track) GetHiCConfig() (HiCConfig, error) {
+	var config HiCConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
+
 func (t *Track) GetTranscriptConfig() (TranscriptConfig, error) {
 	var config TranscriptConfig
 	err := json.Unmarshal(t.Config, &config)
 	return config, err
 }
+
+func (t *Track) GetDerivedConfig() (DerivedConfig, error) {
+	var config DerivedConfig
+	err := json.Unmarshal(t.Config, &config)
+	return config, err
+}
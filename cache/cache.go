@@ -35,6 +35,13 @@ func (c *Cache[T]) Get(key string) (val T, hit bool) {
 	return val, hit
 }
 
+func (c *Cache[T]) Remove(key string) (present bool) {
+	c.Mu.Lock()
+	present = c.Cache.Remove(key)
+	c.Mu.Unlock()
+	return present
+}
+
 func (c *Cache[T]) Len() (length int) {
 	return c.Cache.Len()
 }
@@ -18,6 +18,21 @@ type Config struct {
 
 	// Cache settings
 	CacheSize int
+
+	// Data settings
+	LocalDataDir string // Root directory for track files given as local paths
+
+	// Transcript annotation settings
+	TranscriptDataDir     string // Root directory for annotation files given as relative paths
+	TranscriptAnnotations string // Comma-separated assembly=path or assembly=URL entries, empty for the built-in list
+	DefaultAssembly       string // Assembly used by transcript requests without one
+	TranscriptPreload     string // Comma-separated assemblies to hold in memory, empty for none
+
+	// How often annotation files that were read are checked for changes, 0 to never
+	TranscriptReloadInterval time.Duration
+
+	// Liftover settings
+	LiftoverChains string // Comma-separated from:to=path or from:to=URL chain files, relative to LocalDataDir
 }
 
 // Default configuration values
@@ -29,6 +44,10 @@ var (
 	DefaultMaxRequestBody  = int64(1 << 20) // 1 MB
 	DefaultShutdownTimeout = 30 * time.Second
 	DefaultCacheSize       = 250
+	DefaultLocalDataDir    = "./data"
+	DefaultTranscriptDir   = "./track/transcript/data"
+	DefaultAssembly        = "grch38"
+	DefaultReloadInterval  = 60 * time.Second
 )
 
 // Load reads configuration from environment variables with defaults
@@ -46,6 +65,16 @@ func Load() *Config {
 		MaxRequestBody:  getInt64Env("MAX_REQUEST_BODY", DefaultMaxRequestBody),
 		ShutdownTimeout: getDurationEnv("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout),
 		CacheSize:       getIntEnv("CACHE_SIZE", DefaultCacheSize),
+		LocalDataDir:    GetLocalDataDir(),
+
+		TranscriptDataDir:     getEnvOrDefault("TRANSCRIPT_DATA_DIR", DefaultTranscriptDir),
+		TranscriptAnnotations: os.Getenv("TRANSCRIPT_ANNOTATIONS"),
+		DefaultAssembly:       getEnvOrDefault("DEFAULT_ASSEMBLY", DefaultAssembly),
+		TranscriptPreload:     os.Getenv("TRANSCRIPT_PRELOAD"),
+
+		TranscriptReloadInterval: getDurationEnv("TRANSCRIPT_RELOAD_INTERVAL", DefaultReloadInterval),
+
+		LiftoverChains: os.Getenv("LIFTOVER_CHAINS"),
 	}
 }
 
@@ -55,6 +84,11 @@ func GetCacheSize() int {
 	return getIntEnv("CACHE_SIZE", DefaultCacheSize)
 }
 
+// GetLocalDataDir returns the root directory for local track files
+func GetLocalDataDir() string {
+	return getEnvOrDefault("LOCAL_DATA_DIR", DefaultLocalDataDir)
+}
+
 // getEnvOrDefault returns the environment variable value or a default
 func getEnvOrDefault(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
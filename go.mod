@@ -3,14 +3,12 @@ module gb-api
 go 1.25.4
 
 require (
+	github.com/biogo/hts v1.4.5
 	github.com/brentp/bix v0.0.0-20250701183917-000f089eabc0
 	github.com/brentp/irelate v0.0.1
+	github.com/brentp/vcfgo v0.0.0-20250902214554-a31336cef488
 	github.com/hashicorp/golang-lru/v2 v2.0.7
+	golang.org/x/time v0.14.0
 )
 
-require (
-	github.com/biogo/hts v1.4.5 // indirect
-	github.com/brentp/vcfgo v0.0.0-20250902214554-a31336cef488 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/time v0.14.0 // indirect
-)
+require github.com/pkg/errors v0.9.1 // indirect
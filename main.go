@@ -5,10 +5,13 @@ import (
 	"gb-api/api"
 	"gb-api/api/middleware"
 	"gb-api/config"
+	"gb-api/track/liftover"
+	"gb-api/track/transcript"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 )
 
@@ -27,8 +30,21 @@ func addRoutes(m *http.ServeMux) {
 
 	// API endpoints
 	m.HandleFunc(apiVersion+"/bigwig", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BigWigHandler)))
+	m.HandleFunc(apiVersion+"/composite", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.CompositeHandler)))
+	m.HandleFunc(apiVersion+"/signalregions", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.SignalRegionsHandler)))
+	m.HandleFunc(apiVersion+"/matrix", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.MatrixHandler)))
 	m.HandleFunc(apiVersion+"/bigbed", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BigBedHandler)))
+	m.HandleFunc(apiVersion+"/bigchain", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BigChainHandler)))
+	m.HandleFunc(apiVersion+"/bigmaf", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BigMafHandler)))
+	m.HandleFunc(apiVersion+"/tabix", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.TabixHandler)))
+	m.HandleFunc(apiVersion+"/vcf", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.VCFHandler)))
+	m.HandleFunc(apiVersion+"/bam", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BAMHandler)))
+	m.HandleFunc(apiVersion+"/junctions", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.JunctionHandler)))
+	m.HandleFunc(apiVersion+"/hic", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.HiCHandler)))
 	m.HandleFunc(apiVersion+"/transcript", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.TranscriptHandler)))
+	m.HandleFunc(apiVersion+"/derived", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.DerivedHandler)))
+	m.HandleFunc(apiVersion+"/liftover", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.LiftoverHandler)))
+	m.HandleFunc(apiVersion+"/search", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.SearchHandler)))
 	m.HandleFunc(apiVersion+"/browser", middleware.CORSMiddleware(middleware.RateLimitMiddleware(api.BrowserHandler)))
 
 	// Admin endpoints (unversioned)
@@ -49,6 +65,48 @@ func main() {
 	// Load configuration from environment
 	cfg = config.Load()
 
+	// Register transcript annotations, replacing the built-in list if given
+	annotations := transcript.DefaultAnnotations
+	if cfg.TranscriptAnnotations != "" {
+		var err error
+		if annotations, err = transcript.ParseAnnotations(cfg.TranscriptAnnotations); err != nil {
+			slog.Error("Invalid transcript annotations", "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := transcript.Configure(cfg.TranscriptDataDir, annotations, cfg.DefaultAssembly); err != nil {
+		slog.Error("Could not configure transcript annotations", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Transcript annotations configured", "assemblies", transcript.Assemblies(), "default", cfg.DefaultAssembly)
+
+	// Hold chosen annotations in memory, and reload any annotation whose file changes
+	ctx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.TranscriptPreload != "" {
+		if err := transcript.Preload(strings.Split(cfg.TranscriptPreload, ",")); err != nil {
+			slog.Error("Could not preload transcript annotations", "error", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.TranscriptReloadInterval > 0 {
+		go transcript.WatchIndexes(ctx, cfg.TranscriptReloadInterval)
+	}
+
+	// Register chain files for liftover, read on first use
+	chains, err := liftover.ParseChains(cfg.LiftoverChains)
+	if err != nil {
+		slog.Error("Invalid liftover chains", "error", err)
+		os.Exit(1)
+	}
+	if err := liftover.Configure(cfg.LocalDataDir, chains); err != nil {
+		slog.Error("Could not configure liftover chains", "error", err)
+		os.Exit(1)
+	}
+	if len(chains) > 0 {
+		slog.Info("Liftover chains configured", "chains", len(chains))
+	}
+
 	mux := http.NewServeMux()
 	addRoutes(mux)
 
@@ -0,0 +1,236 @@
+// Package bam reads alignments from indexed BAM files for coverage and read tracks
+package bam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gb-api/track/bigdata"
+	"strings"
+	"sync"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/bgzf/index"
+	"github.com/biogo/hts/sam"
+)
+
+// BAM is an opened, indexed BAM file
+type BAM struct {
+	Path   string
+	Index  *bam.Index
+	Header *sam.Header
+
+	refs map[string]*sam.Reference
+	mu   sync.Mutex // Index chunk lookups sort bins lazily and are not safe for concurrent use
+}
+
+// New loads the index and header for path. indexPath may be empty to use
+// path+".bai", falling back to the path with ".bam" replaced by ".bai".
+func New(path string, indexPath string) (*BAM, error) {
+	idx, err := loadIndex(path, indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := bigdata.OpenSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	r, err := bam.NewReader(src, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BAM header from %s: %w", path, err)
+	}
+	defer r.Close()
+
+	b := &BAM{Path: path, Index: idx, Header: r.Header(), refs: map[string]*sam.Reference{}}
+	for _, ref := range b.Header.Refs() {
+		b.refs[ref.Name()] = ref
+	}
+	return b, nil
+}
+
+func loadIndex(path string, indexPath string) (*bam.Index, error) {
+	candidates := []string{indexPath}
+	if indexPath == "" {
+		candidates = []string{path + ".bai"}
+		if trimmed, ok := strings.CutSuffix(path, ".bam"); ok {
+			candidates = append(candidates, trimmed+".bai")
+		}
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		data, err := bigdata.ReadSource(candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		idx, err := bam.ReadIndex(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse BAM index %s: %w", candidate, err)
+		}
+		if idx == nil {
+			return nil, fmt.Errorf("BAM index %s has no references", candidate)
+		}
+		return idx, nil
+	}
+	return nil, fmt.Errorf("failed to read index for %s: %w", path, lastErr)
+}
+
+// reference finds chrom in the header, with or without a "chr" prefix
+func (b *BAM) reference(chrom string) *sam.Reference {
+	if ref, ok := b.refs[chrom]; ok {
+		return ref
+	}
+	if trimmed, ok := strings.CutPrefix(chrom, "chr"); ok {
+		return b.refs[trimmed]
+	}
+	return b.refs["chr"+chrom]
+}
+
+// Query calls fn for each alignment overlapping [start, end) on chrom, in
+// coordinate order. Unmapped, secondary, QC-failed and duplicate reads are skipped.
+func (b *BAM) Query(chrom string, start, end int, fn func(*sam.Record)) error {
+	ref := b.reference(chrom)
+	if ref == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	chunks, err := b.Index.Chunks(ref, start, end)
+	b.mu.Unlock()
+	switch {
+	case errors.Is(err, index.ErrInvalid):
+		// Region starts past the last indexed alignment
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to query index: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	src, err := bigdata.OpenSource(b.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", b.Path, err)
+	}
+	defer src.Close()
+
+	r, err := bam.NewReader(src, 1)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", b.Path, err)
+	}
+	defer r.Close()
+
+	it, err := bam.NewIterator(r, chunks)
+	if err != nil {
+		return fmt.Errorf("failed to seek in %s: %w", b.Path, err)
+	}
+	defer it.Close()
+
+	for it.Next() {
+		rec := it.Record()
+		if rec.Ref == nil || rec.Ref.ID() != ref.ID() {
+			continue
+		}
+		// Records are sorted by start, so nothing further can overlap
+		if rec.Pos >= end {
+			break
+		}
+		if rec.End() <= start || rec.Flags&(sam.Unmapped|sam.Secondary|sam.QCFail|sam.Duplicate) != 0 {
+			continue
+		}
+		fn(rec)
+	}
+	return it.Error()
+}
+
+const (
+	MAX_READ_WINDOW         = 30_000     // Widest region that returns individual reads
+	MAX_COVERAGE_WINDOW     = 10_000_000 // Widest region that returns coverage
+	DEFAULT_COVERAGE_BINS   = 1000
+	DEFAULT_SAMPLING_WINDOW = 50  // bp bucket for downsampling, by read start
+	DEFAULT_SAMPLING_DEPTH  = 100 // Reads kept per sampling bucket
+	READ_ROW_GAP            = 2   // Minimum bp between reads packed in the same row
+)
+
+// Display modes
+const (
+	ModeAuto     = "auto" // Reads up to MAX_READ_WINDOW, coverage only beyond it
+	ModeCoverage = "coverage"
+	ModeReads    = "reads"
+)
+
+// Options controls what is returned for a region
+type Options struct {
+	Mode           string // ModeAuto when empty
+	Bins           int    // Coverage bins across the region, DEFAULT_COVERAGE_BINS when 0
+	MinMapQ        int    // Skip alignments with a lower mapping quality
+	SamplingWindow int    // DEFAULT_SAMPLING_WINDOW when 0
+	SamplingDepth  int    // DEFAULT_SAMPLING_DEPTH when 0
+}
+
+// BAMData is coverage for a region and, for narrow regions, the packed reads.
+// Coverage counts every read that passes the filters, before downsampling.
+type BAMData struct {
+	Coverage    []CoverageBin `json:"coverage"`
+	Reads       []Read        `json:"reads,omitempty"`
+	Rows        int           `json:"rows,omitempty"`
+	TotalReads  int           `json:"totalReads"`            // Reads before downsampling
+	Downsampled []Block       `json:"downsampled,omitempty"` // Sampling buckets where reads were dropped
+}
+
+// IncludesReads reports whether a request for a region of this width returns reads
+func (o Options) IncludesReads(width int) bool {
+	switch o.Mode {
+	case ModeReads:
+		return true
+	case ModeCoverage:
+		return false
+	default:
+		return width <= MAX_READ_WINDOW
+	}
+}
+
+// Alignments returns coverage and, depending on the mode, downsampled and packed reads
+func (b *BAM) Alignments(chrom string, start, end int, opts Options) (BAMData, error) {
+	bins := opts.Bins
+	if bins <= 0 {
+		bins = DEFAULT_COVERAGE_BINS
+	}
+	window := opts.SamplingWindow
+	if window <= 0 {
+		window = DEFAULT_SAMPLING_WINDOW
+	}
+	depth := opts.SamplingDepth
+	if depth <= 0 {
+		depth = DEFAULT_SAMPLING_DEPTH
+	}
+	withReads := opts.IncludesReads(end - start)
+
+	coverage := newCoverageCounter(start, end, bins)
+	reads := []Read{}
+	total := 0
+	err := b.Query(chrom, start, end, func(rec *sam.Record) {
+		if int(rec.MapQ) < opts.MinMapQ {
+			return
+		}
+		total++
+		coverage.add(rec)
+		if withReads {
+			reads = append(reads, newRead(rec))
+		}
+	})
+	if err != nil {
+		return BAMData{}, err
+	}
+
+	data := BAMData{Coverage: coverage.bins(), TotalReads: total}
+	if withReads {
+		data.Reads, data.Downsampled = downsample(reads, window, depth, uint64(start))
+		data.Rows = packReads(data.Reads, READ_ROW_GAP)
+	}
+	return data, nil
+}
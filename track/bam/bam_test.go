@@ -0,0 +1,215 @@
+package bam
+
+import (
+	"fmt"
+	"gb-api/track/bam/bamtest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/biogo/hts/sam"
+)
+
+var refs = []bamtest.Reference{{Name: "chr1", Length: 100_000}, {Name: "chr2", Length: 50_000}}
+
+var alignments = []bamtest.Alignment{
+	{Name: "r1", Chrom: "chr1", Pos: 100, Cigar: "10M", Seq: "ACGTACGTAC", MapQ: 60, MD: "3A6"},
+	{Name: "r2", Chrom: "chr1", Pos: 105, Cigar: "5M2D5M", MapQ: 60, Flags: sam.Reverse},
+	{Name: "r3", Chrom: "chr1", Pos: 108, Cigar: "4M100N2I4M", MapQ: 10},
+	{Name: "dup", Chrom: "chr1", Pos: 110, Cigar: "10M", MapQ: 60, Flags: sam.Duplicate},
+	{Name: "r4", Chrom: "chr1", Pos: 5000, Cigar: "3M1X3M", Seq: "AAAGAAA", MapQ: 60},
+	{Name: "r5", Chrom: "chr2", Pos: 10, Cigar: "10M", MapQ: 60},
+}
+
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	return bamtest.WriteFile(t, t.TempDir(), "reads.bam", refs, alignments)
+}
+
+func readNames(reads []Read) []string {
+	names := make([]string, len(reads))
+	for i, r := range reads {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func TestQuery(t *testing.T) {
+	b, err := New(writeFixture(t), "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		chrom     string
+		start     int
+		end       int
+		wantNames []string
+	}{
+		{name: "overlapping reads skip duplicates", chrom: "chr1", start: 100, end: 120, wantNames: []string{"r1", "r2", "r3"}},
+		{name: "spliced read spans its intron", chrom: "chr1", start: 150, end: 160, wantNames: []string{"r3"}},
+		{name: "end is exclusive", chrom: "chr1", start: 0, end: 100, wantNames: []string{}},
+		{name: "chromosome without chr prefix", chrom: "2", start: 0, end: 100, wantNames: []string{"r5"}},
+		{name: "chromosome not in file", chrom: "chr3", start: 0, end: 100, wantNames: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			err := b.Query(tt.chrom, tt.start, tt.end, func(rec *sam.Record) {
+				names = append(names, rec.Name)
+			})
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Errorf("expected %v, got %v", tt.wantNames, names)
+			}
+		})
+	}
+}
+
+func TestAlignmentsReads(t *testing.T) {
+	data, err := GetCachedAlignments(writeFixture(t), "", "chr1", 100, 300, Options{})
+	if err != nil {
+		t.Fatalf("GetCachedAlignments() error = %v", err)
+	}
+	if data.TotalReads != 3 || len(data.Reads) != 3 {
+		t.Fatalf("expected 3 reads, got total %d, returned %v", data.TotalReads, readNames(data.Reads))
+	}
+
+	r1, r2, r3 := data.Reads[0], data.Reads[1], data.Reads[2]
+	if r1.Strand != "+" || r2.Strand != "-" {
+		t.Errorf("unexpected strands %s %s", r1.Strand, r2.Strand)
+	}
+	if len(r1.Mismatches) != 1 || r1.Mismatches[0].Pos != 103 || r1.Mismatches[0].Base != "T" {
+		t.Errorf("expected MD mismatch T at 103, got %+v", r1.Mismatches)
+	}
+	if len(r2.Blocks) != 2 || len(r2.Gaps) != 1 || r2.Gaps[0] != (Gap{Start: 110, End: 112, Type: "D"}) {
+		t.Errorf("unexpected deletion layout: %+v %+v", r2.Blocks, r2.Gaps)
+	}
+	if r3.End != 216 || r3.Gaps[0].Type != "N" || len(r3.Insertions) != 1 || r3.Insertions[0].Pos != 212 {
+		t.Errorf("unexpected spliced read: %+v", r3)
+	}
+	// r2 overlaps r1, and r3 overlaps both
+	if r1.Row != 0 || r2.Row != 1 || r3.Row != 2 || data.Rows != 3 {
+		t.Errorf("unexpected rows %d %d %d (total %d)", r1.Row, r2.Row, r3.Row, data.Rows)
+	}
+
+	// Coverage defaults to 1000 bins, so 1bp bins here
+	if len(data.Coverage) != 200 {
+		t.Fatalf("expected 200 coverage bins, got %d", len(data.Coverage))
+	}
+	if c := data.Coverage[8]; c.Depth != 3 || c.A+c.C+c.G+c.T+c.N != 3 {
+		t.Errorf("expected depth 3 at 108, got %+v", c)
+	}
+	if c := data.Coverage[1]; c.C != 1 || c.Depth != 1 {
+		t.Errorf("expected a single C at 101, got %+v", c)
+	}
+}
+
+func TestAlignmentsMismatchOperation(t *testing.T) {
+	data, err := GetCachedAlignments(writeFixture(t), "", "chr1", 4990, 5100, Options{})
+	if err != nil {
+		t.Fatalf("GetCachedAlignments() error = %v", err)
+	}
+	if len(data.Reads) != 1 || len(data.Reads[0].Mismatches) != 1 {
+		t.Fatalf("expected one read with one mismatch, got %+v", data.Reads)
+	}
+	if m := data.Reads[0].Mismatches[0]; m.Pos != 5003 || m.Base != "G" || m.Qual != 30 {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+}
+
+func TestAlignmentsModes(t *testing.T) {
+	path := writeFixture(t)
+
+	tests := []struct {
+		name      string
+		end       int
+		opts      Options
+		wantReads int
+		wantBins  int
+	}{
+		{name: "auto returns reads in narrow windows", end: 1000, wantReads: 3, wantBins: 1000},
+		{name: "auto returns coverage only in wide windows", end: 100_000, wantReads: 0, wantBins: 1000},
+		{name: "coverage mode", end: 1000, opts: Options{Mode: ModeCoverage, Bins: 10}, wantReads: 0, wantBins: 10},
+		{name: "minimum mapping quality", end: 1000, opts: Options{MinMapQ: 30}, wantReads: 2, wantBins: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GetCachedAlignments(path, "", "chr1", 0, tt.end, tt.opts)
+			if err != nil {
+				t.Fatalf("GetCachedAlignments() error = %v", err)
+			}
+			if len(data.Reads) != tt.wantReads {
+				t.Errorf("expected %d reads, got %d", tt.wantReads, len(data.Reads))
+			}
+			if len(data.Coverage) != tt.wantBins {
+				t.Errorf("expected %d bins, got %d", tt.wantBins, len(data.Coverage))
+			}
+		})
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	var reads []Read
+	for i := 0; i < 30; i++ {
+		reads = append(reads, Read{Name: fmt.Sprint(i), Start: int32(i), End: int32(i + 10)})
+	}
+	reads = append(reads, Read{Name: "far", Start: 1000, End: 1010})
+
+	kept, dropped := downsample(reads, 50, 5, 1)
+	if len(kept) != 6 {
+		t.Fatalf("expected 5 sampled reads plus the lone read, got %d", len(kept))
+	}
+	if kept[5].Name != "far" {
+		t.Errorf("expected the read in an unsaturated bucket to be kept, got %v", readNames(kept))
+	}
+	for i := 1; i < len(kept); i++ {
+		if kept[i].Start < kept[i-1].Start {
+			t.Fatal("downsampled reads should stay in coordinate order")
+		}
+	}
+	if len(dropped) != 1 || dropped[0].Start != 0 {
+		t.Errorf("expected one downsampled bucket at 0, got %+v", dropped)
+	}
+
+	again, _ := downsample(reads, 50, 5, 1)
+	if fmt.Sprint(readNames(again)) != fmt.Sprint(readNames(kept)) {
+		t.Error("downsampling should be stable for the same seed")
+	}
+}
+
+func TestMDMismatches(t *testing.T) {
+	tests := []struct {
+		md   string
+		want []int
+	}{
+		{md: "10", want: nil},
+		{md: "3A6", want: []int{3}},
+		{md: "0C5^AC2T0", want: []int{0, 8}},
+	}
+	for _, tt := range tests {
+		if got := mdMismatches(tt.md); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("mdMismatches(%q) = %v, want %v", tt.md, got, tt.want)
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	path := writeFixture(t)
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(path))))
+	defer server.Close()
+
+	data, err := GetCachedAlignments(server.URL+"/"+filepath.Base(path), "", "chr1", 100, 120, Options{})
+	if err != nil {
+		t.Fatalf("GetCachedAlignments() error = %v", err)
+	}
+	if data.TotalReads != 3 {
+		t.Errorf("expected 3 reads, got %d", data.TotalReads)
+	}
+}
@@ -0,0 +1,136 @@
+// Package bamtest writes small sorted, indexed BAM files for tests.
+package bamtest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/sam"
+)
+
+// Reference is a sequence in the BAM header
+type Reference struct {
+	Name   string
+	Length int
+}
+
+// Alignment describes a record to write. Pos is 0-based.
+type Alignment struct {
+	Name  string
+	Chrom string
+	Pos   int
+	Cigar string
+	Seq   string // Defaults to A repeated for the query length
+	Flags sam.Flags
+	MapQ  byte
+	MD    string // Optional MD tag
+	XS    byte   // Optional XS strand tag, '+' or '-'
+}
+
+// WriteFile writes alignments, which must be sorted by position, to
+// dir/name with a matching name.bai index. It returns the path of the BAM.
+func WriteFile(t testing.TB, dir, name string, refs []Reference, alignments []Alignment) string {
+	t.Helper()
+
+	byName := map[string]*sam.Reference{}
+	var samRefs []*sam.Reference
+	for _, r := range refs {
+		ref, err := sam.NewReference(r.Name, "", "", r.Length, nil, nil)
+		if err != nil {
+			t.Fatalf("new reference: %v", err)
+		}
+		samRefs = append(samRefs, ref)
+		byName[r.Name] = ref
+	}
+	header, err := sam.NewHeader(nil, samRefs)
+	if err != nil {
+		t.Fatalf("new header: %v", err)
+	}
+	header.SortOrder = sam.Coordinate
+
+	var out bytes.Buffer
+	w, err := bam.NewWriter(&out, header, 1)
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	for _, a := range alignments {
+		cigar, err := sam.ParseCigar([]byte(a.Cigar))
+		if err != nil {
+			t.Fatalf("bad cigar %q: %v", a.Cigar, err)
+		}
+		seq := a.Seq
+		if seq == "" {
+			_, qlen := cigar.Lengths()
+			seq = strings.Repeat("A", qlen)
+		}
+		qual := bytes.Repeat([]byte{30}, len(seq))
+
+		var aux []sam.Aux
+		if a.MD != "" {
+			md, err := sam.NewAux(sam.NewTag("MD"), a.MD)
+			if err != nil {
+				t.Fatalf("bad MD %q: %v", a.MD, err)
+			}
+			aux = append(aux, md)
+		}
+		if a.XS != 0 {
+			xs, err := sam.NewAux(sam.NewTag("XS"), sam.ASCII(a.XS))
+			if err != nil {
+				t.Fatalf("bad XS %q: %v", a.XS, err)
+			}
+			aux = append(aux, xs)
+		}
+
+		rec, err := sam.NewRecord(a.Name, byName[a.Chrom], nil, a.Pos, -1, 0, a.MapQ, cigar, []byte(seq), qual, aux)
+		if err != nil {
+			t.Fatalf("new record %s: %v", a.Name, err)
+		}
+		rec.Flags = a.Flags
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("write record: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("write bam: %v", err)
+	}
+
+	// Index from the chunk each record was read back from
+	r, err := bam.NewReader(bytes.NewReader(out.Bytes()), 1)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	var idx bam.Index
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read back: %v", err)
+		}
+		if err := idx.Add(rec, r.LastChunk()); err != nil {
+			t.Fatalf("index record: %v", err)
+		}
+	}
+	r.Close()
+
+	var index bytes.Buffer
+	if err := bam.WriteIndex(&index, &idx); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	if err := os.WriteFile(path+".bai", index.Bytes(), 0644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+
+	return path
+}
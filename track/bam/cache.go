@@ -0,0 +1,71 @@
+package bam
+
+import (
+	"fmt"
+	"gb-api/cache"
+	"gb-api/config"
+	"log/slog"
+)
+
+// cache of opened indexes and headers, keyed by file and index location
+var BAMIndexCache *cache.Cache[*BAM]
+
+func init() {
+	cacheSize := config.GetCacheSize()
+
+	indexCache, err := cache.NewCache[*BAM](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	BAMIndexCache = indexCache
+}
+
+// GetCachedBAM returns an opened file, reusing a cached index and header
+func GetCachedBAM(path string, indexPath string) (*BAM, error) {
+	cacheId := path + "-" + indexPath
+	if cached, ok := BAMIndexCache.Get(cacheId); ok {
+		return cached, nil
+	}
+	b, err := New(path, indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	BAMIndexCache.Add(cacheId, b)
+	return b, nil
+}
+
+// GetCachedAlignments reads coverage and reads for a region, reusing a cached index.
+// indexPath may be empty to look for a .bai next to the BAM.
+func GetCachedAlignments(path string, indexPath string, chrom string, start, end int, opts Options) (BAMData, error) {
+	b, err := GetCachedBAM(path, indexPath)
+	if err != nil {
+		return BAMData{}, fmt.Errorf("Failed to open BAM file, %w", err)
+	}
+
+	slog.Debug("BAM query", "path", path, "chrom", chrom, "start", start, "end", end, "mode", opts.Mode)
+	data, err := b.Alignments(chrom, start, end, opts)
+	if err != nil {
+		return BAMData{}, fmt.Errorf("Failed to read BAM data, %w", err)
+	}
+	slog.Debug("Returning alignments", "total", data.TotalReads, "reads", len(data.Reads))
+
+	return data, nil
+}
+
+// GetCachedJunctions collects splice junctions for a region, reusing a cached index
+func GetCachedJunctions(path string, indexPath string, chrom string, start, end int, opts JunctionOptions) ([]Junction, error) {
+	b, err := GetCachedBAM(path, indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open BAM file, %w", err)
+	}
+
+	slog.Debug("BAM junction query", "path", path, "chrom", chrom, "start", start, "end", end)
+	junctions, err := b.Junctions(chrom, start, end, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read BAM data, %w", err)
+	}
+	slog.Debug("Returning junctions", "count", len(junctions))
+
+	return junctions, nil
+}
@@ -0,0 +1,97 @@
+package bam
+
+import (
+	"github.com/biogo/hts/sam"
+)
+
+// CoverageBin is the mean per-base depth over [Start, End), split by read base.
+// For 1bp bins the values are the base counts at that position.
+type CoverageBin struct {
+	Start int32   `json:"start"`
+	End   int32   `json:"end"`
+	Depth float32 `json:"depth"`
+	A     float32 `json:"a"`
+	C     float32 `json:"c"`
+	G     float32 `json:"g"`
+	T     float32 `json:"t"`
+	N     float32 `json:"n"`
+}
+
+// coverageCounter accumulates aligned bases per bin across a region
+type coverageCounter struct {
+	start, end int
+	binSize    int
+	counts     [][5]int64 // A, C, G, T, N
+}
+
+func newCoverageCounter(start, end, bins int) *coverageCounter {
+	binSize := (end - start + bins - 1) / bins
+	if binSize < 1 {
+		binSize = 1
+	}
+	n := (end - start + binSize - 1) / binSize
+	return &coverageCounter{start: start, end: end, binSize: binSize, counts: make([][5]int64, n)}
+}
+
+func baseIndex(b byte) int {
+	switch b {
+	case 'A', 'a':
+		return 0
+	case 'C', 'c':
+		return 1
+	case 'G', 'g':
+		return 2
+	case 'T', 't':
+		return 3
+	default:
+		return 4
+	}
+}
+
+// add counts the read bases of each aligned (M, =, X) position within the region
+func (c *coverageCounter) add(rec *sam.Record) {
+	seq := rec.Seq.Expand()
+	refPos, queryPos := rec.Pos, 0
+	for _, op := range rec.Cigar {
+		n := op.Len()
+		consume := op.Type().Consumes()
+		switch op.Type() {
+		case sam.CigarMatch, sam.CigarEqual, sam.CigarMismatch:
+			for i := 0; i < n; i++ {
+				pos := refPos + i
+				if pos < c.start || pos >= c.end {
+					continue
+				}
+				base := byte('N')
+				if queryPos+i < len(seq) {
+					base = seq[queryPos+i]
+				}
+				c.counts[(pos-c.start)/c.binSize][baseIndex(base)]++
+			}
+		}
+		refPos += n * consume.Reference
+		queryPos += n * consume.Query
+	}
+}
+
+func (c *coverageCounter) bins() []CoverageBin {
+	out := make([]CoverageBin, len(c.counts))
+	for i, counts := range c.counts {
+		binStart := c.start + i*c.binSize
+		binEnd := min(binStart+c.binSize, c.end)
+		width := float32(binEnd - binStart)
+
+		bin := CoverageBin{
+			Start: int32(binStart),
+			End:   int32(binEnd),
+			A:     float32(counts[0]) / width,
+			C:     float32(counts[1]) / width,
+			G:     float32(counts[2]) / width,
+			T:     float32(counts[3]) / width,
+			N:     float32(counts[4]) / width,
+		}
+		bin.Depth = bin.A + bin.C + bin.G + bin.T + bin.N
+		out[i] = bin
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package bam
+
+import (
+	"gb-api/track/transcript"
+	"sort"
+
+	"github.com/biogo/hts/sam"
+)
+
+const MAX_JUNCTION_WINDOW = 1_000_000 // Widest region that returns splice junctions
+
+// Junction is an intron supported by split reads (CIGAR N operations).
+// Start and End are the 0-based, half-open intron coordinates.
+type Junction struct {
+	Start  int32    `json:"start"`
+	End    int32    `json:"end"`
+	Strand string   `json:"strand"`          // From the XS or ts tag, "." when unknown
+	Count  int      `json:"count"`           // Supporting reads
+	Known  *bool    `json:"known,omitempty"` // Set when annotated: whether the intron is in the annotation
+	Genes  []string `json:"genes,omitempty"` // Annotated genes with this intron
+}
+
+// JunctionOptions filters the collected junctions
+type JunctionOptions struct {
+	MinCount int // Drop junctions with fewer supporting reads
+	MinMapQ  int // Skip alignments with a lower mapping quality
+}
+
+type junctionKey struct {
+	start, end int32
+	strand     string
+}
+
+// Junctions returns the unique junctions whose intron overlaps [start, end),
+// sorted by position
+func (b *BAM) Junctions(chrom string, start, end int, opts JunctionOptions) ([]Junction, error) {
+	counts := map[junctionKey]int{}
+	err := b.Query(chrom, start, end, func(rec *sam.Record) {
+		if int(rec.MapQ) < opts.MinMapQ {
+			return
+		}
+		strand := junctionStrand(rec)
+		refPos := rec.Pos
+		for _, op := range rec.Cigar {
+			n := op.Len()
+			if op.Type() == sam.CigarSkipped && refPos < end && refPos+n > start {
+				counts[junctionKey{int32(refPos), int32(refPos + n), strand}]++
+			}
+			refPos += n * op.Type().Consumes().Reference
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	junctions := []Junction{}
+	for k, count := range counts {
+		if count < opts.MinCount {
+			continue
+		}
+		junctions = append(junctions, Junction{Start: k.start, End: k.end, Strand: k.strand, Count: count})
+	}
+	sort.Slice(junctions, func(i, j int) bool {
+		a, b := junctions[i], junctions[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Strand < b.Strand
+	})
+	return junctions, nil
+}
+
+// junctionStrand reads the transcript strand of a spliced alignment from the
+// XS tag (STAR, HISAT2) or the ts tag (minimap2, relative to the read strand)
+func junctionStrand(rec *sam.Record) string {
+	if xs, ok := rec.Tag([]byte("XS")); ok {
+		if v, ok := xs.Value().(byte); ok && (v == '+' || v == '-') {
+			return string(v)
+		}
+	}
+	if ts, ok := rec.Tag([]byte("ts")); ok {
+		if v, ok := ts.Value().(byte); ok && (v == '+' || v == '-') {
+			reverse := rec.Flags&sam.Reverse != 0
+			if (v == '+') != reverse {
+				return "+"
+			}
+			return "-"
+		}
+	}
+	return "."
+}
+
+// AnnotateJunctions marks each junction as known or novel by matching it
+// against the introns of the genes' transcripts. A junction with an unknown
+// strand takes the strand of the matching annotation.
+func AnnotateJunctions(junctions []Junction, genes []transcript.Gene) {
+	type introns struct {
+		strands []string
+		genes   []string
+	}
+	byCoords := map[[2]int32]*introns{}
+	for _, intron := range transcript.Introns(genes) {
+		// Annotation coordinates are 1-based and inclusive
+		k := [2]int32{int32(intron.Start - 1), int32(intron.End)}
+		if byCoords[k] == nil {
+			byCoords[k] = &introns{}
+		}
+		byCoords[k].strands = append(byCoords[k].strands, intron.Strand)
+		byCoords[k].genes = append(byCoords[k].genes, intron.Gene)
+	}
+
+	for i := range junctions {
+		j := &junctions[i]
+		known := false
+		if match, ok := byCoords[[2]int32{j.Start, j.End}]; ok {
+			for n, strand := range match.strands {
+				if j.Strand != "." && j.Strand != strand {
+					continue
+				}
+				known = true
+				if j.Strand == "." {
+					j.Strand = strand
+				}
+				j.Genes = append(j.Genes, match.genes[n])
+			}
+		}
+		j.Known = &known
+	}
+}
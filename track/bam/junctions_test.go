@@ -0,0 +1,117 @@
+package bam
+
+import (
+	"gb-api/track/bam/bamtest"
+	"gb-api/track/transcript"
+	"testing"
+
+	"github.com/biogo/hts/sam"
+)
+
+var splicedAlignments = []bamtest.Alignment{
+	{Name: "s1", Chrom: "chr1", Pos: 100, Cigar: "10M90N10M", MapQ: 60, XS: '+'},
+	{Name: "s2", Chrom: "chr1", Pos: 105, Cigar: "5M90N10M", MapQ: 60, XS: '+'},
+	{Name: "s3", Chrom: "chr1", Pos: 105, Cigar: "5M90N10M100N5M", MapQ: 60, XS: '+'},
+	{Name: "s4", Chrom: "chr1", Pos: 150, Cigar: "5M500N5M", MapQ: 60},
+	{Name: "s5", Chrom: "chr1", Pos: 190, Cigar: "20M", MapQ: 60},
+	{Name: "low", Chrom: "chr1", Pos: 200, Cigar: "5M50N5M", MapQ: 1, XS: '-'},
+	{Name: "ts", Chrom: "chr1", Pos: 300, Cigar: "5M50N5M", MapQ: 60, Flags: sam.Reverse},
+}
+
+func TestJunctions(t *testing.T) {
+	path := bamtest.WriteFile(t, t.TempDir(), "spliced.bam", refs, splicedAlignments)
+	b, err := GetCachedBAM(path, "")
+	if err != nil {
+		t.Fatalf("GetCachedBAM() error = %v", err)
+	}
+
+	junctions, err := b.Junctions("chr1", 0, 1000, JunctionOptions{})
+	if err != nil {
+		t.Fatalf("Junctions() error = %v", err)
+	}
+	want := []Junction{
+		{Start: 110, End: 200, Strand: "+", Count: 3},
+		{Start: 155, End: 655, Strand: ".", Count: 1},
+		{Start: 205, End: 255, Strand: "-", Count: 1},
+		{Start: 210, End: 310, Strand: "+", Count: 1},
+		{Start: 305, End: 355, Strand: ".", Count: 1},
+	}
+	if len(junctions) != len(want) {
+		t.Fatalf("expected %d junctions, got %+v", len(want), junctions)
+	}
+	for i := range want {
+		if junctions[i].Start != want[i].Start || junctions[i].End != want[i].End ||
+			junctions[i].Strand != want[i].Strand || junctions[i].Count != want[i].Count {
+			t.Errorf("junction %d: expected %+v, got %+v", i, want[i], junctions[i])
+		}
+	}
+
+	filtered, err := b.Junctions("chr1", 0, 1000, JunctionOptions{MinCount: 2, MinMapQ: 30})
+	if err != nil {
+		t.Fatalf("Junctions() error = %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].Count != 3 {
+		t.Errorf("expected only the junction with 3 reads, got %+v", filtered)
+	}
+
+	// Only introns overlapping the region are returned
+	inside, err := b.Junctions("chr1", 400, 500, JunctionOptions{})
+	if err != nil {
+		t.Fatalf("Junctions() error = %v", err)
+	}
+	if len(inside) != 1 || inside[0].Start != 155 {
+		t.Errorf("expected only the long intron, got %+v", inside)
+	}
+}
+
+func TestJunctionStrandFromTSTag(t *testing.T) {
+	ts, _ := sam.NewAux(sam.NewTag("ts"), sam.ASCII('+'))
+	rec := &sam.Record{Flags: sam.Reverse, AuxFields: sam.AuxFields{ts}}
+	if got := junctionStrand(rec); got != "-" {
+		t.Errorf("expected ts:A:+ on a reverse read to be -, got %s", got)
+	}
+}
+
+func TestAnnotateJunctions(t *testing.T) {
+	exon := func(start, end int) transcript.Exon {
+		return transcript.Exon{Feature: transcript.Feature{GenomicRange: transcript.GenomicRange{Chrom: "chr1", Start: start, End: end}}}
+	}
+	genes := []transcript.Gene{{
+		Feature:     transcript.Feature{Name: "GENE1"},
+		Strand:      "+",
+		Transcripts: []transcript.Transcript{{Exons: []transcript.Exon{exon(101, 110), exon(201, 210), exon(311, 320)}}},
+	}}
+
+	junctions := []Junction{
+		{Start: 110, End: 200, Strand: "+"},
+		{Start: 210, End: 310, Strand: "."},
+		{Start: 210, End: 310, Strand: "-"},
+		{Start: 155, End: 655, Strand: "."},
+	}
+	AnnotateJunctions(junctions, genes)
+
+	wantKnown := []bool{true, true, false, false}
+	for i, j := range junctions {
+		if j.Known == nil || *j.Known != wantKnown[i] {
+			t.Errorf("junction %d: expected known=%v, got %v", i, wantKnown[i], j.Known)
+		}
+	}
+	if junctions[0].Genes[0] != "GENE1" {
+		t.Errorf("expected gene name on known junction, got %v", junctions[0].Genes)
+	}
+	if junctions[1].Strand != "+" {
+		t.Errorf("expected unknown strand to take the annotated strand, got %s", junctions[1].Strand)
+	}
+
+	// A second gene on the same strand sharing the intron is listed too
+	genes = append(genes, transcript.Gene{
+		Feature:     transcript.Feature{Name: "GENE2"},
+		Strand:      "+",
+		Transcripts: []transcript.Transcript{{Exons: []transcript.Exon{exon(51, 110), exon(201, 260)}}},
+	})
+	shared := []Junction{{Start: 110, End: 200, Strand: "+"}}
+	AnnotateJunctions(shared, genes)
+	if len(shared[0].Genes) != 2 || shared[0].Genes[0] != "GENE1" || shared[0].Genes[1] != "GENE2" {
+		t.Errorf("expected both genes on a shared intron, got %v", shared[0].Genes)
+	}
+}
@@ -0,0 +1,218 @@
+package bam
+
+import (
+	"math/rand/v2"
+	"strconv"
+
+	"github.com/biogo/hts/sam"
+)
+
+// Block is a 0-based, half-open reference interval
+type Block struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+// Gap is a deletion (D) or skipped region such as an intron (N)
+type Gap struct {
+	Start int32  `json:"start"`
+	End   int32  `json:"end"`
+	Type  string `json:"type"` // "D" or "N"
+}
+
+// Insertion is inserted read sequence placed before reference position Pos
+type Insertion struct {
+	Pos    int32 `json:"pos"`
+	Length int32 `json:"length"`
+}
+
+// Mismatch is a read base that differs from the reference
+type Mismatch struct {
+	Pos  int32  `json:"pos"`
+	Base string `json:"base"`
+	Qual uint8  `json:"qual"`
+}
+
+// Read is an alignment laid out for rendering
+type Read struct {
+	Name       string      `json:"name"`
+	Start      int32       `json:"start"`
+	End        int32       `json:"end"`
+	Strand     string      `json:"strand"`
+	MapQ       uint8       `json:"mapq"`
+	Flags      uint16      `json:"flags"`
+	Cigar      string      `json:"cigar"`
+	Blocks     []Block     `json:"blocks"` // Aligned (M, =, X) segments
+	Gaps       []Gap       `json:"gaps,omitempty"`
+	Insertions []Insertion `json:"insertions,omitempty"`
+	Mismatches []Mismatch  `json:"mismatches,omitempty"` // From X operations and the MD tag when present
+	Row        int         `json:"row"`
+}
+
+// newRead converts an alignment into blocks, gaps, insertions and mismatches
+func newRead(rec *sam.Record) Read {
+	strand := "+"
+	if rec.Flags&sam.Reverse != 0 {
+		strand = "-"
+	}
+	read := Read{
+		Name:   rec.Name,
+		Start:  int32(rec.Pos),
+		End:    int32(rec.End()),
+		Strand: strand,
+		MapQ:   rec.MapQ,
+		Flags:  uint16(rec.Flags),
+		Cigar:  rec.Cigar.String(),
+		Blocks: []Block{},
+	}
+
+	seq := rec.Seq.Expand()
+	// Reference and query offsets of each aligned base, in order, for the MD tag
+	var aligned [][2]int
+	refPos, queryPos := rec.Pos, 0
+	for _, op := range rec.Cigar {
+		n := op.Len()
+		consume := op.Type().Consumes()
+		switch op.Type() {
+		case sam.CigarMatch, sam.CigarEqual, sam.CigarMismatch:
+			read.Blocks = append(read.Blocks, Block{Start: int32(refPos), End: int32(refPos + n)})
+			for i := 0; i < n; i++ {
+				aligned = append(aligned, [2]int{refPos + i, queryPos + i})
+				if op.Type() == sam.CigarMismatch {
+					read.Mismatches = append(read.Mismatches, mismatchAt(rec, seq, refPos+i, queryPos+i))
+				}
+			}
+		case sam.CigarDeletion:
+			read.Gaps = append(read.Gaps, Gap{Start: int32(refPos), End: int32(refPos + n), Type: "D"})
+		case sam.CigarSkipped:
+			read.Gaps = append(read.Gaps, Gap{Start: int32(refPos), End: int32(refPos + n), Type: "N"})
+		case sam.CigarInsertion:
+			read.Insertions = append(read.Insertions, Insertion{Pos: int32(refPos), Length: int32(n)})
+		}
+		refPos += n * consume.Reference
+		queryPos += n * consume.Query
+	}
+
+	// X operations already carry their mismatches; otherwise use MD
+	if len(read.Mismatches) == 0 {
+		if md, ok := rec.Tag([]byte("MD")); ok {
+			if s, ok := md.Value().(string); ok {
+				for _, i := range mdMismatches(s) {
+					if i < len(aligned) {
+						read.Mismatches = append(read.Mismatches, mismatchAt(rec, seq, aligned[i][0], aligned[i][1]))
+					}
+				}
+			}
+		}
+	}
+	return read
+}
+
+func mismatchAt(rec *sam.Record, seq []byte, refPos, queryPos int) Mismatch {
+	m := Mismatch{Pos: int32(refPos), Base: "N"}
+	if queryPos < len(seq) {
+		m.Base = string(seq[queryPos])
+	}
+	if queryPos < len(rec.Qual) && rec.Qual[queryPos] != 0xff {
+		m.Qual = rec.Qual[queryPos]
+	}
+	return m
+}
+
+// mdMismatches returns the indexes, among a read's aligned bases, of the
+// mismatches in an MD tag such as "10A5^AC6"
+func mdMismatches(md string) []int {
+	var out []int
+	aligned := 0
+	for i := 0; i < len(md); {
+		switch c := md[i]; {
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(md) && md[j] >= '0' && md[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(md[i:j])
+			aligned += n
+			i = j
+		case c == '^':
+			// Deleted reference bases are not aligned to the read
+			i++
+			for i < len(md) && (md[i] < '0' || md[i] > '9') {
+				i++
+			}
+		default:
+			out = append(out, aligned)
+			aligned++
+			i++
+		}
+	}
+	return out
+}
+
+// downsample keeps at most depth reads starting in each window-sized bucket,
+// chosen by reservoir sampling. The seed makes results stable across requests.
+// It returns the kept reads in their original order and the buckets that dropped reads.
+func downsample(reads []Read, window, depth int, seed uint64) ([]Read, []Block) {
+	if len(reads) == 0 || window <= 0 || depth <= 0 {
+		return reads, nil
+	}
+	rng := rand.New(rand.NewPCG(seed, uint64(len(reads))))
+
+	var kept []Read
+	var dropped []Block
+	for i := 0; i < len(reads); {
+		bucketStart := reads[i].Start
+		j := i
+		for j < len(reads) && reads[j].Start < bucketStart+int32(window) {
+			j++
+		}
+		bucket := reads[i:j]
+		if len(bucket) <= depth {
+			kept = append(kept, bucket...)
+		} else {
+			sample := make([]int, depth)
+			for k := range sample {
+				sample[k] = k
+			}
+			for k := depth; k < len(bucket); k++ {
+				if r := rng.IntN(k + 1); r < depth {
+					sample[r] = k
+				}
+			}
+			selected := make([]bool, len(bucket))
+			for _, k := range sample {
+				selected[k] = true
+			}
+			for k, read := range bucket {
+				if selected[k] {
+					kept = append(kept, read)
+				}
+			}
+			dropped = append(dropped, Block{Start: bucketStart, End: bucket[len(bucket)-1].Start + 1})
+		}
+		i = j
+	}
+	return kept, dropped
+}
+
+// packReads assigns each read the first row whose previous read ends at
+// least gap bases before it, returning the number of rows
+func packReads(reads []Read, gap int32) int {
+	var rowEnds []int32
+	for i := range reads {
+		row := -1
+		for r, end := range rowEnds {
+			if end+gap <= reads[i].Start {
+				row = r
+				break
+			}
+		}
+		if row < 0 {
+			row = len(rowEnds)
+			rowEnds = append(rowEnds, 0)
+		}
+		rowEnds[row] = reads[i].End
+		reads[i].Row = row
+	}
+	return len(rowEnds)
+}
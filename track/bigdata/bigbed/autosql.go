@@ -0,0 +1,101 @@
+package bigbed
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AutoSql is the table definition stored in a bigBed header, naming and
+// typing each column
+type AutoSql struct {
+	Name    string         `json:"name"`
+	Comment string         `json:"comment,omitempty"`
+	Fields  []AutoSqlField `json:"fields"`
+}
+
+// AutoSqlField is one column of an autoSql table
+type AutoSqlField struct {
+	Type    string `json:"type"`           // e.g. "uint", "string", "lstring", "char"
+	Size    string `json:"size,omitempty"` // Array size, a number or the field holding it
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// autoSqlField matches a field declaration such as
+//
+//	uint[blockCount] blockSizes; "Comma separated list of block sizes"
+var autoSqlField = regexp.MustCompile(`^(\w+)\s*(?:\([^)]*\))?\s*(?:\[\s*(\w*)\s*\])?\s+(\w+)\s*;\s*(?:"(.*)")?`)
+
+// ParseAutoSql parses the autoSql definition of a bigBed table
+func ParseAutoSql(text string) (*AutoSql, error) {
+	as := &AutoSql{}
+
+	// The field list opens after the table comment, which may hold brackets
+	skip := 0
+	if quoted := strings.SplitN(text, `"`, 3); len(quoted) == 3 && !strings.Contains(quoted[0], "(") {
+		as.Comment = quoted[1]
+		skip = len(quoted[0]) + len(quoted[1]) + 2
+	}
+	open := strings.Index(text[skip:], "(") + skip
+	end := strings.LastIndex(text, ")")
+	if open < skip || end < open {
+		return nil, fmt.Errorf("autoSql has no field list")
+	}
+
+	header := strings.Fields(text[:open])
+	if len(header) < 2 || (header[0] != "table" && header[0] != "simple" && header[0] != "object") {
+		return nil, fmt.Errorf("autoSql must start with a table declaration")
+	}
+	as.Name = header[1]
+
+	for line := range strings.SplitSeq(text[open+1:end], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := autoSqlField.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("invalid autoSql field %q", line)
+		}
+		as.Fields = append(as.Fields, AutoSqlField{Type: m[1], Size: m[2], Name: m[3], Comment: m[4]})
+	}
+	if len(as.Fields) < 3 {
+		return nil, fmt.Errorf("autoSql table %s has %d fields, expected chrom, start and end first", as.Name, len(as.Fields))
+	}
+	return as, nil
+}
+
+// Index returns the column of a field, counting chrom, start and end, or -1
+func (as *AutoSql) Index(name string) int {
+	for i, f := range as.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// restColumns maps field names to their position in BigBedData.Rest, which
+// holds the columns after chrom, start and end. It fails when a field is
+// missing.
+func (as *AutoSql) restColumns(names ...string) ([]int, error) {
+	columns := make([]int, len(names))
+	for i, name := range names {
+		idx := as.Index(name)
+		if idx < 3 {
+			return nil, fmt.Errorf("autoSql table %s has no %s field", as.Name, name)
+		}
+		columns[i] = idx - 3
+	}
+	return columns, nil
+}
+
+// tableAutoSql parses the autoSql of a bigBed, falling back to a standard
+// definition for files written without one
+func tableAutoSql(text, fallback string) (*AutoSql, error) {
+	if strings.TrimSpace(text) == "" {
+		text = fallback
+	}
+	return ParseAutoSql(text)
+}
@@ -20,6 +20,7 @@ type BigBedData struct {
 	Start int32  `json:"start"`
 	End   int32  `json:"end"`
 	Rest  string `json:"rest,omitempty"`
+	Row   *int   `json:"row,omitempty"` // Layout row when packing was requested, none when hidden
 }
 
 // ReadBigBed reads data without caching (use GetCachedBedData for cached reads)
@@ -6,6 +6,7 @@ import (
 	"gb-api/config"
 	"gb-api/track/bigdata"
 	"log/slog"
+	"slices"
 	"sort"
 	"sync"
 )
@@ -140,3 +141,29 @@ func GetCachedBedData(url string, chrom string, start, end int) ([]BigBedData, e
 
 	return data, nil
 }
+
+// GetOverlappingBedData reads the features overlapping a region, including
+// those starting before it, with a cached header. Long features such as
+// chains and alignment blocks need these, which GetCachedBedData leaves out.
+func GetOverlappingBedData(url string, chrom string, start, end int) ([]BigBedData, error) {
+	bb, err := getCachedHeader(url)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create bigbed, %w", err)
+	}
+	data, err := bigdata.ReadData(bb, chrom, int32(start), int32(end), decodeBedData)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read BigBed data, %w", err)
+	}
+	// The decoder also keeps features ending at start
+	return slices.DeleteFunc(data, func(d BigBedData) bool { return d.End <= int32(start) }), nil
+}
+
+// getCachedAutoSql parses the autoSql of a bigBed, falling back to a
+// standard definition for files written without one
+func getCachedAutoSql(url string, fallback string) (*AutoSql, error) {
+	bb, err := getCachedHeader(url)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create bigbed, %w", err)
+	}
+	return tableAutoSql(bb.AutoSql, fallback)
+}
@@ -0,0 +1,192 @@
+package bigbed
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BIGCHAIN_AS is the standard bigChain definition, used for files without autoSql
+const BIGCHAIN_AS = `table bigChain
+"bigChain pairwise alignment"
+    (
+    string chrom;       "Reference sequence chromosome or scaffold"
+    uint   chromStart;  "Start position in chromosome"
+    uint   chromEnd;    "End position in chromosome"
+    string name;        "Name or ID of item, ideally both human readable and unique"
+    uint score;         "Score (0-1000)"
+    char[1] strand;     "+ or - for strand"
+    uint tSize;         "size of target sequence"
+    string qName;       "name of query sequence"
+    uint qSize;         "size of query sequence"
+    uint qStart;        "start of alignment on query sequence"
+    uint qEnd;          "end of alignment on query sequence"
+    float chainScore;   "score from chain"
+    )`
+
+// BIGLINK_AS is the standard definition of bigChain link files, used for
+// files without autoSql
+const BIGLINK_AS = `table bigLink
+"bigLink pairwise alignment"
+    (
+    string chrom;       "Reference sequence chromosome or scaffold"
+    uint   chromStart;  "Start position in chromosome"
+    uint   chromEnd;    "End position in chromosome"
+    string name;        "Name or ID of item, ideally both human readable and unique"
+    uint qStart;        "start of query sequence"
+    )`
+
+// ChainBlock is an ungapped part of a chain. Query coordinates count from
+// the end of the query chromosome on chains with strand -, as in chain files.
+type ChainBlock struct {
+	Start  int32 `json:"start"`
+	End    int32 `json:"end"`
+	QStart int32 `json:"qStart"`
+	QEnd   int32 `json:"qEnd"`
+}
+
+// BigChain is a chain from a bigChain file with its blocks from the link file
+type BigChain struct {
+	BigBedData
+	Name       string       `json:"name"`
+	Score      int32        `json:"score"`
+	Strand     string       `json:"strand"` // Query strand
+	QName      string       `json:"qName"`
+	QSize      int32        `json:"qSize"`
+	QStart     int32        `json:"qStart"`
+	QEnd       int32        `json:"qEnd"`
+	ChainScore float64      `json:"chainScore"`
+	Blocks     []ChainBlock `json:"blocks,omitempty"` // Blocks in the region, by start
+}
+
+// ReadBigChain reads the chains overlapping a region and, given a link file,
+// their blocks in the region
+func ReadBigChain(url, linkURL string, chrom string, start, end int) ([]BigChain, error) {
+	as, err := getCachedAutoSql(url, BIGCHAIN_AS)
+	if err != nil {
+		return nil, err
+	}
+	data, err := GetOverlappingBedData(url, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	chains, err := ParseBigChain(data, as)
+	if err != nil || linkURL == "" {
+		return chains, err
+	}
+
+	linkAs, err := getCachedAutoSql(linkURL, BIGLINK_AS)
+	if err != nil {
+		return nil, err
+	}
+	links, err := GetOverlappingBedData(linkURL, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return chains, AddChainLinks(chains, links, linkAs)
+}
+
+// ParseBigChain reads chains from bigChain features, finding columns by
+// their autoSql names
+func ParseBigChain(data []BigBedData, as *AutoSql) ([]BigChain, error) {
+	columns, err := as.restColumns("name", "score", "strand", "qName", "qSize", "qStart", "qEnd", "chainScore")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]BigChain, len(data))
+	for i, d := range data {
+		fields := strings.Split(d.Rest, "\t")
+		f, err := pickFields(fields, columns)
+		if err != nil {
+			return nil, fmt.Errorf("chain at %s:%d: %w", d.Chr, d.Start, err)
+		}
+		nums, err := parseInt32s(f[1], f[4], f[5], f[6])
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: %w", f[0], err)
+		}
+		chainScore, err := strconv.ParseFloat(f[7], 64)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: invalid chainScore %q", f[0], f[7])
+		}
+
+		d.Rest = ""
+		out[i] = BigChain{
+			BigBedData: d,
+			Name:       f[0],
+			Score:      nums[0],
+			Strand:     f[2],
+			QName:      f[3],
+			QSize:      nums[1],
+			QStart:     nums[2],
+			QEnd:       nums[3],
+			ChainScore: chainScore,
+		}
+	}
+	return out, nil
+}
+
+// AddChainLinks adds link file features to the chains they name as blocks
+func AddChainLinks(chains []BigChain, links []BigBedData, as *AutoSql) error {
+	columns, err := as.restColumns("name", "qStart")
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]*BigChain, len(chains))
+	for i := range chains {
+		byName[chains[i].Name] = &chains[i]
+	}
+	for _, l := range links {
+		f, err := pickFields(strings.Split(l.Rest, "\t"), columns)
+		if err != nil {
+			return fmt.Errorf("link at %s:%d: %w", l.Chr, l.Start, err)
+		}
+		c, ok := byName[f[0]]
+		if !ok {
+			// Chain outside the region, or filtered out
+			continue
+		}
+		qStart, err := parseInt32s(f[1])
+		if err != nil {
+			return fmt.Errorf("link of chain %s: %w", f[0], err)
+		}
+		c.Blocks = append(c.Blocks, ChainBlock{
+			Start:  l.Start,
+			End:    l.End,
+			QStart: qStart[0],
+			QEnd:   qStart[0] + l.End - l.Start,
+		})
+	}
+
+	for i := range chains {
+		blocks := chains[i].Blocks
+		sort.Slice(blocks, func(a, b int) bool { return blocks[a].Start < blocks[b].Start })
+	}
+	return nil
+}
+
+// pickFields returns the fields at the given columns
+func pickFields(fields []string, columns []int) ([]string, error) {
+	picked := make([]string, len(columns))
+	for i, c := range columns {
+		if c >= len(fields) {
+			return nil, fmt.Errorf("expected at least %d fields after end, got %d", c+1, len(fields))
+		}
+		picked[i] = fields[c]
+	}
+	return picked, nil
+}
+
+func parseInt32s(fields ...string) ([]int32, error) {
+	nums := make([]int32, len(fields))
+	for i, f := range fields {
+		n, err := strconv.ParseInt(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		nums[i] = int32(n)
+	}
+	return nums, nil
+}
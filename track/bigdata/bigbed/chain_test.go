@@ -0,0 +1,111 @@
+package bigbed
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAutoSql(t *testing.T) {
+	as, err := ParseAutoSql(BIGCHAIN_AS)
+	if err != nil {
+		t.Fatalf("ParseAutoSql() error = %v", err)
+	}
+	if as.Name != "bigChain" || len(as.Fields) != 12 || as.Index("qName") != 7 {
+		t.Errorf("unexpected table %+v", as)
+	}
+	if f := as.Fields[5]; f.Type != "char" || f.Size != "1" || f.Name != "strand" {
+		t.Errorf("unexpected strand field %+v", f)
+	}
+
+	as, err = ParseAutoSql(`table peaks
+"Peaks (narrowPeak-like)"
+(
+string chrom; "Chromosome"
+uint chromStart; "Start"
+uint chromEnd; "End"
+enum(low, high) confidence; "Call confidence"
+int blockCount; "Blocks"
+int[blockCount] blockSizes; "Block sizes (bp)"
+)`)
+	if err != nil {
+		t.Fatalf("ParseAutoSql() error = %v", err)
+	}
+	if as.Comment != "Peaks (narrowPeak-like)" || as.Index("blockSizes") != 5 || as.Fields[5].Size != "blockCount" || as.Fields[3].Type != "enum" {
+		t.Errorf("unexpected table %+v", as)
+	}
+
+	if _, err := ParseAutoSql("not autoSql"); err == nil {
+		t.Error("expected an error without a field list")
+	}
+}
+
+func TestParseBigChain(t *testing.T) {
+	as, err := ParseAutoSql(BIGCHAIN_AS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains, err := ParseBigChain([]BigBedData{
+		{Chr: "chr1", Start: 1000, End: 5000, Rest: "7\t1000\t-\t248956422\tchr2\t242193529\t200\t4200\t35000.5"},
+	}, as)
+	if err != nil {
+		t.Fatalf("ParseBigChain() error = %v", err)
+	}
+	c := chains[0]
+	if c.Name != "7" || c.Strand != "-" || c.QName != "chr2" || c.QStart != 200 || c.QEnd != 4200 || c.ChainScore != 35000.5 || c.Rest != "" {
+		t.Errorf("unexpected chain %+v", c)
+	}
+
+	linkAs, err := ParseAutoSql(BIGLINK_AS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := []BigBedData{
+		{Chr: "chr1", Start: 3000, End: 5000, Rest: "7\t2200"},
+		{Chr: "chr1", Start: 1000, End: 2500, Rest: "7\t200"},
+		{Chr: "chr1", Start: 1200, End: 1300, Rest: "8\t0"},
+	}
+	if err := AddChainLinks(chains, links, linkAs); err != nil {
+		t.Fatalf("AddChainLinks() error = %v", err)
+	}
+	want := []ChainBlock{{Start: 1000, End: 2500, QStart: 200, QEnd: 1700}, {Start: 3000, End: 5000, QStart: 2200, QEnd: 4200}}
+	if !reflect.DeepEqual(chains[0].Blocks, want) {
+		t.Errorf("expected blocks %+v, got %+v", want, chains[0].Blocks)
+	}
+}
+
+func TestParseBigChainByFieldName(t *testing.T) {
+	// Columns are found by name, so extra or reordered fields are read
+	as, err := ParseAutoSql(`table chainPlus
+"bigChain with a label first"
+(
+string chrom; "Chromosome"
+uint chromStart; "Start"
+uint chromEnd; "End"
+string label; "Label"
+string name; "Name"
+uint score; "Score"
+char[1] strand; "Strand"
+uint tSize; "Target size"
+string qName; "Query"
+uint qSize; "Query size"
+uint qStart; "Query start"
+uint qEnd; "Query end"
+float chainScore; "Chain score"
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains, err := ParseBigChain([]BigBedData{
+		{Chr: "chr1", Start: 0, End: 100, Rest: "mouse\t1\t500\t+\t1000\tchr3\t5000\t10\t110\t99"},
+	}, as)
+	if err != nil {
+		t.Fatalf("ParseBigChain() error = %v", err)
+	}
+	if chains[0].Name != "1" || chains[0].QName != "chr3" || chains[0].QStart != 10 {
+		t.Errorf("unexpected chain %+v", chains[0])
+	}
+
+	if _, err := ParseBigChain(nil, &AutoSql{Name: "bed3", Fields: as.Fields[:3]}); err == nil {
+		t.Error("expected an error for a table without chain fields")
+	}
+}
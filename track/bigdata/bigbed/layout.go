@@ -0,0 +1,56 @@
+package bigbed
+
+import (
+	"gb-api/track/layout"
+	"slices"
+	"strings"
+)
+
+// BedDataWithLayout is a bigBed response packed into display rows, like the
+// legacy transcript layout. Each feature carries its row.
+type BedDataWithLayout[T any] struct {
+	Features  []T   `json:"features"`
+	TotalRows int   `json:"totalRows"`
+	Overflow  []int `json:"overflow,omitempty"` // Features per row beyond maxRows, which have no row
+	Hidden    int   `json:"hidden,omitempty"`   // Features beyond maxRows
+}
+
+// PackBedData lays out features, labelled by their BED name and strand when
+// the file has those columns
+func PackBedData(data []BigBedData, opts layout.Options) BedDataWithLayout[BigBedData] {
+	return pack(data, func(d BigBedData) layout.Item {
+		fields := strings.Split(d.Rest, "\t")
+		item := layout.Item{Start: int(d.Start), End: int(d.End), Label: fields[0]}
+		if len(fields) > 2 {
+			item.Strand = fields[2]
+		}
+		return item
+	}, func(d *BigBedData) *BigBedData { return d }, opts)
+}
+
+// PackCCRE lays out cCREs, labelled by their accession
+func PackCCRE(data []CCRE, opts layout.Options) BedDataWithLayout[CCRE] {
+	return pack(data, func(c CCRE) layout.Item {
+		return layout.Item{Start: int(c.Start), End: int(c.End), Strand: c.Strand, Label: c.Name}
+	}, func(c *CCRE) *BigBedData { return &c.BigBedData }, opts)
+}
+
+// pack sets the row of every feature, reaching it through bed, on a copy of
+// features
+func pack[T any](features []T, item func(T) layout.Item, bed func(*T) *BigBedData, opts layout.Options) BedDataWithLayout[T] {
+	rows, packed := layout.PackFeatures(features, item, opts)
+	features = slices.Clone(features)
+	for i, row := range rows {
+		if row >= 0 {
+			bed(&features[i]).Row = &row
+		} else {
+			bed(&features[i]).Row = nil
+		}
+	}
+	return BedDataWithLayout[T]{
+		Features:  features,
+		TotalRows: packed.TotalRows,
+		Overflow:  packed.Overflow,
+		Hidden:    packed.Hidden,
+	}
+}
@@ -0,0 +1,52 @@
+package bigbed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gb-api/track/layout"
+)
+
+func TestPackBedData(t *testing.T) {
+	data := []BigBedData{
+		{Chr: "chr1", Start: 100, End: 200, Rest: "peak\t0\t+"},
+		{Chr: "chr1", Start: 150, End: 250, Rest: "peak\t0\t-"},
+		{Chr: "chr1", Start: 1000, End: 1100},
+	}
+	packed := PackBedData(data, layout.Options{PaddingBp: 10})
+	if packed.TotalRows != 2 {
+		t.Fatalf("expected 2 rows, got %d", packed.TotalRows)
+	}
+	want := []int{0, 1, 0}
+	for i, f := range packed.Features {
+		if f.Row == nil || *f.Row != want[i] {
+			t.Errorf("feature %d: expected row %d, got %v", i, want[i], f.Row)
+		}
+	}
+}
+
+func TestPackCCRE(t *testing.T) {
+	data := []CCRE{
+		{BigBedData: BigBedData{Chr: "chr1", Start: 100, End: 200}, Name: "EH38E0000001"},
+		{BigBedData: BigBedData{Chr: "chr1", Start: 300, End: 400}, Name: "EH38E0000002"},
+	}
+	// Without labels the features fit in a row, with them the second collides
+	opts := layout.Options{Width: 1000, Start: 0, End: 1000, MaxRows: 1}
+	if packed := PackCCRE(data, opts); packed.Hidden != 0 {
+		t.Errorf("expected no hidden features, got %+v", packed)
+	}
+	opts.CharWidth = 10
+	packed := PackCCRE(data, opts)
+	if packed.Hidden != 1 || packed.Features[1].Row != nil {
+		t.Errorf("expected the second cCRE to be hidden, got %+v", packed)
+	}
+
+	body, err := json.Marshal(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"row":0`) || !strings.Contains(string(body), `"totalRows":1`) {
+		t.Errorf("expected rows in %s", body)
+	}
+}
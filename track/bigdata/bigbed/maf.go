@@ -0,0 +1,190 @@
+package bigbed
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BIGMAF_AS is the standard bigMaf definition, used for files without autoSql
+const BIGMAF_AS = `table bedMaf
+"Bed3 with MAF block"
+    (
+    string chrom;         "Reference sequence chromosome or scaffold"
+    uint   chromStart;    "Start position in chromosome"
+    uint   chromEnd;      "End position in chromosome"
+    lstring mafBlock;     "MAF block"
+    )`
+
+// MAX_MAF_WINDOW is the widest region read from a bigMaf, whose blocks
+// hold every aligned base
+const MAX_MAF_WINDOW = 100000
+
+// MafSequence is one species' row of an alignment block. Start counts from
+// the end of the source chromosome on strand -, as in MAF.
+type MafSequence struct {
+	Species string `json:"species"` // Source before the first dot, e.g. "mm10" of "mm10.chr4"
+	Chrom   string `json:"chrom"`
+	Start   int32  `json:"start"`
+	Size    int32  `json:"size"` // Aligned bases, not counting gaps
+	Strand  string `json:"strand"`
+	SrcSize int32  `json:"srcSize"`
+	Text    string `json:"text"` // Aligned bases with - for gaps
+}
+
+// MafBlock is an alignment block from a bigMaf file, the reference first
+type MafBlock struct {
+	Chr       string        `json:"chr"`
+	Start     int32         `json:"start"`
+	End       int32         `json:"end"`
+	Score     float64       `json:"score,omitempty"`
+	Sequences []MafSequence `json:"sequences"`
+}
+
+// ReadBigMaf reads the alignment blocks overlapping a region, clipped to it.
+// Given species, other species' rows are left out.
+func ReadBigMaf(url string, chrom string, start, end int, species []string) ([]MafBlock, error) {
+	as, err := getCachedAutoSql(url, BIGMAF_AS)
+	if err != nil {
+		return nil, err
+	}
+	data, err := GetOverlappingBedData(url, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := ParseBigMaf(data, as)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		blocks[i] = ClipMafBlock(blocks[i], int32(start), int32(end))
+		if len(species) > 0 {
+			blocks[i] = keepSpecies(blocks[i], species)
+		}
+	}
+	return blocks, nil
+}
+
+// keepSpecies drops the rows of species other than the reference and those given
+func keepSpecies(block MafBlock, species []string) MafBlock {
+	kept := []MafSequence{block.Sequences[0]}
+	for _, s := range block.Sequences[1:] {
+		if slices.Contains(species, s.Species) {
+			kept = append(kept, s)
+		}
+	}
+	block.Sequences = kept
+	return block
+}
+
+// ParseBigMaf reads alignment blocks from bigMaf features, whose mafBlock
+// field holds the lines of a MAF block joined by semicolons
+func ParseBigMaf(data []BigBedData, as *AutoSql) ([]MafBlock, error) {
+	columns, err := as.restColumns("mafBlock")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MafBlock, 0, len(data))
+	for _, d := range data {
+		f, err := pickFields(strings.Split(d.Rest, "\t"), columns)
+		if err != nil {
+			return nil, fmt.Errorf("block at %s:%d: %w", d.Chr, d.Start, err)
+		}
+		block, err := parseMafBlock(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("block at %s:%d: %w", d.Chr, d.Start, err)
+		}
+		if len(block.Sequences) == 0 {
+			continue
+		}
+		block.Chr, block.Start, block.End = d.Chr, d.Start, d.End
+		out = append(out, block)
+	}
+	return out, nil
+}
+
+// parseMafBlock reads the a and s lines of a MAF block. Other lines, which
+// describe the context of each row, are skipped.
+func parseMafBlock(text string) (MafBlock, error) {
+	var block MafBlock
+	for line := range strings.SplitSeq(text, ";") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "a":
+			for _, kv := range fields[1:] {
+				if v, ok := strings.CutPrefix(kv, "score="); ok {
+					block.Score, _ = strconv.ParseFloat(v, 64)
+				}
+			}
+		case "s":
+			if len(fields) != 7 {
+				return block, fmt.Errorf("s line has %d fields, expected 7", len(fields))
+			}
+			nums, err := parseInt32s(fields[2], fields[3], fields[5])
+			if err != nil {
+				return block, fmt.Errorf("s line of %s: %w", fields[1], err)
+			}
+			species, chrom, _ := strings.Cut(fields[1], ".")
+			block.Sequences = append(block.Sequences, MafSequence{
+				Species: species,
+				Chrom:   chrom,
+				Start:   nums[0],
+				Size:    nums[1],
+				Strand:  fields[4],
+				SrcSize: nums[2],
+				Text:    fields[6],
+			})
+		}
+	}
+	return block, nil
+}
+
+// ClipMafBlock trims the columns of a block outside [start, end) on the
+// reference, moving every row's start past the bases trimmed before it
+func ClipMafBlock(block MafBlock, start, end int32) MafBlock {
+	if len(block.Sequences) == 0 || (block.Start >= start && block.End <= end) {
+		return block
+	}
+
+	// Find the columns of the first and past the last reference base kept
+	reference := block.Sequences[0]
+	from, to := -1, 0
+	pos := reference.Start
+	for col := 0; col < len(reference.Text); col++ {
+		if reference.Text[col] == '-' {
+			continue
+		}
+		if pos >= start && pos < end {
+			if from < 0 {
+				from = col
+			}
+			to = col + 1
+		}
+		pos++
+	}
+	if from < 0 {
+		from = 0
+	}
+
+	clipped := block
+	clipped.Sequences = make([]MafSequence, len(block.Sequences))
+	for i, s := range block.Sequences {
+		s.Start += bases(s.Text[:min(from, len(s.Text))])
+		s.Text = s.Text[min(from, len(s.Text)):min(to, len(s.Text))]
+		s.Size = bases(s.Text)
+		clipped.Sequences[i] = s
+	}
+	clipped.Start = clipped.Sequences[0].Start
+	clipped.End = clipped.Start + clipped.Sequences[0].Size
+	return clipped
+}
+
+// bases counts the aligned bases of MAF text
+func bases(text string) int32 {
+	return int32(len(text) - strings.Count(text, "-"))
+}
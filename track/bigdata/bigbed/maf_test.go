@@ -0,0 +1,66 @@
+package bigbed
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testMafBlock = "a score=2500.0;s hg38.chr1 100 10 + 248956422 ACGT--ACGTAC;s mm10.chr4 50 11 - 156508116 ACGTTTAC-TAC;i mm10.chr4 C 0 C 0;s rn6.chr2 7 12 + 266435125 ACGTTTACGTAC;"
+
+func TestParseBigMaf(t *testing.T) {
+	as, err := ParseAutoSql(BIGMAF_AS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := ParseBigMaf([]BigBedData{{Chr: "chr1", Start: 100, End: 110, Rest: testMafBlock}}, as)
+	if err != nil {
+		t.Fatalf("ParseBigMaf() error = %v", err)
+	}
+	if len(blocks) != 1 || blocks[0].Score != 2500 || len(blocks[0].Sequences) != 3 {
+		t.Fatalf("unexpected blocks %+v", blocks)
+	}
+	want := MafSequence{Species: "mm10", Chrom: "chr4", Start: 50, Size: 11, Strand: "-", SrcSize: 156508116, Text: "ACGTTTAC-TAC"}
+	if got := blocks[0].Sequences[1]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	if _, err := ParseBigMaf([]BigBedData{{Chr: "chr1", Start: 100, End: 110, Rest: "a score=1;s hg38.chr1 100 10 +"}}, as); err == nil {
+		t.Error("expected an error for a short s line")
+	}
+}
+
+func TestClipMafBlock(t *testing.T) {
+	as, err := ParseAutoSql(BIGMAF_AS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := ParseBigMaf([]BigBedData{{Chr: "chr1", Start: 100, End: 110, Rest: testMafBlock}}, as)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reference bases 102-105 sit in columns 2-7, including a reference gap
+	clipped := ClipMafBlock(blocks[0], 102, 106)
+	if clipped.Start != 102 || clipped.End != 106 {
+		t.Errorf("expected the block at 102-106, got %d-%d", clipped.Start, clipped.End)
+	}
+	type row struct {
+		start, size int32
+		text        string
+	}
+	var got []row
+	for _, s := range clipped.Sequences {
+		got = append(got, row{s.Start, s.Size, s.Text})
+	}
+	want := []row{{102, 4, "GT--AC"}, {52, 6, "GTTTAC"}, {9, 6, "GTTTAC"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if blocks[0].Sequences[0].Text != "ACGT--ACGTAC" {
+		t.Error("expected the parsed block to be left unchanged")
+	}
+
+	if kept := keepSpecies(clipped, []string{"rn6"}); len(kept.Sequences) != 2 || kept.Sequences[1].Species != "rn6" {
+		t.Errorf("expected the reference and rn6, got %+v", kept.Sequences)
+	}
+}
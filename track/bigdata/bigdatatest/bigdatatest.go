@@ -0,0 +1,168 @@
+// Package bigdatatest writes small uncompressed bigWig and bigBed files for
+// tests, with one data block per chromosome and no zoom levels.
+package bigdatatest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	bigWigMagic    = 0x888FFC26
+	bigBedMagic    = 0x8789F2EB
+	chromTreeMagic = 0x78CA8C91
+	indexMagic     = 0x2468ACE0
+	headerSize     = 64
+	prefetchSize   = 4096 // Readers fetch this much at each index node
+)
+
+// Chrom is a chromosome of a file, numbered in the order given
+type Chrom struct {
+	Name string
+	Size int32
+}
+
+// Record is a bigWig value or a bigBed feature, sorted by chromosome and start
+type Record struct {
+	Chrom      string
+	Start, End int32
+	Value      float32 // bigWig value
+	Rest       string  // bigBed columns after the coordinates, tab-separated
+}
+
+// WriteBigWig writes records as bedGraph blocks to dir/name and returns its path
+func WriteBigWig(t testing.TB, dir, name string, chroms []Chrom, records []Record) string {
+	t.Helper()
+	return write(t, dir, name, bigWigMagic, chroms, records, "", func(chromID int32, recs []Record) []byte {
+		b := &bytes.Buffer{}
+		put(b, chromID, recs[0].Start, recs[len(recs)-1].End, int32(0), int32(0), uint8(1), uint8(0), uint16(len(recs)))
+		for _, r := range recs {
+			put(b, r.Start, r.End, r.Value)
+		}
+		return b.Bytes()
+	})
+}
+
+// WriteBigBed writes records to dir/name with an optional autoSql definition
+// and returns its path
+func WriteBigBed(t testing.TB, dir, name string, chroms []Chrom, records []Record, autoSql string) string {
+	t.Helper()
+	return write(t, dir, name, bigBedMagic, chroms, records, autoSql, func(chromID int32, recs []Record) []byte {
+		b := &bytes.Buffer{}
+		for _, r := range recs {
+			put(b, chromID, r.Start, r.End)
+			b.WriteString(r.Rest)
+			b.WriteByte(0)
+		}
+		return b.Bytes()
+	})
+}
+
+// write lays out the header, autoSql, total summary, chromosome tree, data
+// blocks and a one-leaf R tree over the blocks
+func write(t testing.TB, dir, name string, magic uint32, chroms []Chrom, records []Record, autoSql string, block func(int32, []Record) []byte) string {
+	ids := map[string]int32{}
+	keySize := 1
+	for i, c := range chroms {
+		ids[c.Name] = int32(i)
+		keySize = max(keySize, len(c.Name))
+	}
+	byChrom := make([][]Record, len(chroms))
+	for _, r := range records {
+		id, ok := ids[r.Chrom]
+		if !ok {
+			t.Fatalf("record on unknown chromosome %s", r.Chrom)
+		}
+		byChrom[id] = append(byChrom[id], r)
+	}
+
+	body := &bytes.Buffer{} // Everything after the header
+	offset := func() uint64 { return uint64(headerSize + body.Len()) }
+
+	var autoSqlOffset uint64
+	if autoSql != "" {
+		autoSqlOffset = offset()
+		body.WriteString(autoSql)
+		body.WriteByte(0)
+	}
+
+	summaryOffset := offset()
+	var covered uint64
+	minVal, maxVal, sum, squares := math.Inf(1), math.Inf(-1), 0.0, 0.0
+	for _, r := range records {
+		n, v := float64(r.End-r.Start), float64(r.Value)
+		covered += uint64(r.End - r.Start)
+		minVal, maxVal = min(minVal, v), max(maxVal, v)
+		sum, squares = sum+v*n, squares+v*v*n
+	}
+	if len(records) == 0 {
+		minVal, maxVal = 0, 0
+	}
+	put(body, covered, minVal, maxVal, sum, squares)
+
+	chromTreeOffset := offset()
+	put(body, uint32(chromTreeMagic), int32(max(1, len(chroms))), int32(keySize), int32(8), uint64(len(chroms)), uint64(0))
+	put(body, uint8(1), uint8(0), uint16(len(chroms)))
+	for i, c := range chroms {
+		key := make([]byte, keySize)
+		copy(key, c.Name)
+		body.Write(key)
+		put(body, int32(i), c.Size)
+	}
+
+	dataOffset := offset()
+	put(body, uint32(len(records)))
+	type leaf struct {
+		chromID      uint32
+		start, end   uint32
+		offset, size uint64
+	}
+	var leaves []leaf
+	for id, recs := range byChrom {
+		if len(recs) == 0 {
+			continue
+		}
+		start := offset()
+		body.Write(block(int32(id), recs))
+		leaves = append(leaves, leaf{uint32(id), uint32(recs[0].Start), uint32(recs[len(recs)-1].End), start, offset() - start})
+	}
+
+	indexOffset := offset()
+	put(body, uint32(indexMagic), uint32(256), uint64(len(leaves)), uint32(0), uint32(0), uint32(0), uint32(0), offset(), uint32(1), uint32(0))
+	put(body, uint8(1), uint8(0), uint16(len(leaves)))
+	for _, l := range leaves {
+		put(body, l.chromID, l.start, l.chromID, l.end, l.offset, l.size)
+	}
+	body.Write(make([]byte, prefetchSize))
+
+	fieldCount := uint16(0)
+	if magic == bigBedMagic {
+		fieldCount = 3
+		if len(records) > 0 && records[0].Rest != "" {
+			fieldCount += uint16(len(strings.Split(records[0].Rest, "\t")))
+		}
+	}
+	header := &bytes.Buffer{}
+	put(header, magic, uint16(4), uint16(0), chromTreeOffset, dataOffset, indexOffset, fieldCount, min(fieldCount, 3),
+		autoSqlOffset, summaryOffset, int32(0), uint64(0))
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(header.Bytes(), body.Bytes()...), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+// put writes values in little-endian order
+func put(b *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		if err := binary.Write(b, binary.LittleEndian, v); err != nil {
+			panic(err)
+		}
+	}
+}
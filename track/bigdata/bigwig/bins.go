@@ -0,0 +1,30 @@
+package bigwig
+
+// bin is a stretch of the genome averaged into one value. Scaled bodies
+// give bins fractional bounds.
+type bin struct {
+	start, end float64
+}
+
+// binValues returns the base-weighted mean of sorted data over each bin.
+// Bases without data count as 0, as bigWigs leave out regions without
+// signal. Bins must be sorted and not overlap.
+func binValues(data []BigWigData, bins []bin) []float64 {
+	values := make([]float64, len(bins))
+	next := 0 // First record that may reach the current bin
+	for b, bn := range bins {
+		for next < len(data) && float64(data[next].End) <= bn.start {
+			next++
+		}
+		total := 0.0
+		for _, d := range data[next:] {
+			if float64(d.Start) >= bn.end {
+				break
+			}
+			overlap := min(float64(d.End), bn.end) - max(float64(d.Start), bn.start)
+			total += float64(d.Value) * overlap
+		}
+		values[b] = total / (bn.end - bn.start)
+	}
+	return values
+}
@@ -0,0 +1,18 @@
+package bigwig
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBinValues(t *testing.T) {
+	data := []BigWigData{
+		{Chr: "chr1", Start: 100, End: 105, Value: 4},
+		{Chr: "chr1", Start: 110, End: 120, Value: 2},
+	}
+	got := binValues(data, []bin{{95, 105}, {105, 115}, {115, 117.5}, {120, 130}})
+	want := []float64{2, 1, 2, 0}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
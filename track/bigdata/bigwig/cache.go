@@ -51,7 +51,12 @@ func GetCachedWigData(url string, chrom string, start, end int, preRenderedWidth
 
 	// Select optimal zoom level
 	zoomIdx := bw.SelectZoomLevel(start, end, preRenderedWidth)
+	return getCachedWigDataAtZoom(bw, url, chrom, start, end, zoomIdx)
+}
 
+// getCachedWigDataAtZoom reads data at a zoom level, -1 for full resolution,
+// through the range cache
+func getCachedWigDataAtZoom(bw *bigdata.BigData, url string, chrom string, start, end int, zoomIdx int) ([]BigWigData, error) {
 	// Create cache key that includes zoom level
 	var cacheId string
 	if zoomIdx >= 0 {
@@ -123,21 +128,29 @@ func GetCachedWigData(url string, chrom string, start, end int, preRenderedWidth
 
 	// Merge cached and newly fetched data
 	rangeData := cachedData
-	for dc := range dchan {
-		rangeData = append(rangeData, dc)
-	}
+	if len(rangesToFetch) > 0 {
+		// Copy before sorting and merging so concurrent readers of the cached
+		// slices are not disturbed; capping capacity makes merges reallocate
+		rangeData = make([]cache.RangeData[BigWigData], 0, len(cachedData)+len(rangesToFetch))
+		for _, r := range cachedData {
+			rangeData = append(rangeData, cache.RangeData[BigWigData]{Start: r.Start, End: r.End, Data: r.Data[:len(r.Data):len(r.Data)]})
+		}
+		for dc := range dchan {
+			rangeData = append(rangeData, dc)
+		}
 
-	slog.Debug("Collected ranges", "total", len(rangeData))
+		slog.Debug("Collected ranges", "total", len(rangeData))
 
-	sort.Slice(rangeData, func(i, j int) bool {
-		return rangeData[i].Start < rangeData[j].Start
-	})
+		sort.Slice(rangeData, func(i, j int) bool {
+			return rangeData[i].Start < rangeData[j].Start
+		})
 
-	// Merge overlapping/adjacent ranges
-	rangeData = cache.MergeRanges(rangeData)
-	slog.Debug("After merging", "ranges", len(rangeData))
+		// Merge overlapping/adjacent ranges
+		rangeData = cache.MergeRanges(rangeData)
+		slog.Debug("After merging", "ranges", len(rangeData))
 
-	BigWigDataCache.Add(cacheId, rangeData)
+		BigWigDataCache.Add(cacheId, rangeData)
+	}
 
 	// Filter data to only include points within the requested range
 	// Count total points for pre-allocation
@@ -0,0 +1,194 @@
+package bigwig
+
+import (
+	"fmt"
+	"gb-api/track/bigdata"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Operations combining the signal of several bigWigs
+const (
+	OpSum        = "sum"
+	OpMean       = "mean"
+	OpMin        = "min"
+	OpMax        = "max"
+	OpDifference = "difference" // First minus second
+	OpRatio      = "ratio"      // (first + pseudocount) / (second + pseudocount)
+	OpLog2Ratio  = "log2ratio"  // log2 of the ratio
+)
+
+// Operations lists the supported operations
+var Operations = []string{OpSum, OpMean, OpMin, OpMax, OpDifference, OpRatio, OpLog2Ratio}
+
+// MAX_COMPOSITE_INPUTS is the most bigWigs combined in one track
+const MAX_COMPOSITE_INPUTS = 10
+
+// CompositeOptions controls how bigWigs are combined
+type CompositeOptions struct {
+	Operation   string
+	Pseudocount float64 // Added to both sides of ratios
+}
+
+// PairOperation reports whether an operation takes exactly two inputs
+func PairOperation(op string) bool {
+	return op == OpDifference || op == OpRatio || op == OpLog2Ratio
+}
+
+// GetCompositeWigData reads several bigWigs over a region and combines them.
+// Every input is read at the zoom level chosen for the first, so the values
+// summarise bins of the same size, then split at every input's boundaries.
+func GetCompositeWigData(urls []string, chrom string, start, end int, preRenderedWidth int, opts CompositeOptions) ([]BigWigData, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("composite needs at least one bigWig")
+	}
+
+	headers := make([]*bigdata.BigData, len(urls))
+	for i, url := range urls {
+		bw, err := getCachedHeader(url)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create bigwig %s, %w", url, err)
+		}
+		headers[i] = bw
+	}
+	zooms := matchZoomLevels(headers, start, end, preRenderedWidth)
+
+	inputs := make([][]BigWigData, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inputs[i], errs[i] = getCachedWigDataAtZoom(headers[i], url, chrom, start, end, zooms[i])
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read bigwig %s, %w", urls[i], err)
+		}
+	}
+
+	return CombineWigData(inputs, int32(start), int32(end), opts)
+}
+
+// matchZoomLevels picks the zoom level of the first header for the region
+// and the level with the same reduction in the others, falling back to
+// their own choice when they have none
+func matchZoomLevels(headers []*bigdata.BigData, start, end int, preRenderedWidth int) []int {
+	zooms := make([]int, len(headers))
+	first := headers[0].SelectZoomLevel(start, end, preRenderedWidth)
+	zooms[0] = first
+	for i, bw := range headers[1:] {
+		zooms[i+1] = bw.SelectZoomLevel(start, end, preRenderedWidth)
+		if first < 0 {
+			zooms[i+1] = -1
+			continue
+		}
+		reduction := headers[0].ZoomLevels[first].ReductionLevel
+		for j, zoom := range bw.ZoomLevels {
+			if zoom.ReductionLevel == reduction {
+				zooms[i+1] = j
+				break
+			}
+		}
+	}
+	return zooms
+}
+
+// CombineWigData splits [start, end) at the boundaries of every input and
+// combines their values in each piece. Bases without data in an input count
+// as 0, as in binValues; pieces without data in any input, or whose ratio is
+// undefined, are left out.
+func CombineWigData(inputs [][]BigWigData, start, end int32, opts CompositeOptions) ([]BigWigData, error) {
+	if PairOperation(opts.Operation) && len(inputs) != 2 {
+		return nil, fmt.Errorf("%s needs exactly two bigWigs, got %d", opts.Operation, len(inputs))
+	}
+	combine, err := combiner(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every boundary inside the region starts a piece
+	bounds := []int32{start, end}
+	for _, data := range inputs {
+		for _, d := range data {
+			if d.Start > start && d.Start < end {
+				bounds = append(bounds, d.Start)
+			}
+			if d.End > start && d.End < end {
+				bounds = append(bounds, d.End)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	chrom := ""
+	next := make([]int, len(inputs)) // Next record of each input to check
+	values := make([]float64, len(inputs))
+	var out []BigWigData
+	for b := 0; b+1 < len(bounds); b++ {
+		pieceStart, pieceEnd := bounds[b], bounds[b+1]
+		covered := false
+		for i, data := range inputs {
+			// Skip records ending before the piece
+			for next[i] < len(data) && data[next[i]].End <= pieceStart {
+				next[i]++
+			}
+			values[i] = 0
+			if next[i] < len(data) && data[next[i]].Start <= pieceStart {
+				values[i] = float64(data[next[i]].Value)
+				chrom = data[next[i]].Chr
+				covered = true
+			}
+		}
+		if !covered {
+			continue
+		}
+		v := combine(values)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		// Extend the previous piece when the value carries on
+		if n := len(out); n > 0 && out[n-1].End == pieceStart && out[n-1].Value == float32(v) {
+			out[n-1].End = pieceEnd
+			continue
+		}
+		out = append(out, BigWigData{Chr: chrom, Start: pieceStart, End: pieceEnd, Value: float32(v)})
+	}
+	return out, nil
+}
+
+// combiner returns the function combining the values of one piece
+func combiner(opts CompositeOptions) (func([]float64) float64, error) {
+	pc := opts.Pseudocount
+	switch opts.Operation {
+	case OpSum:
+		return sum, nil
+	case OpMean:
+		return func(v []float64) float64 { return sum(v) / float64(len(v)) }, nil
+	case OpMin:
+		return slices.Min[[]float64], nil
+	case OpMax:
+		return slices.Max[[]float64], nil
+	case OpDifference:
+		return func(v []float64) float64 { return v[0] - v[1] }, nil
+	case OpRatio:
+		return func(v []float64) float64 { return (v[0] + pc) / (v[1] + pc) }, nil
+	case OpLog2Ratio:
+		return func(v []float64) float64 { return math.Log2((v[0] + pc) / (v[1] + pc)) }, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", opts.Operation)
+}
+
+func sum(v []float64) float64 {
+	total := 0.0
+	for _, x := range v {
+		total += x
+	}
+	return total
+}
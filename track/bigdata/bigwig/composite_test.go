@@ -0,0 +1,92 @@
+package bigwig
+
+import (
+	"gb-api/track/bigdata"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCombineWigData(t *testing.T) {
+	treatment := []BigWigData{
+		{Chr: "chr1", Start: 0, End: 50, Value: 4},
+		{Chr: "chr1", Start: 50, End: 100, Value: 8},
+	}
+	control := []BigWigData{
+		{Chr: "chr1", Start: 25, End: 75, Value: 2},
+	}
+	type piece struct {
+		start, end int32
+		value      float32
+	}
+	tests := []struct {
+		name string
+		opts CompositeOptions
+		want []piece
+	}{
+		{"sum", CompositeOptions{Operation: OpSum}, []piece{{0, 25, 4}, {25, 50, 6}, {50, 75, 10}, {75, 100, 8}}},
+		{"mean", CompositeOptions{Operation: OpMean}, []piece{{0, 25, 2}, {25, 50, 3}, {50, 75, 5}, {75, 100, 4}}},
+		{"min", CompositeOptions{Operation: OpMin}, []piece{{0, 25, 0}, {25, 75, 2}, {75, 100, 0}}},
+		{"difference", CompositeOptions{Operation: OpDifference}, []piece{{0, 25, 4}, {25, 50, 2}, {50, 75, 6}, {75, 100, 8}}},
+		// Without a pseudocount, pieces missing from the control are undefined
+		{"ratio", CompositeOptions{Operation: OpRatio}, []piece{{25, 50, 2}, {50, 75, 4}}},
+		{"log2 ratio with pseudocount", CompositeOptions{Operation: OpLog2Ratio, Pseudocount: 1}, []piece{
+			{0, 25, float32(math.Log2(5))}, {25, 50, float32(math.Log2(5.0 / 3))}, {50, 75, float32(math.Log2(3))}, {75, 100, float32(math.Log2(9))},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := CombineWigData([][]BigWigData{treatment, control}, 0, 100, tt.opts)
+			if err != nil {
+				t.Fatalf("CombineWigData() error = %v", err)
+			}
+			var got []piece
+			for _, d := range data {
+				if d.Chr != "chr1" {
+					t.Errorf("expected chr1, got %+v", d)
+				}
+				got = append(got, piece{d.Start, d.End, d.Value})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCombineWigDataGaps(t *testing.T) {
+	a := []BigWigData{{Chr: "chr1", Start: 10, End: 20, Value: 1}}
+	b := []BigWigData{{Chr: "chr1", Start: 30, End: 40, Value: 1}}
+	data, err := CombineWigData([][]BigWigData{a, b, a}, 0, 50, CompositeOptions{Operation: OpSum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BigWigData{{Chr: "chr1", Start: 10, End: 20, Value: 2}, {Chr: "chr1", Start: 30, End: 40, Value: 1}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("expected pieces without data left out, got %+v", data)
+	}
+
+	if _, err := CombineWigData([][]BigWigData{a, b, a}, 0, 50, CompositeOptions{Operation: OpRatio}); err == nil {
+		t.Error("expected an error for a ratio of three inputs")
+	}
+}
+
+func TestMatchZoomLevels(t *testing.T) {
+	levels := func(reductions ...int32) *bigdata.BigData {
+		b := &bigdata.BigData{}
+		for i, r := range reductions {
+			b.ZoomLevels = append(b.ZoomLevels, bigdata.ZoomLevelHeader{Index: i, ReductionLevel: r})
+		}
+		return b
+	}
+	headers := []*bigdata.BigData{levels(10, 40, 160), levels(40, 160), levels(20, 80)}
+
+	// 100 bases per pixel picks 40 in the first file, matched by the second
+	if got := matchZoomLevels(headers, 0, 100000, 1000); !reflect.DeepEqual(got, []int{1, 0, 1}) {
+		t.Errorf("expected zooms [1 0 1], got %v", got)
+	}
+	// Full resolution for the first means full resolution for all
+	if got := matchZoomLevels(headers, 0, 1000, 1000); !reflect.DeepEqual(got, []int{-1, -1, -1}) {
+		t.Errorf("expected full resolution, got %v", got)
+	}
+}
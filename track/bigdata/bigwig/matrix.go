@@ -0,0 +1,272 @@
+package bigwig
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Matrix modes, as in deepTools computeMatrix
+const (
+	MatrixReferencePoint = "reference-point" // Flanks around one point of each feature
+	MatrixScaleRegions   = "scale-regions"   // Each feature stretched or shrunk to the same length, with flanks
+)
+
+// Reference points of features, 5' and 3' following the strand
+const (
+	ReferenceStart  = "start"
+	ReferenceEnd    = "end"
+	ReferenceCenter = "center"
+)
+
+// MatrixModes and ReferencePoints list the supported options
+var (
+	MatrixModes     = []string{MatrixReferencePoint, MatrixScaleRegions}
+	ReferencePoints = []string{ReferenceStart, ReferenceEnd, ReferenceCenter}
+)
+
+const (
+	MAX_MATRIX_INPUTS       = 10     // Most bigWigs in a matrix
+	MAX_MATRIX_FEATURES     = 5000   // Most rows of a matrix
+	MAX_MATRIX_BINS         = 1000   // Most columns per bigWig
+	MAX_MATRIX_FLANK        = 100000 // Widest flank or scaled body in bp
+	DEFAULT_MATRIX_BIN_SIZE = 10
+)
+
+// matrixConcurrency is how many features are read at once
+const matrixConcurrency = 8
+
+// MatrixFeature is a row of a matrix. Start and End are 0-based and half-open.
+type MatrixFeature struct {
+	Chrom  string `json:"chrom"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Strand string `json:"strand,omitempty"` // - reverses the row, anything else reads it forward
+	Name   string `json:"name,omitempty"`
+}
+
+// MatrixOptions controls how signal around features is binned
+type MatrixOptions struct {
+	Mode           string // Matrix mode, reference-point when empty
+	ReferencePoint string // Point of reference-point mode, start when empty
+	Upstream       int    // Flank 5' of the reference point or feature in bp
+	Downstream     int    // Flank 3' of the reference point or feature in bp
+	BodyLength     int    // Length features are scaled to in scale-regions mode
+	BinSize        int    // Bin width in bp, 0 uses DEFAULT_MATRIX_BIN_SIZE
+}
+
+func (opts MatrixOptions) withDefaults() MatrixOptions {
+	if opts.Mode == "" {
+		opts.Mode = MatrixReferencePoint
+	}
+	if opts.Mode == MatrixReferencePoint && opts.ReferencePoint == "" {
+		opts.ReferencePoint = ReferenceStart
+	}
+	if opts.BinSize <= 0 {
+		opts.BinSize = DEFAULT_MATRIX_BIN_SIZE
+	}
+	return opts
+}
+
+// SignalMatrix holds binned signal around features, one block of columns
+// per bigWig. Columns run 5' to 3': upstream bins, body bins in
+// scale-regions mode, then downstream bins.
+type SignalMatrix struct {
+	Mode           string          `json:"mode"`
+	ReferencePoint string          `json:"referencePoint,omitempty"`
+	BinSize        int             `json:"binSize"`
+	UpstreamBins   int             `json:"upstreamBins"`
+	BodyBins       int             `json:"bodyBins,omitempty"`
+	DownstreamBins int             `json:"downstreamBins"`
+	Features       []MatrixFeature `json:"features"` // Rows, in the order given
+	Samples        []MatrixSample  `json:"samples"`
+}
+
+// MatrixSample is the block of a matrix read from one bigWig
+type MatrixSample struct {
+	URL    string      `json:"url"`
+	Values [][]float32 `json:"values"` // One row per feature
+	Mean   []float32   `json:"mean"`   // Column means over features, the metaplot profile
+}
+
+// ValidateMatrixOptions checks the mode, reference point and bin layout
+func ValidateMatrixOptions(opts MatrixOptions) error {
+	opts = opts.withDefaults()
+	if !slices.Contains(MatrixModes, opts.Mode) {
+		return fmt.Errorf("unknown mode %q", opts.Mode)
+	}
+	if opts.Mode == MatrixReferencePoint && !slices.Contains(ReferencePoints, opts.ReferencePoint) {
+		return fmt.Errorf("unknown reference point %q", opts.ReferencePoint)
+	}
+	for _, length := range []int{opts.Upstream, opts.Downstream, opts.BodyLength} {
+		if length < 0 || length > MAX_MATRIX_FLANK {
+			return fmt.Errorf("flanks and body length must be between 0 and %d", MAX_MATRIX_FLANK)
+		}
+		if length%opts.BinSize != 0 {
+			return fmt.Errorf("flanks and body length must be multiples of the bin size %d", opts.BinSize)
+		}
+	}
+	if opts.Mode == MatrixScaleRegions && opts.BodyLength == 0 {
+		return fmt.Errorf("scale-regions needs a body length")
+	}
+	bins := (opts.Upstream + opts.Downstream) / opts.BinSize
+	if opts.Mode == MatrixScaleRegions {
+		bins += opts.BodyLength / opts.BinSize
+	}
+	if bins == 0 || bins > MAX_MATRIX_BINS {
+		return fmt.Errorf("matrix must have between 1 and %d bins, got %d", MAX_MATRIX_BINS, bins)
+	}
+	return nil
+}
+
+// ComputeMatrix bins the signal of each bigWig around each feature. Every
+// feature is read at the zoom level suiting its own bins, a few at a time.
+func ComputeMatrix(urls []string, features []MatrixFeature, opts MatrixOptions) (SignalMatrix, error) {
+	if err := ValidateMatrixOptions(opts); err != nil {
+		return SignalMatrix{}, err
+	}
+	if len(features) > MAX_MATRIX_FEATURES {
+		return SignalMatrix{}, fmt.Errorf("matrix can have at most %d features, got %d", MAX_MATRIX_FEATURES, len(features))
+	}
+	for _, f := range features {
+		if f.Start < 0 || f.End <= f.Start {
+			return SignalMatrix{}, fmt.Errorf("feature %s:%d-%d must have 0 <= start < end", f.Chrom, f.Start, f.End)
+		}
+	}
+	opts = opts.withDefaults()
+	matrix := SignalMatrix{
+		Mode:           opts.Mode,
+		ReferencePoint: opts.ReferencePoint,
+		BinSize:        opts.BinSize,
+		UpstreamBins:   opts.Upstream / opts.BinSize,
+		DownstreamBins: opts.Downstream / opts.BinSize,
+		Features:       features,
+		Samples:        make([]MatrixSample, len(urls)),
+	}
+	if opts.Mode == MatrixScaleRegions {
+		matrix.BodyBins = opts.BodyLength / opts.BinSize
+	}
+
+	sem := make(chan struct{}, matrixConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for s, url := range urls {
+		values := make([][]float32, len(features))
+		matrix.Samples[s] = MatrixSample{URL: url, Values: values}
+		for i, f := range features {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				row, err := featureRow(url, f, opts)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("Failed to read bigwig %s at %s:%d-%d, %w", url, f.Chrom, f.Start, f.End, err)
+					}
+					mu.Unlock()
+					return
+				}
+				values[i] = row
+			}()
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return SignalMatrix{}, firstErr
+	}
+
+	for s := range matrix.Samples {
+		matrix.Samples[s].Mean = columnMeans(matrix.Samples[s].Values)
+	}
+	return matrix, nil
+}
+
+// featureRow reads the signal under a feature's bins and orders it 5' to 3'
+func featureRow(url string, f MatrixFeature, opts MatrixOptions) ([]float32, error) {
+	bins := featureBins(f, opts)
+	fetchStart := max(0, int(math.Floor(bins[0].start)))
+	fetchEnd := int(math.Ceil(bins[len(bins)-1].end))
+	var data []BigWigData
+	if fetchEnd > fetchStart {
+		var err error
+		data, err = GetCachedWigData(url, f.Chrom, fetchStart, fetchEnd, len(bins))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	row := make([]float32, len(bins))
+	for b, v := range binValues(data, bins) {
+		row[b] = float32(v)
+	}
+	if f.Strand == "-" {
+		slices.Reverse(row)
+	}
+	return row, nil
+}
+
+// featureBins lays out a feature's bins in genome order. On strand - the
+// flanks swap sides and reference points mirror, so reversing the row puts
+// it 5' to 3'.
+func featureBins(f MatrixFeature, opts MatrixOptions) []bin {
+	left, right := opts.Upstream, opts.Downstream
+	if f.Strand == "-" {
+		left, right = right, left
+	}
+	size := float64(opts.BinSize)
+	var bins []bin
+	flank := func(from float64, length int) {
+		for b := 0; b < length/opts.BinSize; b++ {
+			bins = append(bins, bin{from + float64(b)*size, from + float64(b+1)*size})
+		}
+	}
+
+	if opts.Mode == MatrixScaleRegions {
+		flank(float64(f.Start-left), left)
+		body := opts.BodyLength / opts.BinSize
+		width := float64(f.End-f.Start) / float64(body)
+		for b := 0; b < body; b++ {
+			bins = append(bins, bin{float64(f.Start) + float64(b)*width, float64(f.Start) + float64(b+1)*width})
+		}
+		flank(float64(f.End), right)
+		return bins
+	}
+
+	point := float64(f.Start+f.End) / 2
+	switch opts.ReferencePoint {
+	case ReferenceStart:
+		point = float64(f.Start)
+		if f.Strand == "-" {
+			point = float64(f.End)
+		}
+	case ReferenceEnd:
+		point = float64(f.End)
+		if f.Strand == "-" {
+			point = float64(f.Start)
+		}
+	}
+	flank(point-float64(left), left+right)
+	return bins
+}
+
+// columnMeans averages each column over the rows
+func columnMeans(rows [][]float32) []float32 {
+	if len(rows) == 0 {
+		return []float32{}
+	}
+	sums := make([]float64, len(rows[0]))
+	for _, row := range rows {
+		for c, v := range row {
+			sums[c] += float64(v)
+		}
+	}
+	means := make([]float32, len(sums))
+	for c, total := range sums {
+		means[c] = float32(total / float64(len(rows)))
+	}
+	return means
+}
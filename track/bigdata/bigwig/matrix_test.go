@@ -0,0 +1,132 @@
+package bigwig
+
+import (
+	"gb-api/track/bigdata/bigdatatest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeatureBins(t *testing.T) {
+	tests := []struct {
+		name    string
+		feature MatrixFeature
+		opts    MatrixOptions
+		want    []bin
+	}{
+		{
+			name:    "start on +",
+			feature: MatrixFeature{Chrom: "chr1", Start: 100, End: 200, Strand: "+"},
+			opts:    MatrixOptions{Upstream: 20, Downstream: 10, BinSize: 10},
+			want:    []bin{{80, 90}, {90, 100}, {100, 110}},
+		},
+		{
+			name:    "start on - mirrors",
+			feature: MatrixFeature{Chrom: "chr1", Start: 100, End: 200, Strand: "-"},
+			opts:    MatrixOptions{Upstream: 20, Downstream: 10, BinSize: 10},
+			want:    []bin{{190, 200}, {200, 210}, {210, 220}},
+		},
+		{
+			name:    "center",
+			feature: MatrixFeature{Chrom: "chr1", Start: 100, End: 200},
+			opts:    MatrixOptions{ReferencePoint: ReferenceCenter, Upstream: 10, Downstream: 10, BinSize: 10},
+			want:    []bin{{140, 150}, {150, 160}},
+		},
+		{
+			name:    "scale-regions",
+			feature: MatrixFeature{Chrom: "chr1", Start: 100, End: 130, Strand: "-"},
+			opts:    MatrixOptions{Mode: MatrixScaleRegions, Upstream: 10, BodyLength: 20, BinSize: 10},
+			want:    []bin{{100, 115}, {115, 130}, {130, 140}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := featureBins(tt.feature, tt.opts.withDefaults())
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestColumnMeans(t *testing.T) {
+	got := columnMeans([][]float32{{1, 2}, {3, 6}})
+	if !equalValues(got, []float32{2, 4}) {
+		t.Errorf("expected [2 4], got %v", got)
+	}
+	if got := columnMeans(nil); len(got) != 0 {
+		t.Errorf("expected no means without rows, got %v", got)
+	}
+}
+
+func TestValidateMatrixOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  MatrixOptions
+		valid bool
+	}{
+		{"reference-point defaults", MatrixOptions{Upstream: 1000, Downstream: 1000}, true},
+		{"scale-regions", MatrixOptions{Mode: MatrixScaleRegions, Upstream: 500, BodyLength: 1000, BinSize: 50}, true},
+		{"unknown mode", MatrixOptions{Mode: "profile", Upstream: 100}, false},
+		{"unknown reference point", MatrixOptions{ReferencePoint: "TSS", Upstream: 100}, false},
+		{"no bins", MatrixOptions{}, false},
+		{"scale-regions without body", MatrixOptions{Mode: MatrixScaleRegions, Upstream: 100}, false},
+		{"flank off the bin grid", MatrixOptions{Upstream: 105}, false},
+		{"too many bins", MatrixOptions{Upstream: 20000, BinSize: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMatrixOptions(tt.opts)
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestComputeMatrixWithoutFeatures(t *testing.T) {
+	matrix, err := ComputeMatrix([]string{"https://example.org/a.bw"}, nil, MatrixOptions{Upstream: 100, Downstream: 100})
+	if err != nil {
+		t.Fatalf("ComputeMatrix() error = %v", err)
+	}
+	if matrix.UpstreamBins != 10 || matrix.DownstreamBins != 10 || len(matrix.Samples) != 1 {
+		t.Errorf("unexpected layout %+v", matrix)
+	}
+	if _, err := ComputeMatrix(nil, []MatrixFeature{{Chrom: "chr1", Start: 10, End: 10}}, MatrixOptions{Upstream: 100}); err == nil {
+		t.Error("expected an error for an empty feature")
+	}
+}
+
+func TestComputeMatrix(t *testing.T) {
+	dir := t.TempDir()
+	var records []bigdatatest.Record
+	for start := int32(0); start < 50000; start += 100 {
+		records = append(records, bigdatatest.Record{Chrom: "chr1", Start: start, End: start + 100, Value: 2})
+	}
+	bigdatatest.WriteBigWig(t, dir, "matrix.bw", []bigdatatest.Chrom{{Name: "chr1", Size: 100000}}, records)
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+
+	// Features on one chromosome are read at once through the same cache entry
+	var features []MatrixFeature
+	for i := 1; i <= 40; i++ {
+		features = append(features, MatrixFeature{Chrom: "chr1", Start: 1000 * i, End: 1000*i + 500})
+	}
+	matrix, err := ComputeMatrix([]string{server.URL + "/matrix.bw"}, features, MatrixOptions{Upstream: 100, Downstream: 100})
+	if err != nil {
+		t.Fatalf("ComputeMatrix() error = %v", err)
+	}
+	for i, row := range matrix.Samples[0].Values {
+		for b, v := range row {
+			if v != 2 {
+				t.Fatalf("row %d bin %d: expected 2, got %v", i, b, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package bigwig
+
+import (
+	"cmp"
+	"fmt"
+	"gb-api/track/bigdata"
+	"math"
+	"slices"
+)
+
+// Normalisation methods, each a linear map of the raw values
+const (
+	NormScale  = "scale"  // Multiply by a given factor
+	NormCPM    = "cpm"    // Per million units of the file's total signal
+	NormRPKM   = "rpkm"   // Per kilobase per million units of total signal
+	NormZScore = "zscore" // Standard deviations from the file's mean
+)
+
+// Transforms applied after normalisation
+const (
+	TransformLog1p = "log1p"
+	TransformLog2  = "log2"
+	TransformSqrt  = "sqrt"
+)
+
+// NormalizationMethods and Transforms list the supported options
+var (
+	NormalizationMethods = []string{NormScale, NormCPM, NormRPKM, NormZScore}
+	Transforms           = []string{TransformLog1p, TransformLog2, TransformSqrt}
+)
+
+// NormalizeOptions controls how bigWig values are normalised, transformed
+// and clipped, in that order
+type NormalizeOptions struct {
+	Method         string  // Normalisation method, none when empty
+	ScaleFactor    float64 // Factor of the scale method
+	Transform      string  // Transform, none when empty
+	ClipPercentile float64 // Clamp values to this percentile of the region and its mirror, e.g. 99 to the 1st-99th, 0 for none
+}
+
+// Enabled reports whether the options change any value
+func (o NormalizeOptions) Enabled() bool {
+	return o.Method != "" || o.Transform != "" || o.ClipPercentile > 0
+}
+
+// Normalization describes how values were changed: each raw value became
+// transform((raw - offset) * scale), clamped to [clipMin, clipMax]
+type Normalization struct {
+	Method    string   `json:"method,omitempty"`
+	Scale     float64  `json:"scale"`
+	Offset    float64  `json:"offset,omitempty"`
+	Transform string   `json:"transform,omitempty"`
+	ClipMin   *float32 `json:"clipMin,omitempty"`
+	ClipMax   *float32 `json:"clipMax,omitempty"`
+}
+
+// GetTotalSummary returns the summary of every value in a bigWig, which
+// normalization scales by
+func GetTotalSummary(url string) (bigdata.TotalSummary, error) {
+	bw, err := getCachedHeader(url)
+	if err != nil {
+		return bigdata.TotalSummary{}, fmt.Errorf("Failed to create bigwig, %w", err)
+	}
+	return bw.TotalSummary, nil
+}
+
+// Normalize returns normalised copies of values. Values a transform leaves
+// undefined, such as log2 of 0, are left out.
+func Normalize(data []BigWigData, summary bigdata.TotalSummary, opts NormalizeOptions) ([]BigWigData, Normalization, error) {
+	norm := Normalization{Method: opts.Method, Scale: 1, Transform: opts.Transform}
+	switch opts.Method {
+	case "":
+	case NormScale:
+		if opts.ScaleFactor <= 0 {
+			return nil, norm, fmt.Errorf("scale needs a positive factor")
+		}
+		norm.Scale = opts.ScaleFactor
+	case NormCPM, NormRPKM:
+		if summary.SumData <= 0 {
+			return nil, norm, fmt.Errorf("%s needs a positive total signal, got %g", opts.Method, summary.SumData)
+		}
+		norm.Scale = 1e6 / summary.SumData
+		if opts.Method == NormRPKM {
+			norm.Scale *= 1000
+		}
+	case NormZScore:
+		if summary.BasesCovered == 0 {
+			return nil, norm, fmt.Errorf("zscore needs a summary of covered bases")
+		}
+		n := float64(summary.BasesCovered)
+		mean := summary.SumData / n
+		variance := summary.SumSquares/n - mean*mean
+		if variance <= 0 {
+			return nil, norm, fmt.Errorf("zscore needs a positive variance, got %g", variance)
+		}
+		norm.Offset, norm.Scale = mean, 1/math.Sqrt(variance)
+	default:
+		return nil, norm, fmt.Errorf("unknown normalization %q", opts.Method)
+	}
+
+	transform, err := transformer(opts.Transform)
+	if err != nil {
+		return nil, norm, err
+	}
+	out := make([]BigWigData, 0, len(data))
+	for _, d := range data {
+		v := transform((float64(d.Value) - norm.Offset) * norm.Scale)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		d.Value = float32(v)
+		out = append(out, d)
+	}
+
+	if opts.ClipPercentile > 0 && len(out) > 0 {
+		low, high := percentile(out, 100-opts.ClipPercentile), percentile(out, opts.ClipPercentile)
+		for i := range out {
+			out[i].Value = min(max(out[i].Value, low), high)
+		}
+		norm.ClipMin, norm.ClipMax = &low, &high
+	}
+	return out, norm, nil
+}
+
+// transformer returns the function applied to normalised values
+func transformer(transform string) (func(float64) float64, error) {
+	switch transform {
+	case "":
+		return func(v float64) float64 { return v }, nil
+	case TransformLog1p:
+		return math.Log1p, nil
+	case TransformLog2:
+		return math.Log2, nil
+	case TransformSqrt:
+		return math.Sqrt, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", transform)
+}
+
+// percentile returns the value below which p percent of the covered bases
+// lie, weighting each value by its length
+func percentile(data []BigWigData, p float64) float32 {
+	sorted := slices.Clone(data)
+	slices.SortFunc(sorted, func(a, b BigWigData) int { return cmp.Compare(a.Value, b.Value) })
+	total := 0.0
+	for _, d := range sorted {
+		total += float64(d.End - d.Start)
+	}
+	target := total * min(max(p, 0), 100) / 100
+	seen := 0.0
+	for _, d := range sorted {
+		seen += float64(d.End - d.Start)
+		if seen >= target {
+			return d.Value
+		}
+	}
+	return sorted[len(sorted)-1].Value
+}
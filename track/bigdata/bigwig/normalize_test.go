@@ -0,0 +1,74 @@
+package bigwig
+
+import (
+	"gb-api/track/bigdata"
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	data := []BigWigData{
+		{Chr: "chr1", Start: 0, End: 10, Value: 0},
+		{Chr: "chr1", Start: 10, End: 20, Value: 3},
+		{Chr: "chr1", Start: 20, End: 30, Value: 7},
+	}
+	// Mean 4 and variance 4 over 100 bases
+	summary := bigdata.TotalSummary{BasesCovered: 100, SumData: 400, SumSquares: 2000}
+
+	tests := []struct {
+		name      string
+		opts      NormalizeOptions
+		want      []float64
+		wantScale float64
+	}{
+		{"scale", NormalizeOptions{Method: NormScale, ScaleFactor: 2}, []float64{0, 6, 14}, 2},
+		{"cpm", NormalizeOptions{Method: NormCPM}, []float64{0, 7500, 17500}, 2500},
+		{"rpkm", NormalizeOptions{Method: NormRPKM}, []float64{0, 7.5e6, 17.5e6}, 2.5e6},
+		{"zscore", NormalizeOptions{Method: NormZScore}, []float64{-2, -0.5, 1.5}, 0.5},
+		{"log1p", NormalizeOptions{Transform: TransformLog1p}, []float64{0, math.Log1p(3), math.Log1p(7)}, 1},
+		// log2 of 0 is undefined, so the first value is left out
+		{"log2", NormalizeOptions{Transform: TransformLog2}, []float64{math.Log2(3), math.Log2(7)}, 1},
+		{"scaled sqrt", NormalizeOptions{Method: NormScale, ScaleFactor: 3, Transform: TransformSqrt}, []float64{0, 3, math.Sqrt(21)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, norm, err := Normalize(data, summary, tt.opts)
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if norm.Scale != tt.wantScale || norm.Method != tt.opts.Method || norm.Transform != tt.opts.Transform {
+				t.Errorf("unexpected normalization %+v", norm)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d values, got %+v", len(tt.want), got)
+			}
+			for i, d := range got {
+				if math.Abs(float64(d.Value)-tt.want[i]) > 1e-3*math.Max(1, math.Abs(tt.want[i])) {
+					t.Errorf("value %d: expected %g, got %g", i, tt.want[i], d.Value)
+				}
+			}
+		})
+	}
+	if data[1].Value != 3 {
+		t.Error("expected the input to be left unchanged")
+	}
+
+	if _, _, err := Normalize(data, bigdata.TotalSummary{}, NormalizeOptions{Method: NormCPM}); err == nil {
+		t.Error("expected an error without a total signal")
+	}
+}
+
+func TestNormalizeClip(t *testing.T) {
+	// 97 bases at 1 and an outlier of 3 bases
+	data := []BigWigData{
+		{Chr: "chr1", Start: 0, End: 97, Value: 1},
+		{Chr: "chr1", Start: 97, End: 100, Value: 50},
+	}
+	got, norm, err := Normalize(data, bigdata.TotalSummary{}, NormalizeOptions{ClipPercentile: 95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if norm.ClipMax == nil || *norm.ClipMax != 1 || got[1].Value != 1 {
+		t.Errorf("expected the outlier clipped to 1, got %+v with %+v", got, norm)
+	}
+}
@@ -0,0 +1,146 @@
+package bigwig
+
+import (
+	"fmt"
+	"gb-api/track/bigdata"
+	"math"
+)
+
+// MAX_REGION_WINDOW is the widest region called from full-resolution data
+const MAX_REGION_WINDOW = 10000000
+
+// SignalRegion is a stretch of signal above a threshold
+type SignalRegion struct {
+	Chr   string  `json:"chr"`
+	Start int32   `json:"start"`
+	End   int32   `json:"end"`
+	Max   float32 `json:"max"`
+	Mean  float32 `json:"mean"` // Over the bases above the threshold, leaving out merged gaps
+}
+
+// RegionOptions controls region calling
+type RegionOptions struct {
+	Threshold     float64 // Signal must exceed this
+	MinLength     int     // Shorter regions, after merging, are dropped
+	MergeDistance int     // Regions this close or closer are joined
+}
+
+// MAX_REGION_EXTENSION is how far past each edge of a view data is read to
+// find the ends of regions crossing it
+const MAX_REGION_EXTENSION = 1000000
+
+// minRegionExtension is the first read past each edge, doubled while a
+// region crosses it
+const minRegionExtension = 1000
+
+// GetSignalRegions calls the regions overlapping a view from full-resolution
+// data and returns them whole, so they do not change as the view is panned
+func GetSignalRegions(url string, chrom string, start, end int, opts RegionOptions) ([]SignalRegion, error) {
+	return regionsInView(func(start, end int) ([]BigWigData, error) {
+		return GetCachedWigData(url, chrom, start, end, 0)
+	}, start, end, opts)
+}
+
+// regionsInView calls regions from data read past the view's edges, widening
+// the read until the regions crossing them end, at most MAX_REGION_EXTENSION
+// on either side. Regions still open there are kept whatever their length.
+func regionsInView(read func(start, end int) ([]BigWigData, error), start, end int, opts RegionOptions) ([]SignalRegion, error) {
+	// Lengths are checked once regions are whole
+	unfiltered := opts
+	unfiltered.MinLength = 0
+	first := min(max(opts.MergeDistance+1, minRegionExtension), MAX_REGION_EXTENSION)
+	left, right := first, first
+	for {
+		fetchStart, fetchEnd := max(0, start-left), end+right
+		data, err := read(fetchStart, fetchEnd)
+		if err != nil {
+			return nil, err
+		}
+		var inView []SignalRegion
+		for _, r := range CallRegions(data, unfiltered) {
+			if r.End > int32(start) && r.Start < int32(end) {
+				inView = append(inView, r)
+			}
+		}
+
+		// A region is open when data it could extend or merge into was not read
+		n := len(inView)
+		openLeft := n > 0 && fetchStart > 0 && int(inView[0].Start)-opts.MergeDistance <= fetchStart
+		openRight := n > 0 && int(inView[n-1].End)+opts.MergeDistance >= fetchEnd
+		widened := false
+		if openLeft && left < MAX_REGION_EXTENSION {
+			left, widened = min(2*left, MAX_REGION_EXTENSION), true
+		}
+		if openRight && right < MAX_REGION_EXTENSION {
+			right, widened = min(2*right, MAX_REGION_EXTENSION), true
+		}
+		if widened {
+			continue
+		}
+
+		regions := []SignalRegion{}
+		for i, r := range inView {
+			open := (i == 0 && openLeft) || (i == n-1 && openRight)
+			if open || int(r.End-r.Start) >= opts.MinLength {
+				regions = append(regions, r)
+			}
+		}
+		return regions, nil
+	}
+}
+
+// CallRegions joins consecutive data above the threshold into regions,
+// merges regions within the merge distance and drops the short ones
+func CallRegions(data []BigWigData, opts RegionOptions) []SignalRegion {
+	regions := []SignalRegion{}
+	var sum float64 // Signal times bases of the current region
+	var bases int32 // Bases above the threshold in the current region
+	flush := func() {
+		n := len(regions)
+		if n == 0 {
+			return
+		}
+		r := &regions[n-1]
+		r.Mean = float32(sum / float64(bases))
+		if int(r.End-r.Start) < opts.MinLength {
+			regions = regions[:n-1]
+		}
+	}
+
+	for _, d := range data {
+		if float64(d.Value) <= opts.Threshold {
+			continue
+		}
+		length := d.End - d.Start
+		if n := len(regions); n > 0 && d.Chr == regions[n-1].Chr && int(d.Start-regions[n-1].End) <= opts.MergeDistance {
+			r := &regions[n-1]
+			r.End = max(r.End, d.End)
+			r.Max = max(r.Max, d.Value)
+			sum += float64(d.Value) * float64(length)
+			bases += length
+			continue
+		}
+		flush()
+		regions = append(regions, SignalRegion{Chr: d.Chr, Start: d.Start, End: d.End, Max: d.Value})
+		sum, bases = float64(d.Value)*float64(length), length
+	}
+	flush()
+	return regions
+}
+
+// SummaryPercentile estimates a percentile of a bigWig's values from its
+// total summary, which holds only their mean and variance: it assumes the
+// covered bases are normally distributed and clamps to the observed range
+func SummaryPercentile(summary bigdata.TotalSummary, p float64) (float64, error) {
+	if p <= 0 || p >= 100 {
+		return 0, fmt.Errorf("percentile must be between 0 and 100, got %g", p)
+	}
+	if summary.BasesCovered == 0 {
+		return 0, fmt.Errorf("percentile needs a summary of covered bases")
+	}
+	n := float64(summary.BasesCovered)
+	mean := summary.SumData / n
+	sd := math.Sqrt(max(0, summary.SumSquares/n-mean*mean))
+	z := math.Sqrt2 * math.Erfinv(2*p/100-1)
+	return min(max(mean+z*sd, summary.MinVal), summary.MaxVal), nil
+}
@@ -0,0 +1,166 @@
+package bigwig
+
+import (
+	"gb-api/track/bigdata"
+	"gb-api/track/bigdata/bigdatatest"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallRegions(t *testing.T) {
+	data := []BigWigData{
+		{Chr: "chr1", Start: 100, End: 110, Value: 5},
+		{Chr: "chr1", Start: 110, End: 120, Value: 9},
+		{Chr: "chr1", Start: 120, End: 130, Value: 1}, // Below the threshold
+		{Chr: "chr1", Start: 130, End: 140, Value: 3},
+		{Chr: "chr1", Start: 200, End: 205, Value: 4}, // Too short alone
+	}
+
+	got := CallRegions(data, RegionOptions{Threshold: 2})
+	want := []SignalRegion{
+		{Chr: "chr1", Start: 100, End: 120, Max: 9, Mean: 7},
+		{Chr: "chr1", Start: 130, End: 140, Max: 3, Mean: 3},
+		{Chr: "chr1", Start: 200, End: 205, Max: 4, Mean: 4},
+	}
+	if !equalRegions(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// The gap of 10 merges, leaving it out of the mean
+	got = CallRegions(data, RegionOptions{Threshold: 2, MergeDistance: 10, MinLength: 10})
+	want = []SignalRegion{{Chr: "chr1", Start: 100, End: 140, Max: 9, Mean: 17.0 / 3}}
+	if !equalRegions(got, want) {
+		t.Errorf("merged: expected %v, got %v", want, got)
+	}
+
+	if got := CallRegions(data, RegionOptions{Threshold: 9}); len(got) != 0 {
+		t.Errorf("expected no regions above the maximum, got %v", got)
+	}
+}
+
+func TestSummaryPercentile(t *testing.T) {
+	// Mean 2 and standard deviation 1 over 100 bases
+	summary := bigdata.TotalSummary{BasesCovered: 100, MinVal: 0, MaxVal: 4, SumData: 200, SumSquares: 500}
+
+	got, err := SummaryPercentile(summary, 50)
+	if err != nil {
+		t.Fatalf("SummaryPercentile() error = %v", err)
+	}
+	if math.Abs(got-2) > 1e-9 {
+		t.Errorf("median: expected 2, got %g", got)
+	}
+	if got, _ := SummaryPercentile(summary, 97.5); math.Abs(got-3.96) > 0.01 {
+		t.Errorf("97.5th: expected about 3.96, got %g", got)
+	}
+	if got, _ := SummaryPercentile(summary, 99.99); got != 4 {
+		t.Errorf("expected the maximum to clamp, got %g", got)
+	}
+
+	if _, err := SummaryPercentile(summary, 100); err == nil {
+		t.Error("expected an error for the 100th percentile")
+	}
+	if _, err := SummaryPercentile(bigdata.TotalSummary{}, 90); err == nil {
+		t.Error("expected an error for an empty summary")
+	}
+}
+
+func equalRegions(a, b []SignalRegion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Chr != b[i].Chr || a[i].Start != b[i].Start || a[i].End != b[i].End ||
+			a[i].Max != b[i].Max || math.Abs(float64(a[i].Mean-b[i].Mean)) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRegionsInView(t *testing.T) {
+	signal := []BigWigData{
+		{Chr: "chr1", Start: 900, End: 5000, Value: 5},
+		{Chr: "chr1", Start: 6000, End: 6100, Value: 5},
+	}
+	reads := 0
+	read := func(start, end int) ([]BigWigData, error) {
+		reads++
+		var out []BigWigData
+		for _, d := range signal {
+			if int(d.End) > start && int(d.Start) < end {
+				d.Start, d.End = max(d.Start, int32(start)), min(d.End, int32(end))
+				out = append(out, d)
+			}
+		}
+		return out, nil
+	}
+	opts := RegionOptions{Threshold: 1, MinLength: 500}
+
+	// The region crossing the view is read whole, so panning keeps it
+	for _, view := range [][2]int{{0, 1000}, {500, 1500}, {4900, 5500}} {
+		reads = 0
+		got, err := regionsInView(read, view[0], view[1], opts)
+		if err != nil {
+			t.Fatalf("regionsInView() error = %v", err)
+		}
+		want := []SignalRegion{{Chr: "chr1", Start: 900, End: 5000, Max: 5, Mean: 5}}
+		if !equalRegions(got, want) {
+			t.Errorf("view %v: expected %v, got %v", view, want, got)
+		}
+		if reads < 2 {
+			t.Errorf("view %v: expected the read to widen, got %d reads", view, reads)
+		}
+	}
+
+	// Short regions are still dropped, and none serialise as an empty list
+	got, err := regionsInView(read, 5900, 6200, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("expected an empty list, got %#v", got)
+	}
+
+	// Signal running on past the extension is cut there, whatever its length
+	widest := 0
+	endless := func(start, end int) ([]BigWigData, error) {
+		widest = max(widest, end)
+		return []BigWigData{{Chr: "chr1", Start: int32(start), End: int32(end), Value: 5}}, nil
+	}
+	got, err = regionsInView(endless, 0, 1000, RegionOptions{Threshold: 1, MinLength: 2 * MAX_REGION_EXTENSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if widest != 1000+MAX_REGION_EXTENSION || len(got) != 1 || got[0].End != int32(widest) {
+		t.Errorf("expected one region cut %d past the view, got %v read to %d", MAX_REGION_EXTENSION, got, widest)
+	}
+}
+
+func TestGetSignalRegions(t *testing.T) {
+	dir := t.TempDir()
+	bigdatatest.WriteBigWig(t, dir, "signal.bw", []bigdatatest.Chrom{{Name: "chr1", Size: 100000}}, []bigdatatest.Record{
+		{Chrom: "chr1", Start: 900, End: 2000, Value: 5},
+		{Chrom: "chr1", Start: 3000, End: 3100, Value: 2},
+	})
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+
+	got, err := GetSignalRegions(server.URL+"/signal.bw", "chr1", 0, 1000, RegionOptions{Threshold: 1, MinLength: 500})
+	if err != nil {
+		t.Fatalf("GetSignalRegions() error = %v", err)
+	}
+	want := []SignalRegion{{Chr: "chr1", Start: 900, End: 2000, Max: 5, Mean: 5}}
+	if !equalRegions(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	summary, err := GetTotalSummary(server.URL + "/signal.bw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.BasesCovered != 1200 || summary.MaxVal != 5 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
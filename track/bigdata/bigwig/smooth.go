@@ -0,0 +1,196 @@
+package bigwig
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Smoothing methods
+const (
+	SmoothMean     = "mean"     // Moving mean over a window
+	SmoothMedian   = "median"   // Moving median over a window
+	SmoothGaussian = "gaussian" // Gaussian kernel with the window as its standard deviation
+)
+
+// SmoothingMethods lists the supported smoothing methods
+var SmoothingMethods = []string{SmoothMean, SmoothMedian, SmoothGaussian}
+
+// MAX_SMOOTHING_WINDOW is the widest smoothing window or bandwidth in bp
+const MAX_SMOOTHING_WINDOW = 100000
+
+// stepsPerWindow is how many grid steps a smoothing window spans at most
+const stepsPerWindow = 10
+
+// SmoothOptions controls smoothing
+type SmoothOptions struct {
+	Method string // Smoothing method, none when empty
+	Window int    // Window width in bp for mean and median, bandwidth in bp for gaussian
+}
+
+// Enabled reports whether the options smooth the signal
+func (o SmoothOptions) Enabled() bool {
+	return o.Method != ""
+}
+
+// Smoothing describes how a signal was smoothed
+type Smoothing struct {
+	Method string `json:"method"`
+	Window int    `json:"window"`
+	Step   int    `json:"step"` // Grid the signal was smoothed on, in bp from the chromosome start
+}
+
+// flank is how far outside a region the signal affects smoothed values
+func (o SmoothOptions) flank(step int) int {
+	if o.Method == SmoothGaussian {
+		return 3*o.Window + step
+	}
+	return o.Window/2 + step
+}
+
+// smoothingStep returns the grid step for a view: a tenth of the window, but
+// no finer than half a pixel, so wide views stay cheap. It depends on the
+// view's width rather than its position, so panning keeps the grid.
+func smoothingStep(opts SmoothOptions, start, end int, preRenderedWidth int) int {
+	step := max(1, opts.Window/stepsPerWindow)
+	if preRenderedWidth > 0 {
+		step = max(step, (end-start)/preRenderedWidth/2)
+	}
+	return step
+}
+
+// GetSmoothedWigData reads a region with flanks wide enough for the
+// smoothing window, so values at the region's edges match those seen when
+// the region is panned, and smooths it on a grid aligned to the chromosome
+func GetSmoothedWigData(url string, chrom string, start, end int, preRenderedWidth int, opts SmoothOptions) ([]BigWigData, Smoothing, error) {
+	step := smoothingStep(opts, start, end, preRenderedWidth)
+	flank := opts.flank(step)
+	fetchStart, fetchEnd := max(0, start-flank), end+flank
+
+	// Widen the prerendered width with the region to keep the zoom level
+	fetchWidth := preRenderedWidth
+	if preRenderedWidth > 0 {
+		fetchWidth = int(math.Ceil(float64(preRenderedWidth) * float64(fetchEnd-fetchStart) / float64(end-start)))
+	}
+	data, err := GetCachedWigData(url, chrom, fetchStart, fetchEnd, fetchWidth)
+	if err != nil {
+		return nil, Smoothing{}, err
+	}
+	smoothed, err := Smooth(data, start, end, step, opts)
+	return smoothed, Smoothing{Method: opts.Method, Window: opts.Window, Step: step}, err
+}
+
+// Smooth averages data onto a grid of step bp aligned to the chromosome
+// start with binValues, smooths the grid and returns its non-zero cells in
+// [start, end). Data must be sorted and cover the flanks the smoothing reads.
+func Smooth(data []BigWigData, start, end int, step int, opts SmoothOptions) ([]BigWigData, error) {
+	if len(data) == 0 {
+		return []BigWigData{}, nil
+	}
+	if step <= 0 || opts.Window <= 0 {
+		return nil, fmt.Errorf("smoothing needs a positive window and step")
+	}
+	flank := opts.flank(step)
+	gridStart := max(0, start-flank) / step * step
+	cells := (end+flank-gridStart)/step + 1
+	bins := make([]bin, cells)
+	for c := range bins {
+		bins[c] = bin{float64(gridStart + c*step), float64(gridStart + (c+1)*step)}
+	}
+	grid := binValues(data, bins)
+
+	var smoothed []float64
+	switch opts.Method {
+	case SmoothMean:
+		smoothed = movingMean(grid, windowCells(opts.Window, step))
+	case SmoothMedian:
+		smoothed = movingMedian(grid, windowCells(opts.Window, step))
+	case SmoothGaussian:
+		smoothed = gaussian(grid, float64(opts.Window)/float64(step))
+	default:
+		return nil, fmt.Errorf("unknown smoothing %q", opts.Method)
+	}
+
+	chrom := data[0].Chr
+	var out []BigWigData
+	for c, v := range smoothed {
+		cellStart, cellEnd := gridStart+c*step, gridStart+(c+1)*step
+		if cellEnd <= start || cellStart >= end || v == 0 {
+			continue
+		}
+		s, e := int32(max(cellStart, start)), int32(min(cellEnd, end))
+		if n := len(out); n > 0 && out[n-1].End == s && out[n-1].Value == float32(v) {
+			out[n-1].End = e
+			continue
+		}
+		out = append(out, BigWigData{Chr: chrom, Start: s, End: e, Value: float32(v)})
+	}
+	return out, nil
+}
+
+// windowCells is the odd number of grid cells closest to a window, so each
+// window is centred on its cell
+func windowCells(window, step int) int {
+	return max(1, int(math.Round(float64(window)/float64(step)))) | 1
+}
+
+// movingMean returns the mean of each cell's centred window, shrinking the
+// window at the grid's ends
+func movingMean(grid []float64, width int) []float64 {
+	prefix := make([]float64, len(grid)+1)
+	for i, v := range grid {
+		prefix[i+1] = prefix[i] + v
+	}
+	half := width / 2
+	out := make([]float64, len(grid))
+	for i := range grid {
+		lo, hi := max(0, i-half), min(len(grid), i+half+1)
+		out[i] = (prefix[hi] - prefix[lo]) / float64(hi-lo)
+	}
+	return out
+}
+
+// movingMedian returns the median of each cell's centred window
+func movingMedian(grid []float64, width int) []float64 {
+	half := width / 2
+	out := make([]float64, len(grid))
+	window := make([]float64, 0, width)
+	for i := range grid {
+		lo, hi := max(0, i-half), min(len(grid), i+half+1)
+		window = append(window[:0], grid[lo:hi]...)
+		slices.Sort(window)
+		n := len(window)
+		if n%2 == 1 {
+			out[i] = window[n/2]
+		} else {
+			out[i] = (window[n/2-1] + window[n/2]) / 2
+		}
+	}
+	return out
+}
+
+// gaussian convolves the grid with a normalised Gaussian kernel of sigma
+// cells, cut at three sigma
+func gaussian(grid []float64, sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	for k := range kernel {
+		x := float64(k - radius)
+		kernel[k] = math.Exp(-x * x / (2 * sigma * sigma))
+	}
+
+	out := make([]float64, len(grid))
+	for i := range grid {
+		total, weight := 0.0, 0.0
+		for k, w := range kernel {
+			j := i + k - radius
+			if j < 0 || j >= len(grid) {
+				continue
+			}
+			total += grid[j] * w
+			weight += w
+		}
+		out[i] = total / weight
+	}
+	return out
+}
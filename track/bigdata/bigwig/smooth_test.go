@@ -0,0 +1,109 @@
+package bigwig
+
+import (
+	"math"
+	"testing"
+)
+
+// valuesAt expands smoothed records to per-base values over [start, end)
+func valuesAt(data []BigWigData, start, end int) []float32 {
+	values := make([]float32, end-start)
+	for _, d := range data {
+		for p := max(int(d.Start), start); p < min(int(d.End), end); p++ {
+			values[p-start] = d.Value
+		}
+	}
+	return values
+}
+
+func TestSmooth(t *testing.T) {
+	// A single spike of 9 over three bases
+	data := []BigWigData{{Chr: "chr1", Start: 100, End: 103, Value: 9}}
+
+	mean, err := Smooth(data, 90, 110, 1, SmoothOptions{Method: SmoothMean, Window: 3})
+	if err != nil {
+		t.Fatalf("Smooth() error = %v", err)
+	}
+	want := []float32{3, 6, 9, 6, 3}
+	if got := valuesAt(mean, 99, 104); !equalValues(got, want) {
+		t.Errorf("mean: expected %v, got %v", want, got)
+	}
+
+	median, err := Smooth(data, 90, 110, 1, SmoothOptions{Method: SmoothMedian, Window: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []float32{0, 9, 9, 9, 0}
+	if got := valuesAt(median, 99, 104); !equalValues(got, want) {
+		t.Errorf("median: expected %v, got %v", want, got)
+	}
+
+	gauss, err := Smooth(data, 80, 120, 1, SmoothOptions{Method: SmoothGaussian, Window: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := float32(0)
+	for _, v := range valuesAt(gauss, 80, 120) {
+		total += v
+	}
+	// The kernel is normalised, so the signal's mass is kept
+	if math.Abs(float64(total)-27) > 0.5 {
+		t.Errorf("gaussian: expected a total near 27, got %g", total)
+	}
+	if got := valuesAt(gauss, 101, 102)[0]; got >= 9 || got < 4 {
+		t.Errorf("gaussian: expected a lowered peak, got %g", got)
+	}
+
+	if _, err := Smooth(data, 90, 110, 1, SmoothOptions{Method: "loess", Window: 3}); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestSmoothStableWhenPanning(t *testing.T) {
+	var data []BigWigData
+	for p := int32(0); p < 2000; p += 10 {
+		data = append(data, BigWigData{Chr: "chr1", Start: p, End: p + 10, Value: float32(p%70) / 7})
+	}
+	opts := SmoothOptions{Method: SmoothGaussian, Window: 25}
+	step := smoothingStep(opts, 500, 1000, 0)
+
+	first, err := Smooth(data, 500, 1000, step, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panned, err := Smooth(data, 733, 1233, step, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := valuesAt(first, 733, 1000), valuesAt(panned, 733, 1000)
+	if !equalValues(a, b) {
+		t.Errorf("expected the overlap to match after panning")
+	}
+}
+
+func TestSmoothingStep(t *testing.T) {
+	opts := SmoothOptions{Method: SmoothMean, Window: 200}
+	if got := smoothingStep(opts, 0, 1000, 0); got != 20 {
+		t.Errorf("expected a tenth of the window, got %d", got)
+	}
+	// 10 kb per pixel makes half a pixel the step
+	if got := smoothingStep(opts, 0, 10000000, 1000); got != 5000 {
+		t.Errorf("expected half a pixel, got %d", got)
+	}
+	// The step depends on the view's width, not its position
+	if smoothingStep(opts, 0, 500000, 1000) != smoothingStep(opts, 123457, 623457, 1000) {
+		t.Error("expected the same step for panned views")
+	}
+}
+
+func equalValues(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-4 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package bigdata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const RANGE_READER_WINDOW = 64 * 1024 // 64KB read-ahead window per range request
+
+// RangeReader is an io.ReadSeekCloser over a remote file that fetches
+// fixed-size windows with HTTP range requests, so block-based formats
+// (bgzip, BAM, .hic) can be read without downloading the whole file.
+type RangeReader struct {
+	URL    string
+	offset int64
+	buf    []byte
+	bufOff int64
+	eof    bool
+}
+
+// NewRangeReader creates a RangeReader positioned at the start of url
+func NewRangeReader(url string) *RangeReader {
+	return &RangeReader{URL: url}
+}
+
+func (r *RangeReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Refill the window if the current offset is outside of it
+	if r.offset < r.bufOff || r.offset >= r.bufOff+int64(len(r.buf)) {
+		if r.eof && r.offset >= r.bufOff+int64(len(r.buf)) {
+			return 0, io.EOF
+		}
+		data, err := requestRange(r.URL, r.offset, RANGE_READER_WINDOW)
+		if err != nil {
+			return 0, err
+		}
+		r.buf = data
+		r.bufOff = r.offset
+		r.eof = len(data) < RANGE_READER_WINDOW
+		if len(data) == 0 {
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, r.buf[r.offset-r.bufOff:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// offset is already absolute
+	case io.SeekCurrent:
+		offset += r.offset
+	default:
+		return 0, errors.New("range reader: unsupported seek whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("range reader: negative offset")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *RangeReader) Close() error {
+	r.buf = nil
+	return nil
+}
+
+// requestRange fetches up to length bytes starting at offset.
+// Unlike RequestBytes, a short read at the end of the file is not an error.
+func requestRange(url string, offset int64, length int) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", offset, offset+int64(length)-1)
+	req.Header.Set("Range", rangeHeader)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return []byte{}, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %s for range request to %s", resp.Status, url)
+	}
+
+	data := make([]byte, length)
+	n, err := io.ReadFull(resp.Body, data)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+
+	return data[:n], nil
+}
+
+// RequestAll fetches an entire remote file, used for small sidecar files like indexes
+func RequestAll(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s for %s", resp.Status, url)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// IsRemote reports whether path should be read over HTTP rather than from disk
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// OpenSource opens a seekable reader over a local file or a remote URL
+func OpenSource(path string) (io.ReadSeekCloser, error) {
+	if IsRemote(path) {
+		return NewRangeReader(path), nil
+	}
+	return os.Open(path)
+}
+
+// ReadSource reads an entire local file or remote URL
+func ReadSource(path string) ([]byte, error) {
+	if IsRemote(path) {
+		return RequestAll(path)
+	}
+	return os.ReadFile(path)
+}
+
+// ReadSourceRange reads exactly length bytes at offset from a local file or
+// remote URL, using the same range requests as big* files for URLs
+func ReadSourceRange(path string, offset int64, length int) ([]byte, error) {
+	if IsRemote(path) {
+		return RequestBytes(path, int(offset), length)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// streamClient reads whole remote files, which can take longer than the
+// timeout of range requests, over the same connections
+var streamClient = &http.Client{Transport: httpClient.Transport}
+
+// OpenStream opens a local file or remote URL for one sequential read from
+// the start, with a single request rather than a range request per window
+func OpenStream(path string) (io.ReadCloser, error) {
+	if !IsRemote(path) {
+		return os.Open(path)
+	}
+	resp, err := streamClient.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s for %s", resp.Status, path)
+	}
+	return resp.Body, nil
+}
+
+// SourceVersion identifies the content of a local file or remote URL, so
+// readers can tell when it was replaced
+type SourceVersion struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string // Remote only
+}
+
+// Equal reports whether two versions describe the same content
+func (v SourceVersion) Equal(o SourceVersion) bool {
+	return v.Size == o.Size && v.ModTime.Equal(o.ModTime) && v.ETag == o.ETag
+}
+
+// StatSource returns the version of a local file, or of a remote URL from
+// the headers of a HEAD request
+func StatSource(path string) (SourceVersion, error) {
+	if !IsRemote(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return SourceVersion{}, err
+		}
+		return SourceVersion{Size: info.Size(), ModTime: info.ModTime()}, nil
+	}
+
+	resp, err := httpClient.Head(path)
+	if err != nil {
+		return SourceVersion{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return SourceVersion{}, fmt.Errorf("unexpected status %s for %s", resp.Status, path)
+	}
+	version := SourceVersion{Size: resp.ContentLength, ETag: resp.Header.Get("ETag")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		version.ModTime = modified
+	}
+	return version, nil
+}
@@ -0,0 +1,85 @@
+package hic
+
+import (
+	"fmt"
+	"gb-api/cache"
+	"gb-api/config"
+	"gb-api/track/bigdata"
+	"log/slog"
+	"strconv"
+)
+
+// cache of opened files (header, master and normalization indexes), keyed by location
+var HiCCache *cache.Cache[*HiC]
+
+// cache of decoded contact blocks, keyed by file, matrix, zoom and block number
+var HiCBlockCache *cache.Cache[[]ContactRecord]
+
+func init() {
+	cacheSize := config.GetCacheSize()
+
+	headerCache, err := cache.NewCache[*HiC](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	HiCCache = headerCache
+
+	blockCache, err := cache.NewCache[[]ContactRecord](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	HiCBlockCache = blockCache
+}
+
+// GetCachedHiC returns an opened file, reusing a cached header and footer
+func GetCachedHiC(path string) (*HiC, error) {
+	if cached, ok := HiCCache.Get(path); ok {
+		return cached, nil
+	}
+	h, err := New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	HiCCache.Add(path, h)
+	return h, nil
+}
+
+// getBlock reads and decodes a contact block, reusing cached blocks
+func (h *HiC) getBlock(m *matrix, zoom *zoomData, number int32) ([]ContactRecord, error) {
+	cacheId := h.Path + "-" + matrixKey(m.Chr1, m.Chr2) + "-" + zoomKey(zoom.Unit, zoom.BinSize) + "-" + strconv.Itoa(int(number))
+	if cached, ok := HiCBlockCache.Get(cacheId); ok {
+		return cached, nil
+	}
+
+	entry := zoom.Blocks[number]
+	compressed, err := bigdata.ReadSourceRange(h.Path, entry.Position, int(entry.Size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block %d: %w", number, err)
+	}
+	records, err := decodeBlock(h.Version, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block %d: %w", number, err)
+	}
+
+	HiCBlockCache.Add(cacheId, records)
+	return records, nil
+}
+
+// GetCachedContacts reads the contacts between two regions, reusing cached headers and blocks
+func GetCachedContacts(path string, chrom1 string, start1, end1 int, chrom2 string, start2, end2 int, opts Options) (HiCData, error) {
+	h, err := GetCachedHiC(path)
+	if err != nil {
+		return HiCData{}, fmt.Errorf("Failed to open .hic file, %w", err)
+	}
+
+	slog.Debug("Hi-C query", "path", path, "chrom1", chrom1, "start1", start1, "end1", end1,
+		"chrom2", chrom2, "start2", start2, "end2", end2, "resolution", opts.Resolution, "normalization", opts.Normalization)
+	data, err := h.Query(chrom1, start1, end1, chrom2, start2, end2, opts)
+	if err != nil {
+		return HiCData{}, fmt.Errorf("Failed to read .hic data, %w", err)
+	}
+	slog.Debug("Returning contacts", "resolution", data.Resolution, "contacts", len(data.Contacts))
+
+	return data, nil
+}
@@ -0,0 +1,272 @@
+package hic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"gb-api/track/bigdata"
+	"math"
+	"slices"
+)
+
+const (
+	BP_UNIT  = "BP"
+	MAX_BINS = 1000 // Most bins along either axis of a query
+)
+
+// Output formats of a query
+const (
+	FormatRecords = "records"
+	FormatMatrix  = "matrix"
+)
+
+// Options selects the zoom level, normalization and shape of a query
+type Options struct {
+	Resolution    int32  // Bin size in bp, 0 picks the finest with at most MAX_BINS bins
+	Normalization string // NONE (default), KR or VC
+	Format        string // records (default) or matrix
+}
+
+// Contact is a non-zero cell: the starts of the two bins and their (normalized) count
+type Contact struct {
+	Start1 int64   `json:"start1"`
+	Start2 int64   `json:"start2"`
+	Value  float32 `json:"value"`
+}
+
+// Matrix is a dense block of counts. Values[i][j] is the cell between bin i
+// of the first region and bin j of the second; missing cells are 0.
+type Matrix struct {
+	Start1 int64       `json:"start1"`
+	Start2 int64       `json:"start2"`
+	Rows   int         `json:"rows"`
+	Cols   int         `json:"cols"`
+	Values [][]float32 `json:"values"`
+}
+
+type HiCData struct {
+	Resolution    int32     `json:"resolution"`
+	Normalization string    `json:"normalization"`
+	Contacts      []Contact `json:"contacts,omitempty"`
+	Matrix        *Matrix   `json:"matrix,omitempty"`
+}
+
+// region is a query range as inclusive bins of one chromosome
+type region struct {
+	chr        Chromosome
+	bin1, bin2 int64
+}
+
+func (r region) contains(bin int64) bool {
+	return bin >= r.bin1 && bin <= r.bin2
+}
+
+// Query returns the contacts between [start1, end1) of chrom1 and
+// [start2, end2) of chrom2, as records or a dense matrix
+func (h *HiC) Query(chrom1 string, start1, end1 int, chrom2 string, start2, end2 int, opts Options) (HiCData, error) {
+	c1, ok := h.chromosome(chrom1)
+	if !ok {
+		return HiCData{}, fmt.Errorf("chromosome %s not found", chrom1)
+	}
+	c2, ok := h.chromosome(chrom2)
+	if !ok {
+		return HiCData{}, fmt.Errorf("chromosome %s not found", chrom2)
+	}
+
+	resolution, err := h.resolution(opts.Resolution, max(end1-start1, end2-start2))
+	if err != nil {
+		return HiCData{}, err
+	}
+	norm := opts.Normalization
+	if norm == "" {
+		norm = NormNone
+	}
+	if norm != NormNone && !slices.Contains(h.Normalizations, norm) {
+		return HiCData{}, fmt.Errorf("normalization %s not available, expected one of %v", norm, append([]string{NormNone}, h.Normalizations...))
+	}
+
+	res := int64(resolution)
+	r1 := region{chr: c1, bin1: int64(start1) / res, bin2: int64(end1-1) / res}
+	r2 := region{chr: c2, bin1: int64(start2) / res, bin2: int64(end2-1) / res}
+	if r1.bin2-r1.bin1 >= MAX_BINS || r2.bin2-r2.bin1 >= MAX_BINS {
+		return HiCData{}, fmt.Errorf("region spans more than %d bins at resolution %d", MAX_BINS, resolution)
+	}
+
+	data := HiCData{Resolution: resolution, Normalization: norm}
+	cells, err := h.cells(r1, r2, resolution, norm, opts.Format == FormatMatrix)
+	if err != nil {
+		return HiCData{}, err
+	}
+
+	if opts.Format == FormatMatrix {
+		m := &Matrix{
+			Start1: r1.bin1 * res,
+			Start2: r2.bin1 * res,
+			Rows:   int(r1.bin2 - r1.bin1 + 1),
+			Cols:   int(r2.bin2 - r2.bin1 + 1),
+		}
+		m.Values = make([][]float32, m.Rows)
+		for i := range m.Values {
+			m.Values[i] = make([]float32, m.Cols)
+		}
+		for _, c := range cells {
+			m.Values[c.bin1-r1.bin1][c.bin2-r2.bin1] = c.value
+		}
+		data.Matrix = m
+		return data, nil
+	}
+
+	data.Contacts = make([]Contact, len(cells))
+	for i, c := range cells {
+		data.Contacts[i] = Contact{Start1: c.bin1 * res, Start2: c.bin2 * res, Value: c.value}
+	}
+	return data, nil
+}
+
+// resolution validates a requested bin size or picks the finest one that
+// covers width in at most MAX_BINS bins
+func (h *HiC) resolution(requested int32, width int) (int32, error) {
+	if requested > 0 {
+		if !h.HasResolution(requested) {
+			return 0, fmt.Errorf("resolution %d not available, expected one of %v", requested, h.Resolutions)
+		}
+		return requested, nil
+	}
+	if len(h.Resolutions) == 0 {
+		return 0, fmt.Errorf("file has no base-pair resolutions")
+	}
+	best := slices.Max(h.Resolutions)
+	for _, r := range h.Resolutions {
+		// Allow a bin either side for a region that is not bin-aligned
+		if r < best && width/int(r)+2 <= MAX_BINS {
+			best = r
+		}
+	}
+	return best, nil
+}
+
+// cell is a contact in query orientation: bin1 on the first region, bin2 on the second
+type cell struct {
+	bin1, bin2 int64
+	value      float32
+}
+
+// cells reads the contacts between two regions. Intrachromosomal matrices
+// only store one triangle, so a stored record may fall in the query in either
+// orientation. With mirror set both are returned, otherwise only the first.
+func (h *HiC) cells(r1, r2 region, resolution int32, norm string, mirror bool) ([]cell, error) {
+	// Matrices are stored with the lower chromosome index first
+	swapped := r1.chr.Index > r2.chr.Index
+	x, y := r1, r2
+	if swapped {
+		x, y = r2, r1
+	}
+	intra := x.chr.Index == y.chr.Index
+
+	m, err := h.getMatrix(x.chr.Index, y.chr.Index)
+	if err != nil || m == nil {
+		return []cell{}, err
+	}
+	zoom, ok := m.Zooms[zoomKey(BP_UNIT, resolution)]
+	if !ok {
+		return []cell{}, nil
+	}
+
+	var normX, normY *normVector
+	if norm != NormNone {
+		if normX, err = h.readNormVector(norm, x.chr.Index, resolution, x, y, intra); err != nil {
+			return nil, err
+		}
+		normY = normX
+		if !intra {
+			if normY, err = h.readNormVector(norm, y.chr.Index, resolution, y, y, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cells := []cell{}
+	add := func(binX, binY int64, counts float32) {
+		value := counts
+		if norm != NormNone {
+			value = float32(float64(counts) / (normX.at(binX) * normY.at(binY)))
+			if value == 0 || math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+				return
+			}
+		}
+		if swapped {
+			cells = append(cells, cell{bin1: binY, bin2: binX, value: value})
+		} else {
+			cells = append(cells, cell{bin1: binX, bin2: binY, value: value})
+		}
+	}
+
+	for _, number := range zoom.blockNumbers(h.Version, intra, x.bin1, x.bin2, y.bin1, y.bin2) {
+		records, err := h.getBlock(m, zoom, number)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			bx, by := int64(rec.BinX), int64(rec.BinY)
+			direct := x.contains(bx) && y.contains(by)
+			if direct {
+				add(bx, by, rec.Counts)
+			}
+			if intra && bx != by && x.contains(by) && y.contains(bx) && (mirror || !direct) {
+				add(by, bx, rec.Counts)
+			}
+		}
+	}
+	return cells, nil
+}
+
+// normVector holds the normalization factors for bins [offset, offset+len(values))
+type normVector struct {
+	offset int64
+	values []float64
+}
+
+func (n *normVector) at(bin int64) float64 {
+	i := bin - n.offset
+	if i < 0 || i >= int64(len(n.values)) {
+		return math.NaN()
+	}
+	return n.values[i]
+}
+
+// readNormVector reads the factors of a chromosome covering both regions,
+// fetching only the needed range of the stored vector
+func (h *HiC) readNormVector(norm string, chrIndex int, resolution int32, a, b region, both bool) (*normVector, error) {
+	entry, ok := h.normIndex[normKey{Type: norm, ChrIndex: chrIndex, Unit: BP_UNIT, BinSize: resolution}]
+	if !ok {
+		return nil, fmt.Errorf("no %s normalization for %s at resolution %d", norm, h.Chromosomes[chrIndex].Name, resolution)
+	}
+
+	first, last := a.bin1, a.bin2
+	if both {
+		first, last = min(a.bin1, b.bin1), max(a.bin2, b.bin2)
+	}
+	countSize := int64(4)
+	if h.Version > 8 {
+		countSize = 8
+	}
+	valueSize := h.valueSize()
+	count := (entry.Size - countSize) / valueSize
+	last = min(last, count-1)
+	if first > last {
+		return &normVector{offset: first}, nil
+	}
+
+	data, err := bigdata.ReadSourceRange(h.Path, entry.Position+countSize+first*valueSize, int((last-first+1)*valueSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s normalization vector: %w", norm, err)
+	}
+	values := make([]float64, last-first+1)
+	for i := range values {
+		if valueSize == 4 {
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		} else {
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+	}
+	return &normVector{offset: first, values: values}, nil
+}
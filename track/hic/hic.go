@@ -0,0 +1,372 @@
+// Package hic reads contact matrices from Juicer .hic files (versions 7 to 9)
+package hic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"gb-api/track/bigdata"
+	"gb-api/utils"
+	"io"
+	"strings"
+	"sync"
+)
+
+const HIC_MAGIC = "HIC"
+
+// Normalizations supported by the reader. Files list which ones they contain.
+const (
+	NormNone = "NONE"
+	NormKR   = "KR"
+	NormVC   = "VC"
+)
+
+// HiC is an opened .hic file: its header, master index and normalization index
+type HiC struct {
+	Path            string            `json:"path"`
+	Version         int32             `json:"version"`
+	Genome          string            `json:"genome"`
+	Attributes      map[string]string `json:"-"`
+	Chromosomes     []Chromosome      `json:"chromosomes"`
+	Resolutions     []int32           `json:"resolutions"` // Base-pair bin sizes, finest last as stored
+	Normalizations  []string          `json:"normalizations"`
+	masterIndex     map[string]indexEntry
+	normIndex       map[normKey]indexEntry
+	chromosomeIndex map[string]int
+
+	mu       sync.Mutex
+	matrices map[string]*matrix // Matrix headers read so far, keyed by "chr1Idx_chr2Idx"
+}
+
+type Chromosome struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Length int64  `json:"length"`
+}
+
+type indexEntry struct {
+	Position int64
+	Size     int64
+}
+
+type normKey struct {
+	Type     string
+	ChrIndex int
+	Unit     string
+	BinSize  int32
+}
+
+// New reads the header and footer of a local or remote .hic file
+func New(path string) (*HiC, error) {
+	src, err := bigdata.OpenSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	r := newBufferedSource(src)
+	p := utils.NewParser(r, binary.LittleEndian)
+
+	h := &HiC{Path: path, Attributes: map[string]string{}, matrices: map[string]*matrix{}}
+	footerPosition, normIndexPosition, err := h.readHeader(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .hic header from %s: %w", path, err)
+	}
+	if err := h.readFooter(p, r, footerPosition, normIndexPosition); err != nil {
+		return nil, fmt.Errorf("failed to read .hic footer from %s: %w", path, err)
+	}
+	return h, nil
+}
+
+func (h *HiC) readHeader(p *utils.Parser) (int64, int64, error) {
+	magic, err := p.GetString(4)
+	if err != nil {
+		return 0, 0, err
+	}
+	if magic != HIC_MAGIC {
+		return 0, 0, fmt.Errorf("not a .hic file")
+	}
+	if h.Version, err = p.GetInt32(); err != nil {
+		return 0, 0, err
+	}
+	if h.Version < 7 || h.Version > 9 {
+		return 0, 0, fmt.Errorf("unsupported .hic version %d", h.Version)
+	}
+	footerPosition, err := getInt64(p)
+	if err != nil {
+		return 0, 0, err
+	}
+	if h.Genome, err = p.GetString(0); err != nil {
+		return 0, 0, err
+	}
+
+	var normIndexPosition int64
+	if h.Version > 8 {
+		if normIndexPosition, err = getInt64(p); err != nil {
+			return 0, 0, err
+		}
+		if _, err = getInt64(p); err != nil { // Normalization index length
+			return 0, 0, err
+		}
+	}
+
+	nAttributes, err := p.GetInt32()
+	if err != nil {
+		return 0, 0, err
+	}
+	for range nAttributes {
+		key, err := p.GetString(0)
+		if err != nil {
+			return 0, 0, err
+		}
+		value, err := p.GetString(0)
+		if err != nil {
+			return 0, 0, err
+		}
+		h.Attributes[key] = value
+	}
+
+	nChromosomes, err := p.GetInt32()
+	if err != nil {
+		return 0, 0, err
+	}
+	h.chromosomeIndex = make(map[string]int, nChromosomes)
+	for i := range int(nChromosomes) {
+		name, err := p.GetString(0)
+		if err != nil {
+			return 0, 0, err
+		}
+		var length int64
+		if h.Version > 8 {
+			length, err = getInt64(p)
+		} else {
+			var l int32
+			l, err = p.GetInt32()
+			length = int64(l)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		h.Chromosomes = append(h.Chromosomes, Chromosome{Index: i, Name: name, Length: length})
+		h.chromosomeIndex[name] = i
+	}
+
+	nResolutions, err := p.GetInt32()
+	if err != nil {
+		return 0, 0, err
+	}
+	h.Resolutions = make([]int32, nResolutions)
+	for i := range h.Resolutions {
+		if h.Resolutions[i], err = p.GetInt32(); err != nil {
+			return 0, 0, err
+		}
+	}
+	return footerPosition, normIndexPosition, nil
+}
+
+// readFooter reads the master index of matrices and the normalization vector
+// index, skipping over the expected-value vectors between them
+func (h *HiC) readFooter(p *utils.Parser, r io.Seeker, footerPosition, normIndexPosition int64) error {
+	if _, err := r.Seek(footerPosition, io.SeekStart); err != nil {
+		return err
+	}
+	// Byte count of the footer
+	if h.Version > 8 {
+		if _, err := getInt64(p); err != nil {
+			return err
+		}
+	} else if _, err := p.GetInt32(); err != nil {
+		return err
+	}
+
+	nEntries, err := p.GetInt32()
+	if err != nil {
+		return err
+	}
+	h.masterIndex = make(map[string]indexEntry, nEntries)
+	for range nEntries {
+		key, err := p.GetString(0)
+		if err != nil {
+			return err
+		}
+		position, err := getInt64(p)
+		if err != nil {
+			return err
+		}
+		size, err := p.GetInt32()
+		if err != nil {
+			return err
+		}
+		h.masterIndex[key] = indexEntry{Position: position, Size: int64(size)}
+	}
+
+	if h.Version > 8 && normIndexPosition > 0 {
+		if _, err := r.Seek(normIndexPosition, io.SeekStart); err != nil {
+			return err
+		}
+	} else {
+		if err := h.skipExpectedValues(p, r, false); err != nil {
+			return err
+		}
+		if err := h.skipExpectedValues(p, r, true); err != nil {
+			return err
+		}
+	}
+	return h.readNormIndex(p)
+}
+
+// skipExpectedValues moves past a block of (normalized) expected-value vectors
+func (h *HiC) skipExpectedValues(p *utils.Parser, r io.Seeker, normalized bool) error {
+	n, err := p.GetInt32()
+	if err != nil {
+		return err
+	}
+	valueSize := h.valueSize()
+	for range n {
+		if normalized {
+			if _, err := p.GetString(0); err != nil { // Normalization type
+				return err
+			}
+		}
+		if _, err := p.GetString(0); err != nil { // Unit
+			return err
+		}
+		if _, err := p.GetInt32(); err != nil { // Bin size
+			return err
+		}
+		nValues, err := h.getCount(p)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Seek(nValues*valueSize, io.SeekCurrent); err != nil {
+			return err
+		}
+		nFactors, err := p.GetInt32()
+		if err != nil {
+			return err
+		}
+		if _, err := r.Seek(int64(nFactors)*(4+valueSize), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HiC) readNormIndex(p *utils.Parser) error {
+	h.normIndex = map[normKey]indexEntry{}
+	n, err := p.GetInt32()
+	if err == io.EOF {
+		// Files without normalization end after the expected values
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for range n {
+		var key normKey
+		if key.Type, err = p.GetString(0); err != nil {
+			return err
+		}
+		chrIndex, err := p.GetInt32()
+		if err != nil {
+			return err
+		}
+		key.ChrIndex = int(chrIndex)
+		if key.Unit, err = p.GetString(0); err != nil {
+			return err
+		}
+		if key.BinSize, err = p.GetInt32(); err != nil {
+			return err
+		}
+		position, err := getInt64(p)
+		if err != nil {
+			return err
+		}
+		size, err := h.getCount(p)
+		if err != nil {
+			return err
+		}
+		h.normIndex[key] = indexEntry{Position: position, Size: size}
+		if !seen[key.Type] {
+			seen[key.Type] = true
+			h.Normalizations = append(h.Normalizations, key.Type)
+		}
+	}
+	return nil
+}
+
+// valueSize is the width of expected and normalization values: doubles before version 9
+func (h *HiC) valueSize() int64 {
+	if h.Version > 8 {
+		return 4
+	}
+	return 8
+}
+
+// getCount reads a vector length, which became 64-bit in version 9
+func (h *HiC) getCount(p *utils.Parser) (int64, error) {
+	if h.Version > 8 {
+		return getInt64(p)
+	}
+	n, err := p.GetInt32()
+	return int64(n), err
+}
+
+func getInt64(p *utils.Parser) (int64, error) {
+	v, err := p.GetUInt64()
+	return int64(v), err
+}
+
+// chromosome finds chrom in the header, with or without a "chr" prefix
+func (h *HiC) chromosome(chrom string) (Chromosome, bool) {
+	if i, ok := h.chromosomeIndex[chrom]; ok {
+		return h.Chromosomes[i], true
+	}
+	alias := "chr" + chrom
+	if trimmed, ok := strings.CutPrefix(chrom, "chr"); ok {
+		alias = trimmed
+	}
+	if i, ok := h.chromosomeIndex[alias]; ok {
+		return h.Chromosomes[i], true
+	}
+	return Chromosome{}, false
+}
+
+// HasResolution reports whether the file has a base-pair zoom at binSize
+func (h *HiC) HasResolution(binSize int32) bool {
+	for _, r := range h.Resolutions {
+		if r == binSize {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedSource buffers small sequential reads while still allowing seeks
+type bufferedSource struct {
+	src io.ReadSeeker
+	buf *bufio.Reader
+}
+
+func newBufferedSource(src io.ReadSeeker) *bufferedSource {
+	return &bufferedSource{src: src, buf: bufio.NewReader(src)}
+}
+
+func (b *bufferedSource) Read(p []byte) (int, error) {
+	return b.buf.Read(p)
+}
+
+func (b *bufferedSource) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent {
+		// The underlying source is ahead of the reader by the buffered bytes
+		offset -= int64(b.buf.Buffered())
+	}
+	pos, err := b.src.Seek(offset, whence)
+	if err != nil {
+		return 0, err
+	}
+	b.buf.Reset(b.src)
+	return pos, nil
+}
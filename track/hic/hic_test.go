@@ -0,0 +1,210 @@
+package hic
+
+import (
+	"gb-api/track/hic/hictest"
+	"math"
+	"strings"
+	"testing"
+)
+
+var chromosomes = []hictest.Chromosome{{Name: "All", Length: 3000}, {Name: "chr1", Length: 2000}, {Name: "chr2", Length: 1000}}
+
+// testFile has chr1 contacts at 100bp and 500bp, and chr1-chr2 contacts at 100bp
+func testFile(version int32, dense bool) hictest.File {
+	return hictest.File{
+		Version:          version,
+		Genome:           "hg38",
+		Chromosomes:      chromosomes,
+		Resolutions:      []int32{500, 100},
+		BlockBinCount:    5,
+		BlockColumnCount: 4,
+		Dense:            dense,
+		FloatCounts:      dense,
+		Matrices: []hictest.Matrix{
+			{Chr1: 1, Chr2: 1, Resolution: 100, Records: []hictest.Record{
+				{BinX: 0, BinY: 0, Counts: 10},
+				{BinX: 1, BinY: 3, Counts: 4},
+				{BinX: 2, BinY: 12, Counts: 2},
+				{BinX: 8, BinY: 9, Counts: 6},
+				{BinX: 15, BinY: 19, Counts: 1},
+			}},
+			{Chr1: 1, Chr2: 2, Resolution: 100, Records: []hictest.Record{
+				{BinX: 3, BinY: 1, Counts: 5},
+				{BinX: 12, BinY: 7, Counts: 3},
+			}},
+		},
+		Norms: []hictest.Norm{
+			{Type: NormKR, Chr: 1, Resolution: 100, Values: []float64{1, 2, 0.5, 2, 1, 1, 1, 1, 1, 1, 1, 1, 4, 1, 1, 1, 1, 1, 1, 1}},
+			{Type: NormKR, Chr: 2, Resolution: 100, Values: []float64{0.5, 2, 1, 1, 1, 1, 1, 0.5, 1, 1}},
+		},
+	}
+}
+
+func openTestFile(t *testing.T, version int32, dense bool) *HiC {
+	t.Helper()
+	path := hictest.WriteFile(t, t.TempDir(), "test.hic", testFile(version, dense))
+	h, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return h
+}
+
+func contactSet(contacts []Contact) map[[2]int64]float32 {
+	set := map[[2]int64]float32{}
+	for _, c := range contacts {
+		set[[2]int64{c.Start1, c.Start2}] = c.Value
+	}
+	return set
+}
+
+func TestHeader(t *testing.T) {
+	for _, version := range []int32{8, 9} {
+		h := openTestFile(t, version, false)
+		if h.Version != version || h.Genome != "hg38" {
+			t.Errorf("v%d: unexpected header %d %s", version, h.Version, h.Genome)
+		}
+		if len(h.Chromosomes) != 3 || h.Chromosomes[1].Name != "chr1" || h.Chromosomes[1].Length != 2000 {
+			t.Errorf("v%d: unexpected chromosomes %+v", version, h.Chromosomes)
+		}
+		if !h.HasResolution(100) || h.HasResolution(1000) {
+			t.Errorf("v%d: unexpected resolutions %v", version, h.Resolutions)
+		}
+		if len(h.Normalizations) != 1 || h.Normalizations[0] != NormKR {
+			t.Errorf("v%d: unexpected normalizations %v", version, h.Normalizations)
+		}
+		if _, ok := h.chromosome("1"); !ok {
+			t.Errorf("v%d: expected 1 to match chr1", version)
+		}
+	}
+}
+
+func TestQueryRecords(t *testing.T) {
+	for _, version := range []int32{8, 9} {
+		for _, dense := range []bool{false, true} {
+			h := openTestFile(t, version, dense)
+
+			// Upper triangle of chr1:0-1000, each stored record once
+			data, err := h.Query("chr1", 0, 1000, "chr1", 0, 1000, Options{Resolution: 100})
+			if err != nil {
+				t.Fatalf("v%d: Query() error = %v", version, err)
+			}
+			want := map[[2]int64]float32{{0, 0}: 10, {100, 300}: 4, {800, 900}: 6}
+			if got := contactSet(data.Contacts); len(got) != len(want) || len(data.Contacts) != len(want) {
+				t.Errorf("v%d dense=%v: expected %v, got %+v", version, dense, want, data.Contacts)
+			} else {
+				for k, v := range want {
+					if got[k] != v {
+						t.Errorf("v%d dense=%v: expected %v at %v, got %v", version, dense, v, k, got[k])
+					}
+				}
+			}
+
+			// The far record is found below the diagonal
+			data, err = h.Query("chr1", 1000, 1500, "chr1", 0, 500, Options{Resolution: 100})
+			if err != nil {
+				t.Fatalf("v%d: Query() error = %v", version, err)
+			}
+			if len(data.Contacts) != 1 || data.Contacts[0] != (Contact{Start1: 1200, Start2: 200, Value: 2}) {
+				t.Errorf("v%d dense=%v: expected the transposed record, got %+v", version, dense, data.Contacts)
+			}
+		}
+	}
+}
+
+func TestQueryInterchromosomal(t *testing.T) {
+	for _, version := range []int32{8, 9} {
+		h := openTestFile(t, version, false)
+
+		data, err := h.Query("chr1", 0, 2000, "chr2", 0, 1000, Options{Resolution: 100})
+		if err != nil {
+			t.Fatalf("v%d: Query() error = %v", version, err)
+		}
+		want := map[[2]int64]float32{{300, 100}: 5, {1200, 700}: 3}
+		if got := contactSet(data.Contacts); len(got) != 2 || got[[2]int64{300, 100}] != 5 || got[[2]int64{1200, 700}] != 3 {
+			t.Errorf("v%d: expected %v, got %+v", version, want, data.Contacts)
+		}
+
+		// Chromosomes in the other order return the same contacts transposed
+		data, err = h.Query("chr2", 0, 500, "chr1", 0, 500, Options{Resolution: 100})
+		if err != nil {
+			t.Fatalf("v%d: Query() error = %v", version, err)
+		}
+		if len(data.Contacts) != 1 || data.Contacts[0] != (Contact{Start1: 100, Start2: 300, Value: 5}) {
+			t.Errorf("v%d: expected the transposed contact, got %+v", version, data.Contacts)
+		}
+	}
+}
+
+func TestQueryMatrix(t *testing.T) {
+	h := openTestFile(t, 9, false)
+
+	data, err := h.Query("chr1", 50, 450, "chr1", 0, 400, Options{Resolution: 100, Format: FormatMatrix})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	m := data.Matrix
+	if m == nil || m.Start1 != 0 || m.Start2 != 0 || m.Rows != 5 || m.Cols != 4 {
+		t.Fatalf("unexpected matrix %+v", m)
+	}
+	if m.Values[0][0] != 10 || m.Values[1][3] != 4 || m.Values[3][1] != 4 {
+		t.Errorf("expected both orientations of the intrachromosomal contacts, got %v", m.Values)
+	}
+	if m.Values[2][2] != 0 {
+		t.Errorf("expected missing cells to be 0, got %v", m.Values[2][2])
+	}
+}
+
+func TestQueryNormalization(t *testing.T) {
+	for _, version := range []int32{8, 9} {
+		h := openTestFile(t, version, false)
+
+		data, err := h.Query("chr1", 0, 500, "chr1", 0, 500, Options{Resolution: 100, Normalization: NormKR})
+		if err != nil {
+			t.Fatalf("v%d: Query() error = %v", version, err)
+		}
+		got := contactSet(data.Contacts)
+		// 10 / (1*1) and 4 / (2*2)
+		if len(got) != 2 || got[[2]int64{0, 0}] != 10 || got[[2]int64{100, 300}] != 1 {
+			t.Errorf("v%d: unexpected normalized contacts %+v", version, data.Contacts)
+		}
+
+		data, err = h.Query("chr1", 1200, 1300, "chr2", 700, 800, Options{Resolution: 100, Normalization: NormKR})
+		if err != nil {
+			t.Fatalf("v%d: Query() error = %v", version, err)
+		}
+		// 3 / (4*0.5)
+		if len(data.Contacts) != 1 || math.Abs(float64(data.Contacts[0].Value)-1.5) > 1e-6 {
+			t.Errorf("v%d: unexpected normalized contacts %+v", version, data.Contacts)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	h := openTestFile(t, 9, false)
+
+	if _, err := h.Query("chr1", 0, 1000, "chr1", 0, 1000, Options{Resolution: 250}); err == nil || !strings.Contains(err.Error(), "[500 100]") {
+		t.Errorf("expected unknown resolution error listing the available ones, got %v", err)
+	}
+	if _, err := h.Query("chr1", 0, 1000, "chr1", 0, 1000, Options{Resolution: 100, Normalization: NormVC}); err == nil || !strings.Contains(err.Error(), "[NONE KR]") {
+		t.Errorf("expected unknown normalization error listing the available ones, got %v", err)
+	}
+	if _, err := h.Query("chrX", 0, 1000, "chr1", 0, 1000, Options{}); err == nil {
+		t.Errorf("expected an error for a missing chromosome")
+	}
+}
+
+func TestAutoResolution(t *testing.T) {
+	h := openTestFile(t, 9, false)
+
+	data, err := h.Query("chr1", 0, 2000, "chr1", 0, 2000, Options{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if data.Resolution != 100 {
+		t.Errorf("expected the finest resolution, got %d", data.Resolution)
+	}
+	if r, _ := h.resolution(0, 1_000_000); r != 500 {
+		t.Errorf("expected the coarsest resolution for a wide region, got %d", r)
+	}
+}
@@ -0,0 +1,320 @@
+// Package hictest writes small .hic files (versions 8 and 9) for tests.
+package hictest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// Chromosome is an entry of the header. Index 0 is conventionally "All".
+type Chromosome struct {
+	Name   string
+	Length int64
+}
+
+// Record is a contact count between two bins
+type Record struct {
+	BinX, BinY int32
+	Counts     float32
+}
+
+// Matrix is the contacts of a chromosome pair at one resolution.
+// Chr1 must not be greater than Chr2.
+type Matrix struct {
+	Chr1, Chr2 int
+	Resolution int32
+	Records    []Record
+}
+
+// Norm is a normalization vector of one chromosome at one resolution
+type Norm struct {
+	Type       string
+	Chr        int
+	Resolution int32
+	Values     []float64
+}
+
+// File describes the content of a .hic file
+type File struct {
+	Version          int32 // 8 or 9
+	Genome           string
+	Chromosomes      []Chromosome
+	Resolutions      []int32
+	BlockBinCount    int32
+	BlockColumnCount int32
+	Matrices         []Matrix
+	Norms            []Norm
+	Dense            bool // Write blocks in the dense representation
+	FloatCounts      bool // Write counts as float32 rather than int16
+}
+
+type writer struct {
+	bytes.Buffer
+	version int32
+}
+
+func (w *writer) put(v any) {
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+func (w *writer) str(s string) {
+	w.WriteString(s)
+	w.WriteByte(0)
+}
+
+// count writes a vector length, 64-bit from version 9
+func (w *writer) count(n int) {
+	if w.version > 8 {
+		w.put(int64(n))
+	} else {
+		w.put(int32(n))
+	}
+}
+
+type blockEntry struct {
+	number   int32
+	position int64
+	size     int32
+}
+
+type masterEntry struct {
+	key      string
+	position int64
+	size     int32
+}
+
+type normEntry struct {
+	norm     Norm
+	position int64
+	size     int64
+}
+
+// WriteFile writes f to dir/name and returns its path
+func WriteFile(t testing.TB, dir, name string, f File) string {
+	t.Helper()
+
+	w := &writer{version: f.Version}
+	w.str("HIC")
+	w.put(f.Version)
+	footerOffset := w.Len()
+	w.put(int64(0)) // Footer position, patched below
+	w.str(f.Genome)
+	normIndexOffset := w.Len()
+	if f.Version > 8 {
+		w.put(int64(0)) // Normalization index position and length, patched below
+		w.put(int64(0))
+	}
+	w.put(int32(0)) // Attributes
+	w.put(int32(len(f.Chromosomes)))
+	for _, c := range f.Chromosomes {
+		w.str(c.Name)
+		if f.Version > 8 {
+			w.put(c.Length)
+		} else {
+			w.put(int32(c.Length))
+		}
+	}
+	w.put(int32(len(f.Resolutions)))
+	for _, r := range f.Resolutions {
+		w.put(r)
+	}
+	w.put(int32(0)) // Fragment resolutions
+
+	// Each matrix is written as its blocks followed by its header
+	var master []masterEntry
+	for _, m := range f.Matrices {
+		byBlock := map[int32][]Record{}
+		for _, rec := range m.Records {
+			n := f.blockNumber(m.Chr1 == m.Chr2, rec)
+			byBlock[n] = append(byBlock[n], rec)
+		}
+		numbers := make([]int32, 0, len(byBlock))
+		for n := range byBlock {
+			numbers = append(numbers, n)
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+		var blocks []blockEntry
+		for _, n := range numbers {
+			data := f.block(t, byBlock[n])
+			blocks = append(blocks, blockEntry{number: n, position: int64(w.Len()), size: int32(len(data))})
+			w.Write(data)
+		}
+
+		position := int64(w.Len())
+		w.put(int32(m.Chr1))
+		w.put(int32(m.Chr2))
+		w.put(int32(1)) // Resolutions
+		w.str("BP")
+		w.put(int32(0))                                    // Old zoom index
+		w.put([]float32{0, float32(len(m.Records)), 0, 0}) // Sum, occupied cells, std dev, 95th percentile
+		w.put([]int32{m.Resolution, f.BlockBinCount, f.BlockColumnCount, int32(len(blocks))})
+		for _, b := range blocks {
+			w.put(b.number)
+			w.put(b.position)
+			w.put(b.size)
+		}
+		master = append(master, masterEntry{
+			key:      strconv.Itoa(m.Chr1) + "_" + strconv.Itoa(m.Chr2),
+			position: position,
+			size:     int32(int64(w.Len()) - position),
+		})
+	}
+
+	var norms []normEntry
+	for _, n := range f.Norms {
+		position := int64(w.Len())
+		w.count(len(n.Values))
+		for _, v := range n.Values {
+			if f.Version > 8 {
+				w.put(float32(v))
+			} else {
+				w.put(v)
+			}
+		}
+		norms = append(norms, normEntry{norm: n, position: position, size: int64(w.Len()) - position})
+	}
+
+	footer := int64(w.Len())
+	w.count(0) // Byte count of the footer, unused by readers
+	w.put(int32(len(master)))
+	for _, e := range master {
+		w.str(e.key)
+		w.put(e.position)
+		w.put(e.size)
+	}
+	w.put(int32(0)) // Expected values
+	w.put(int32(0)) // Normalized expected values
+	normIndex := int64(w.Len())
+	w.put(int32(len(norms)))
+	for _, e := range norms {
+		w.str(e.norm.Type)
+		w.put(int32(e.norm.Chr))
+		w.str("BP")
+		w.put(e.norm.Resolution)
+		w.put(e.position)
+		if f.Version > 8 {
+			w.put(e.size)
+		} else {
+			w.put(int32(e.size))
+		}
+	}
+
+	out := w.Bytes()
+	binary.LittleEndian.PutUint64(out[footerOffset:], uint64(footer))
+	if f.Version > 8 {
+		binary.LittleEndian.PutUint64(out[normIndexOffset:], uint64(normIndex))
+		binary.LittleEndian.PutUint64(out[normIndexOffset+8:], uint64(len(out)-int(normIndex)))
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+// blockNumber places a record in the grid of blocks, or along the diagonal
+// for intrachromosomal matrices from version 9
+func (f File) blockNumber(intra bool, rec Record) int32 {
+	if f.Version > 8 && intra {
+		pad := (rec.BinX + rec.BinY) / 2 / f.BlockBinCount
+		depth := int32(math.Log2(1 + math.Abs(float64(rec.BinX-rec.BinY))/math.Sqrt2/float64(f.BlockBinCount)))
+		return depth*f.BlockColumnCount + pad
+	}
+	return (rec.BinY/f.BlockBinCount)*f.BlockColumnCount + rec.BinX/f.BlockBinCount
+}
+
+// block writes records as a compressed list of rows or dense block
+func (f File) block(t testing.TB, records []Record) []byte {
+	t.Helper()
+
+	offsetX, offsetY := records[0].BinX, records[0].BinY
+	maxX, maxY := offsetX, offsetY
+	for _, rec := range records {
+		offsetX, offsetY = min(offsetX, rec.BinX), min(offsetY, rec.BinY)
+		maxX, maxY = max(maxX, rec.BinX), max(maxY, rec.BinY)
+	}
+
+	w := &writer{version: f.Version}
+	w.put(int32(len(records)))
+	w.put(offsetX)
+	w.put(offsetY)
+	flag := func(short bool) {
+		if short {
+			w.WriteByte(0)
+		} else {
+			w.WriteByte(1)
+		}
+	}
+	counts := func(v float32) {
+		if f.FloatCounts {
+			w.put(v)
+		} else {
+			w.put(int16(v))
+		}
+	}
+	flag(!f.FloatCounts)
+	if f.Version > 8 {
+		flag(true) // int16 bin offsets
+		flag(true)
+	}
+
+	if f.Dense {
+		w.WriteByte(2)
+		width := maxX - offsetX + 1
+		cells := make([]float32, width*(maxY-offsetY+1))
+		for i := range cells {
+			cells[i] = float32(math.NaN())
+		}
+		for _, rec := range records {
+			cells[(rec.BinY-offsetY)*width+rec.BinX-offsetX] = rec.Counts
+		}
+		w.put(int32(len(cells)))
+		w.put(int16(width))
+		for _, v := range cells {
+			if math.IsNaN(float64(v)) && !f.FloatCounts {
+				w.put(int16(math.MinInt16))
+			} else {
+				counts(v)
+			}
+		}
+	} else {
+		w.WriteByte(1)
+		rows := map[int32][]Record{}
+		var ys []int32
+		for _, rec := range records {
+			if rows[rec.BinY] == nil {
+				ys = append(ys, rec.BinY)
+			}
+			rows[rec.BinY] = append(rows[rec.BinY], rec)
+		}
+		sort.Slice(ys, func(i, j int) bool { return ys[i] < ys[j] })
+		w.put(int16(len(ys)))
+		for _, y := range ys {
+			w.put(int16(y - offsetY))
+			w.put(int16(len(rows[y])))
+			for _, rec := range rows[y] {
+				w.put(int16(rec.BinX - offsetX))
+				counts(rec.Counts)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+	if _, err := zw.Write(w.Bytes()); err != nil {
+		t.Fatalf("compress block: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("compress block: %v", err)
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,286 @@
+package hic
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"gb-api/track/bigdata"
+	"gb-api/utils"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ContactRecord is a stored contact count between two bins of a matrix
+type ContactRecord struct {
+	BinX   int32
+	BinY   int32
+	Counts float32
+}
+
+// matrix is the set of zoom levels stored for a chromosome pair
+type matrix struct {
+	Chr1, Chr2 int
+	Zooms      map[string]*zoomData // Keyed by unit + bin size, e.g. "BP_5000"
+}
+
+// zoomData locates the contact blocks of one resolution
+type zoomData struct {
+	Unit             string
+	BinSize          int32
+	BlockBinCount    int32
+	BlockColumnCount int32
+	Blocks           map[int32]indexEntry
+}
+
+func zoomKey(unit string, binSize int32) string {
+	return unit + "_" + strconv.Itoa(int(binSize))
+}
+
+// matrixKey is the master index key for a chromosome pair, lower index first
+func matrixKey(chr1, chr2 int) string {
+	return strconv.Itoa(chr1) + "_" + strconv.Itoa(chr2)
+}
+
+// getMatrix reads (once) the zoom headers for a chromosome pair. It returns
+// nil when the file has no contacts between them.
+func (h *HiC) getMatrix(chr1, chr2 int) (*matrix, error) {
+	key := matrixKey(chr1, chr2)
+
+	h.mu.Lock()
+	m, ok := h.matrices[key]
+	h.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	entry, ok := h.masterIndex[key]
+	if !ok {
+		return nil, nil
+	}
+	data, err := bigdata.ReadSourceRange(h.Path, entry.Position, int(entry.Size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read matrix %s: %w", key, err)
+	}
+	m, err = parseMatrix(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse matrix %s: %w", key, err)
+	}
+
+	h.mu.Lock()
+	h.matrices[key] = m
+	h.mu.Unlock()
+	return m, nil
+}
+
+func parseMatrix(data []byte) (*matrix, error) {
+	p := utils.NewParser(bytes.NewReader(data), binary.LittleEndian)
+
+	var chr1, chr2, nZooms int32
+	if err := p.ReadMultiple(&chr1, &chr2, &nZooms); err != nil {
+		return nil, err
+	}
+	m := &matrix{Chr1: int(chr1), Chr2: int(chr2), Zooms: make(map[string]*zoomData, nZooms)}
+
+	for range nZooms {
+		unit, err := p.GetString(0)
+		if err != nil {
+			return nil, err
+		}
+		// Old zoom index, sum of counts, occupied cell count, std dev and 95th percentile
+		var zoomIndex int32
+		var sumCounts, occupiedCells, stdDev, percent95 float32
+		if err := p.ReadMultiple(&zoomIndex, &sumCounts, &occupiedCells, &stdDev, &percent95); err != nil {
+			return nil, err
+		}
+
+		z := &zoomData{Unit: unit}
+		var nBlocks int32
+		if err := p.ReadMultiple(&z.BinSize, &z.BlockBinCount, &z.BlockColumnCount, &nBlocks); err != nil {
+			return nil, err
+		}
+		z.Blocks = make(map[int32]indexEntry, nBlocks)
+		for range nBlocks {
+			var number, size int32
+			var position int64
+			if err := p.ReadMultiple(&number, &position, &size); err != nil {
+				return nil, err
+			}
+			z.Blocks[number] = indexEntry{Position: position, Size: int64(size)}
+		}
+		m.Zooms[zoomKey(unit, z.BinSize)] = z
+	}
+	return m, nil
+}
+
+// blockNumbers returns the blocks that may hold contacts between bins
+// [x1, x2] of the first chromosome and [y1, y2] of the second (inclusive).
+// Version 9 stores intrachromosomal blocks along the diagonal rather than in a grid.
+func (z *zoomData) blockNumbers(version int32, intra bool, x1, x2, y1, y2 int64) []int32 {
+	seen := map[int32]bool{}
+	var numbers []int32
+	add := func(n int32) {
+		if _, ok := z.Blocks[n]; ok && !seen[n] {
+			seen[n] = true
+			numbers = append(numbers, n)
+		}
+	}
+	bbc := int64(z.BlockBinCount)
+	bcc := int32(z.BlockColumnCount)
+
+	if version > 8 && intra {
+		lowerPAD := int32((x1 + y1) / 2 / bbc)
+		higherPAD := int32((x2+y2)/2/bbc + 1)
+		nearer := int32(math.Log2(1 + math.Abs(float64(x1-y2))/math.Sqrt2/float64(bbc)))
+		further := int32(math.Log2(1 + math.Abs(float64(x2-y1))/math.Sqrt2/float64(bbc)))
+
+		nearerDepth := min(nearer, further)
+		// The region crosses the diagonal
+		if (x1 > y2 && x2 < y1) || (x2 > y1 && x1 < y2) {
+			nearerDepth = 0
+		}
+		furtherDepth := max(nearer, further) + 1
+		for depth := nearerDepth; depth <= furtherDepth; depth++ {
+			for pad := lowerPAD; pad <= higherPAD; pad++ {
+				add(depth*bcc + pad)
+			}
+		}
+		return numbers
+	}
+
+	col1, col2 := int32(x1/bbc), int32((x2+1)/bbc)
+	row1, row2 := int32(y1/bbc), int32((y2+1)/bbc)
+	for r := row1; r <= row2; r++ {
+		for c := col1; c <= col2; c++ {
+			add(r*bcc + c)
+		}
+	}
+	// Intrachromosomal matrices only store the upper triangle
+	if intra {
+		for r := col1; r <= col2; r++ {
+			for c := row1; c <= row2; c++ {
+				add(r*bcc + c)
+			}
+		}
+	}
+	return numbers
+}
+
+// decodeBlock inflates and parses the contact records of one block
+func decodeBlock(version int32, compressed []byte) ([]ContactRecord, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	p := utils.NewParser(bytes.NewReader(data), binary.LittleEndian)
+
+	nRecords, err := p.GetInt32()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ContactRecord, 0, nRecords)
+
+	var binXOffset, binYOffset int32
+	if err := p.ReadMultiple(&binXOffset, &binYOffset); err != nil {
+		return nil, err
+	}
+	// Note the flags are inverted: 0 means short values
+	useShort, err := getFlag(p)
+	if err != nil {
+		return nil, err
+	}
+	shortX, shortY := true, true
+	if version > 8 {
+		if shortX, err = getFlag(p); err != nil {
+			return nil, err
+		}
+		if shortY, err = getFlag(p); err != nil {
+			return nil, err
+		}
+	}
+	representation, err := p.GetUInt8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch representation {
+	case 1: // List of rows
+		rowCount, err := getIndex(p, shortY)
+		if err != nil {
+			return nil, err
+		}
+		for range rowCount {
+			row, err := getIndex(p, shortY)
+			if err != nil {
+				return nil, err
+			}
+			colCount, err := getIndex(p, shortX)
+			if err != nil {
+				return nil, err
+			}
+			for range colCount {
+				col, err := getIndex(p, shortX)
+				if err != nil {
+					return nil, err
+				}
+				counts, ok, err := getCounts(p, useShort)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					records = append(records, ContactRecord{BinX: binXOffset + col, BinY: binYOffset + row, Counts: counts})
+				}
+			}
+		}
+	case 2: // Dense
+		var nPoints int32
+		var width int16
+		if err := p.ReadMultiple(&nPoints, &width); err != nil {
+			return nil, err
+		}
+		for i := range nPoints {
+			row := i / int32(width)
+			col := i - row*int32(width)
+			counts, ok, err := getCounts(p, useShort)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				records = append(records, ContactRecord{BinX: binXOffset + col, BinY: binYOffset + row, Counts: counts})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown block representation %d", representation)
+	}
+	return records, nil
+}
+
+func getFlag(p *utils.Parser) (bool, error) {
+	b, err := p.GetUInt8()
+	return b == 0, err
+}
+
+// getIndex reads a row/column count or offset stored as int16 or int32
+func getIndex(p *utils.Parser, short bool) (int32, error) {
+	if short {
+		v, err := p.GetInt16()
+		return int32(v), err
+	}
+	return p.GetInt32()
+}
+
+// getCounts reads a contact value. ok is false for the dense format's missing values.
+func getCounts(p *utils.Parser, short bool) (float32, bool, error) {
+	if short {
+		v, err := p.GetInt16()
+		return float32(v), v != math.MinInt16, err
+	}
+	v, err := p.GetFloat32()
+	return v, !math.IsNaN(float64(v)), err
+}
@@ -0,0 +1,158 @@
+// Package layout assigns features to display rows with UCSC-style greedy
+// interval packing, in base pairs or, given a view width, in pixels.
+package layout
+
+import "sort"
+
+const (
+	DEFAULT_PADDING_BP = 100 // Gap between features when packing in base pairs
+	DEFAULT_PADDING_PX = 4   // Gap between features when packing in pixels
+)
+
+// Item is a feature to place. Its label, if any, is drawn beside its 5' end:
+// left of features on the + strand and right of those on the - strand.
+type Item struct {
+	ID     string
+	Start  int
+	End    int
+	Strand string
+	Label  string
+}
+
+// Options controls packing. With Width set, items are packed in pixels over
+// the view [Start, End), so the gap between them stays constant on screen;
+// otherwise PaddingBp is added on either side of each item.
+type Options struct {
+	PaddingBp int // Base pairs either side of an item, 0 uses DEFAULT_PADDING_BP
+	Width     int // View width in pixels, 0 packs in base pairs
+	Start     int // View region, required with Width
+	End       int
+	PaddingPx int // Pixels between items, 0 uses DEFAULT_PADDING_PX
+	CharWidth int // Pixels per label character, 0 reserves no label space
+	MaxRows   int // Most rows to fill, 0 for no limit
+}
+
+// Layout is the row of each placed item, 0 being the top row. Items that
+// would need more than MaxRows rows are hidden: they have no row and are
+// counted in Overflow, whose entry i is the number of items that would have
+// gone to row MaxRows+i.
+type Layout struct {
+	Rows      map[string]int
+	TotalRows int
+	Overflow  []int
+	Hidden    int
+}
+
+// interval is an item's extent on the packing axis, including padding and label
+type interval struct {
+	index      int
+	id         string
+	start, end float64
+}
+
+// Pack lays out items, keeping every row free of overlaps
+func Pack(items []Item, opts Options) Layout {
+	rows, layout := place(items, opts)
+	layout.Rows = make(map[string]int, len(items))
+	for i, row := range rows {
+		if row >= 0 {
+			layout.Rows[items[i].ID] = row
+		}
+	}
+	return layout
+}
+
+// PackFeatures lays out features of any type, converting each with item. It
+// returns the row of every feature in input order, -1 for hidden ones, so
+// features need no unique ID; the Rows map of the layout is left nil.
+func PackFeatures[T any](features []T, item func(T) Item, opts Options) ([]int, Layout) {
+	items := make([]Item, len(features))
+	for i, f := range features {
+		items[i] = item(f)
+	}
+	return place(items, opts)
+}
+
+// place packs items greedily, returning the row of each item in input order
+func place(items []Item, opts Options) ([]int, Layout) {
+	intervals := make([]interval, 0, len(items))
+	for i, item := range items {
+		iv := opts.extent(item)
+		iv.index = i
+		intervals = append(intervals, iv)
+	}
+
+	// Sort deterministically: start, then end, then id, then input order
+	sort.SliceStable(intervals, func(i, j int) bool {
+		if intervals[i].start != intervals[j].start {
+			return intervals[i].start < intervals[j].start
+		}
+		if intervals[i].end != intervals[j].end {
+			return intervals[i].end < intervals[j].end
+		}
+		return intervals[i].id < intervals[j].id
+	})
+
+	// Greedy first-fit interval packing, tracking the rightmost end of each row
+	var rowsRightEnd []float64
+	var layout Layout
+	rows := make([]int, len(items))
+	for _, iv := range intervals {
+		row := -1
+		for r := range rowsRightEnd {
+			if iv.start >= rowsRightEnd[r] {
+				row = r
+				break
+			}
+		}
+		if row == -1 {
+			row = len(rowsRightEnd)
+			rowsRightEnd = append(rowsRightEnd, iv.end)
+		}
+		rowsRightEnd[row] = iv.end
+
+		if opts.MaxRows > 0 && row >= opts.MaxRows {
+			for len(layout.Overflow) <= row-opts.MaxRows {
+				layout.Overflow = append(layout.Overflow, 0)
+			}
+			layout.Overflow[row-opts.MaxRows]++
+			layout.Hidden++
+			rows[iv.index] = -1
+			continue
+		}
+		rows[iv.index] = row
+		layout.TotalRows = max(layout.TotalRows, row+1)
+	}
+	return rows, layout
+}
+
+// extent converts an item to its interval on the packing axis
+func (opts Options) extent(item Item) interval {
+	if opts.Width <= 0 || opts.End <= opts.Start {
+		padding := opts.PaddingBp
+		if padding <= 0 {
+			padding = DEFAULT_PADDING_BP
+		}
+		return interval{id: item.ID, start: float64(item.Start - padding), end: float64(item.End + padding)}
+	}
+
+	pxPerBp := float64(opts.Width) / float64(opts.End-opts.Start)
+	padding := float64(opts.PaddingPx)
+	if padding <= 0 {
+		padding = DEFAULT_PADDING_PX
+	}
+	iv := interval{
+		id:    item.ID,
+		start: float64(item.Start-opts.Start)*pxPerBp - padding/2,
+		end:   float64(item.End-opts.Start)*pxPerBp + padding/2,
+	}
+	if opts.CharWidth > 0 && item.Label != "" {
+		label := float64(len(item.Label)*opts.CharWidth) + padding
+		if item.Strand == "-" {
+			iv.end += label
+		} else {
+			iv.start -= label
+		}
+	}
+	return iv
+}
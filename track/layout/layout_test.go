@@ -0,0 +1,97 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackBasePairs(t *testing.T) {
+	items := []Item{
+		{ID: "a", Start: 100, End: 200},
+		{ID: "b", Start: 150, End: 250},
+		{ID: "c", Start: 500, End: 600},
+	}
+	got := Pack(items, Options{})
+	want := map[string]int{"a": 0, "b": 1, "c": 0}
+	if !reflect.DeepEqual(got.Rows, want) || got.TotalRows != 2 {
+		t.Errorf("expected rows %v, got %+v", want, got)
+	}
+}
+
+func TestPackPixels(t *testing.T) {
+	// 1000 bp apart, which is plenty in base pairs but 1px at 1 Mb over 1000px
+	items := []Item{
+		{ID: "a", Start: 0, End: 10_000},
+		{ID: "b", Start: 11_000, End: 20_000},
+	}
+	wide := Options{Width: 1000, Start: 0, End: 1_000_000}
+	if got := Pack(items, wide); got.TotalRows != 2 {
+		t.Errorf("expected the items to collide on screen, got %+v", got)
+	}
+	// Zoomed in, the same gap is 100px
+	narrow := Options{Width: 1000, Start: 0, End: 100_000}
+	if got := Pack(items, narrow); got.TotalRows != 1 {
+		t.Errorf("expected the items to share a row, got %+v", got)
+	}
+}
+
+func TestPackLabels(t *testing.T) {
+	// 1 bp per pixel with 50px between the items
+	opts := Options{Width: 1000, Start: 0, End: 1000, PaddingPx: 2, CharWidth: 10}
+	tests := []struct {
+		name   string
+		strand string
+		label  string
+		rows   int
+	}{
+		{"label of a + item sits left, in the gap", "+", "GENE12", 2},
+		{"label of a - item sits right, away from the gap", "-", "GENE12", 1},
+		{"short label fits the gap", "+", "G", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := []Item{
+				{ID: "a", Start: 100, End: 200, Strand: "+"},
+				{ID: "b", Start: 250, End: 350, Strand: tt.strand, Label: tt.label},
+			}
+			if got := Pack(items, opts); got.TotalRows != tt.rows {
+				t.Errorf("expected %d rows, got %+v", tt.rows, got)
+			}
+		})
+	}
+}
+
+func TestPackMaxRows(t *testing.T) {
+	items := []Item{
+		{ID: "a", Start: 100, End: 200},
+		{ID: "b", Start: 100, End: 200},
+		{ID: "c", Start: 100, End: 200},
+		{ID: "d", Start: 100, End: 200},
+		{ID: "e", Start: 5000, End: 6000},
+	}
+	got := Pack(items, Options{MaxRows: 2})
+	if got.TotalRows != 2 || got.Hidden != 2 || !reflect.DeepEqual(got.Overflow, []int{1, 1}) {
+		t.Errorf("expected 2 rows and 2 hidden items, got %+v", got)
+	}
+	if _, ok := got.Rows["d"]; ok {
+		t.Errorf("expected d to be hidden, got %+v", got.Rows)
+	}
+	if got.Rows["e"] != 0 {
+		t.Errorf("expected e in the first row, got %+v", got.Rows)
+	}
+}
+
+func TestPackFeatures(t *testing.T) {
+	// Features without unique IDs still get a row each, in input order
+	type peak struct{ start, end int }
+	peaks := []peak{{500, 600}, {100, 200}, {150, 250}}
+	rows, got := PackFeatures(peaks, func(p peak) Item {
+		return Item{Start: p.start, End: p.end}
+	}, Options{MaxRows: 1})
+	if want := []int{0, 0, -1}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("expected rows %v, got %v", want, rows)
+	}
+	if got.Rows != nil || got.TotalRows != 1 || got.Hidden != 1 {
+		t.Errorf("unexpected layout %+v", got)
+	}
+}
@@ -0,0 +1,207 @@
+// Package liftover converts coordinates between assemblies with UCSC chain
+// files, such as hg19ToHg38.over.chain.gz.
+package liftover
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"gb-api/track/bigdata"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// block is an ungapped alignment of [start, end) on the source assembly to
+// [qStart, qStart+end-start) on the target. For chains on the - strand,
+// qStart counts from the end of the target chromosome.
+type block struct {
+	start, end int
+	chain      *chain
+	qStart     int
+}
+
+// chain is the header of a chain: which target chromosome and strand its
+// blocks map to
+type chain struct {
+	id     int
+	score  float64
+	qChrom string
+	qSize  int
+	qMinus bool
+}
+
+// blockTree is an implicit interval tree over the blocks of one source
+// chromosome: blocks sorted by start form a balanced binary tree around each
+// midpoint, and maxEnd[i] is the largest end in the subtree rooted at i
+type blockTree struct {
+	blocks []block
+	maxEnd []int
+}
+
+// Index holds the blocks of a chain file by source chromosome
+type Index struct {
+	chroms map[string]*blockTree
+	chains int
+}
+
+// LoadChain reads a local or remote chain file, gzipped or not
+func LoadChain(path string) (*Index, error) {
+	f, err := bigdata.OpenStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer gz.Close()
+		return ReadChain(gz)
+	}
+	return ReadChain(r)
+}
+
+// ReadChain parses chains in the UCSC format: a header line
+//
+//	chain score tName tSize tStrand tStart tEnd qName qSize qStrand qStart qEnd id
+//
+// followed by "size dt dq" lines and a final "size" line
+func ReadChain(r io.Reader) (*Index, error) {
+	blocks := map[string][]block{}
+	idx := &Index{chroms: map[string]*blockTree{}}
+
+	var current *chain
+	var tChrom string
+	var t, q int
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "chain" {
+			c, chrom, tStart, qStart, err := parseHeader(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			current, tChrom, t, q = c, chrom, tStart, qStart
+			idx.chains++
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: alignment data before a chain header", line)
+		}
+
+		nums, err := atois(fields)
+		if err != nil || (len(nums) != 1 && len(nums) != 3) {
+			return nil, fmt.Errorf("line %d: expected size [dt dq], got %q", line, scanner.Text())
+		}
+		size := nums[0]
+		blocks[tChrom] = append(blocks[tChrom], block{start: t, end: t + size, chain: current, qStart: q})
+		if len(nums) == 1 {
+			// Last block of the chain
+			current = nil
+			continue
+		}
+		t += size + nums[1]
+		q += size + nums[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for chrom, bs := range blocks {
+		idx.chroms[chrom] = newBlockTree(bs)
+	}
+	return idx, nil
+}
+
+func parseHeader(fields []string) (c *chain, tChrom string, tStart, qStart int, err error) {
+	if len(fields) < 12 {
+		return nil, "", 0, 0, fmt.Errorf("chain header has %d fields, expected 12 or 13", len(fields))
+	}
+	score, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("invalid chain score: %w", err)
+	}
+	nums, err := atois([]string{fields[5], fields[8], fields[10]})
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("invalid chain header: %w", err)
+	}
+	if fields[4] != "+" {
+		return nil, "", 0, 0, fmt.Errorf("source strand must be +, got %s", fields[4])
+	}
+	c = &chain{score: score, qChrom: fields[7], qSize: nums[1], qMinus: fields[9] == "-"}
+	if len(fields) > 12 {
+		c.id, _ = strconv.Atoi(fields[12])
+	}
+	return c, fields[2], nums[0], nums[2], nil
+}
+
+func atois(fields []string) ([]int, error) {
+	nums := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func newBlockTree(blocks []block) *blockTree {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].start < blocks[j].start })
+	t := &blockTree{blocks: blocks, maxEnd: make([]int, len(blocks))}
+	t.index(0, len(blocks))
+	return t
+}
+
+// index fills maxEnd for the subtree over [lo, hi), returning its largest end
+func (t *blockTree) index(lo, hi int) int {
+	if lo >= hi {
+		return 0
+	}
+	mid := (lo + hi) / 2
+	t.maxEnd[mid] = max(t.blocks[mid].end, t.index(lo, mid), t.index(mid+1, hi))
+	return t.maxEnd[mid]
+}
+
+// query appends the blocks overlapping [start, end) to blocks, by start
+func (t *blockTree) query(lo, hi, start, end int, blocks []block) []block {
+	if lo >= hi {
+		return blocks
+	}
+	mid := (lo + hi) / 2
+	if t.maxEnd[mid] <= start {
+		return blocks
+	}
+	blocks = t.query(lo, mid, start, end, blocks)
+	if t.blocks[mid].start >= end {
+		return blocks
+	}
+	if t.blocks[mid].end > start {
+		blocks = append(blocks, t.blocks[mid])
+	}
+	return t.query(mid+1, hi, start, end, blocks)
+}
+
+// blocks returns the blocks overlapping [start, end) of a source chromosome
+func (idx *Index) blocks(chrom string, start, end int) []block {
+	tree, ok := idx.chroms[chrom]
+	if !ok {
+		return nil
+	}
+	return tree.query(0, len(tree.blocks), start, end, nil)
+}
+
+// Chains is the number of chains read
+func (idx *Index) Chains() int {
+	return idx.chains
+}
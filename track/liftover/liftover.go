@@ -0,0 +1,183 @@
+package liftover
+
+import "sort"
+
+// DEFAULT_MIN_MATCH is the fraction of bases that must align for a region to
+// be lifted, as in UCSC liftOver
+const DEFAULT_MIN_MATCH = 0.95
+
+// MAX_REGIONS is the most regions lifted in one request
+const MAX_REGIONS = 10000
+
+// Reasons a region is not lifted, after UCSC liftOver's messages
+const (
+	ReasonDeleted          = "deleted"           // No base aligns
+	ReasonPartiallyDeleted = "partially_deleted" // Too few bases align
+	ReasonSplit            = "split"             // Enough bases align, but not on one chain
+)
+
+// Region is a position, region or BED-like feature. Start and End are
+// 0-based and half-open; a position is a region of one base.
+type Region struct {
+	Chrom  string `json:"chrom"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Name   string `json:"name,omitempty"`
+	Strand string `json:"strand,omitempty"`
+}
+
+// Segment is an aligned part of a source region and where it lands
+type Segment struct {
+	SourceStart int    `json:"sourceStart"`
+	SourceEnd   int    `json:"sourceEnd"`
+	Chrom       string `json:"chrom"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Strand      string `json:"strand"` // - when the chain inverts the region
+	Chain       int    `json:"chain"`  // Chain ID from the file
+}
+
+// Span is a part of a source region
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Result is the liftover of one region. A lifted region spans its segments
+// on the best chain, the one aligning the most bases, like UCSC liftOver
+// without -multiple.
+type Result struct {
+	Source   Region    `json:"source"`
+	Mapped   bool      `json:"mapped"`
+	Region   *Region   `json:"region,omitempty"`   // Lifted region when mapped
+	Reason   string    `json:"reason,omitempty"`   // Why the region was not lifted
+	Matched  float64   `json:"matched"`            // Fraction of source bases aligned by the best chain
+	Split    bool      `json:"split,omitempty"`    // Aligned parts lie on more than one chain
+	Segments []Segment `json:"segments,omitempty"` // Aligned parts on every chain, by source position
+	Unmapped []Span    `json:"unmapped,omitempty"` // Source parts aligned by no chain
+}
+
+// Options controls liftover
+type Options struct {
+	MinMatch float64 // Fraction of bases the best chain must align, 0 uses DEFAULT_MIN_MATCH
+}
+
+// Lift converts a region to the target assembly of the index
+func (idx *Index) Lift(region Region, opts Options) Result {
+	minMatch := opts.MinMatch
+	if minMatch <= 0 {
+		minMatch = DEFAULT_MIN_MATCH
+	}
+	result := Result{Source: region}
+	length := region.End - region.Start
+	if length <= 0 {
+		result.Reason = ReasonDeleted
+		return result
+	}
+
+	// Clip every overlapping block to the region and map it
+	aligned := map[*chain]int{}
+	var covered []Span
+	var chains []*chain // Chain of each segment
+	for _, b := range idx.blocks(region.Chrom, region.Start, region.End) {
+		start, end := max(region.Start, b.start), min(region.End, b.end)
+		qStart := b.qStart + start - b.start
+		qEnd := qStart + end - start
+		strand := "+"
+		if b.chain.qMinus {
+			qStart, qEnd = b.chain.qSize-qEnd, b.chain.qSize-qStart
+			strand = "-"
+		}
+		result.Segments = append(result.Segments, Segment{
+			SourceStart: start, SourceEnd: end,
+			Chrom: b.chain.qChrom, Start: qStart, End: qEnd,
+			Strand: strand, Chain: b.chain.id,
+		})
+		chains = append(chains, b.chain)
+		aligned[b.chain] += end - start
+		covered = append(covered, Span{Start: start, End: end})
+	}
+	result.Unmapped = uncovered(region, covered)
+	result.Split = len(aligned) > 1
+	if len(aligned) == 0 {
+		result.Reason = ReasonDeleted
+		return result
+	}
+
+	// The best chain aligns the most bases, ties going to the higher score,
+	// then the lower ID
+	var best *chain
+	for c, n := range aligned {
+		if best == nil || n > aligned[best] {
+			best = c
+			continue
+		}
+		if n == aligned[best] && (c.score > best.score || (c.score == best.score && c.id < best.id)) {
+			best = c
+		}
+	}
+	result.Matched = float64(aligned[best]) / float64(length)
+	if result.Matched < minMatch {
+		result.Reason = ReasonPartiallyDeleted
+		total := 0
+		for _, n := range aligned {
+			total += n
+		}
+		if float64(total)/float64(length) >= minMatch {
+			result.Reason = ReasonSplit
+		}
+		return result
+	}
+
+	lifted := Region{Chrom: best.qChrom, Start: -1, Name: region.Name, Strand: region.Strand}
+	for i, s := range result.Segments {
+		if chains[i] != best {
+			continue
+		}
+		if lifted.Start < 0 || s.Start < lifted.Start {
+			lifted.Start = s.Start
+		}
+		lifted.End = max(lifted.End, s.End)
+	}
+	if best.qMinus {
+		lifted.Strand = flip(region.Strand)
+	}
+	result.Mapped, result.Region = true, &lifted
+	return result
+}
+
+// LiftAll converts regions, keeping their order
+func (idx *Index) LiftAll(regions []Region, opts Options) []Result {
+	results := make([]Result, len(regions))
+	for i, r := range regions {
+		results[i] = idx.Lift(r, opts)
+	}
+	return results
+}
+
+// uncovered returns the parts of region outside the covered spans
+func uncovered(region Region, covered []Span) []Span {
+	sort.Slice(covered, func(i, j int) bool { return covered[i].Start < covered[j].Start })
+	var gaps []Span
+	pos := region.Start
+	for _, c := range covered {
+		if c.Start > pos {
+			gaps = append(gaps, Span{Start: pos, End: c.Start})
+		}
+		pos = max(pos, c.End)
+	}
+	if pos < region.End {
+		gaps = append(gaps, Span{Start: pos, End: region.End})
+	}
+	return gaps
+}
+
+func flip(strand string) string {
+	switch strand {
+	case "+":
+		return "-"
+	case "-":
+		return "+"
+	}
+	return strand
+}
@@ -0,0 +1,145 @@
+package liftover
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testChain maps chr1 to chrA in two blocks, to chrB inverted and to chrC
+const testChain = `chain 1000 chr1 10000 + 1000 2000 chrA 20000 + 5000 5950 1
+400 100 50
+500
+
+chain 500 chr1 10000 + 3000 3100 chrB 1000 - 100 200 2
+100
+
+chain 200 chr1 10000 + 2000 2100 chrC 5000 + 0 100 3
+100
+`
+
+func TestLift(t *testing.T) {
+	idx, err := ReadChain(strings.NewReader(testChain))
+	if err != nil {
+		t.Fatalf("ReadChain() error = %v", err)
+	}
+	if idx.Chains() != 3 {
+		t.Fatalf("expected 3 chains, got %d", idx.Chains())
+	}
+
+	tests := []struct {
+		name     string
+		region   Region
+		minMatch float64
+		want     *Region
+		reason   string
+		unmapped []Span
+		split    bool
+	}{
+		{
+			name:   "position",
+			region: Region{Chrom: "chr1", Start: 1200, End: 1201},
+			want:   &Region{Chrom: "chrA", Start: 5200, End: 5201},
+		},
+		{
+			name:   "region in one block",
+			region: Region{Chrom: "chr1", Start: 1100, End: 1300, Name: "peak", Strand: "+"},
+			want:   &Region{Chrom: "chrA", Start: 5100, End: 5300, Name: "peak", Strand: "+"},
+		},
+		{
+			name:     "region over a gap",
+			region:   Region{Chrom: "chr1", Start: 1350, End: 1450},
+			reason:   ReasonPartiallyDeleted,
+			unmapped: []Span{{1400, 1450}},
+		},
+		{
+			name:     "region over a gap with a lower minimum",
+			region:   Region{Chrom: "chr1", Start: 1300, End: 1600},
+			minMatch: 0.5,
+			want:     &Region{Chrom: "chrA", Start: 5300, End: 5550},
+			unmapped: []Span{{1400, 1500}},
+		},
+		{
+			name:   "inverted chain",
+			region: Region{Chrom: "chr1", Start: 3010, End: 3020, Strand: "+"},
+			want:   &Region{Chrom: "chrB", Start: 880, End: 890, Strand: "-"},
+		},
+		{
+			name:   "split between chains",
+			region: Region{Chrom: "chr1", Start: 1950, End: 2050},
+			reason: ReasonSplit,
+			split:  true,
+		},
+		{
+			name:     "deleted",
+			region:   Region{Chrom: "chr1", Start: 5000, End: 6000},
+			reason:   ReasonDeleted,
+			unmapped: []Span{{5000, 6000}},
+		},
+		{
+			name:     "unknown chromosome",
+			region:   Region{Chrom: "chr2", Start: 0, End: 10},
+			reason:   ReasonDeleted,
+			unmapped: []Span{{0, 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.Lift(tt.region, Options{MinMatch: tt.minMatch})
+			if got.Mapped != (tt.want != nil) || !reflect.DeepEqual(got.Region, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got.Region)
+			}
+			if got.Reason != tt.reason {
+				t.Errorf("expected reason %q, got %q", tt.reason, got.Reason)
+			}
+			if !reflect.DeepEqual(got.Unmapped, tt.unmapped) {
+				t.Errorf("expected unmapped %v, got %v", tt.unmapped, got.Unmapped)
+			}
+			if got.Split != tt.split {
+				t.Errorf("expected split %v, got %v", tt.split, got.Split)
+			}
+		})
+	}
+}
+
+func TestLiftRegistered(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "hg19ToTest.over.chain.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(testChain))
+	gz.Close()
+	f.Close()
+
+	list, err := ParseChains("hg19:test=hg19ToTest.over.chain.gz")
+	if err != nil {
+		t.Fatalf("ParseChains() error = %v", err)
+	}
+	t.Cleanup(func() { Configure("", nil) })
+	if err := Configure(dir, list); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if !HasChain("hg19", "test") || HasChain("test", "hg19") {
+		t.Errorf("expected only the hg19 to test chain, got %+v", Chains())
+	}
+
+	results, err := Lift("hg19", "test", []Region{
+		{Chrom: "chr1", Start: 1200, End: 1201},
+		{Chrom: "chr1", Start: 5000, End: 6000},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("Lift() error = %v", err)
+	}
+	if len(results) != 2 || !results[0].Mapped || results[0].Region.Start != 5200 || results[1].Mapped {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if _, err := Lift("test", "hg19", nil, Options{}); err == nil || !strings.Contains(err.Error(), "hg19:test") {
+		t.Errorf("expected an error listing hg19:test, got %v", err)
+	}
+}
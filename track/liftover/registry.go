@@ -0,0 +1,150 @@
+package liftover
+
+import (
+	"fmt"
+	"gb-api/track/bigdata"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Chain is a chain file converting coordinates of one assembly to another
+type Chain struct {
+	From string `json:"from"` // Source assembly, e.g. "hg19"
+	To   string `json:"to"`   // Target assembly, e.g. "grch38"
+	Path string `json:"-"`    // Relative to the data directory unless absolute or a URL
+}
+
+// lazyIndex is a chain file read on first use
+type lazyIndex struct {
+	chain Chain
+	once  sync.Once
+	index *Index
+	err   error
+}
+
+// registered chain files, keyed by from and to assemblies
+var (
+	chainsMu sync.RWMutex
+	chains   = map[[2]string]*lazyIndex{}
+)
+
+// Configure replaces the registered chain files. Relative paths are resolved
+// against dataDir. Files are read on first use.
+func Configure(dataDir string, list []Chain) error {
+	byPair := make(map[[2]string]*lazyIndex, len(list))
+	for _, c := range list {
+		if c.From == "" || c.To == "" || c.Path == "" {
+			return fmt.Errorf("chain needs from and to assemblies and a path: %+v", c)
+		}
+		key := [2]string{c.From, c.To}
+		if _, ok := byPair[key]; ok {
+			return fmt.Errorf("duplicate chain from %s to %s", c.From, c.To)
+		}
+		if !filepath.IsAbs(c.Path) && !bigdata.IsRemote(c.Path) {
+			c.Path = filepath.Join(dataDir, c.Path)
+		}
+		byPair[key] = &lazyIndex{chain: c}
+	}
+
+	chainsMu.Lock()
+	chains = byPair
+	chainsMu.Unlock()
+	return nil
+}
+
+// ParseChains reads a comma-separated list of from:to=path entries, e.g.
+// "hg19:grch38=hg19ToHg38.over.chain.gz"
+func ParseChains(spec string) ([]Chain, error) {
+	var list []Chain
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair, path, ok := strings.Cut(entry, "=")
+		from, to, okPair := strings.Cut(pair, ":")
+		if !ok || !okPair {
+			return nil, fmt.Errorf("invalid chain %q, expected from:to=path", entry)
+		}
+		list = append(list, Chain{From: strings.TrimSpace(from), To: strings.TrimSpace(to), Path: strings.TrimSpace(path)})
+	}
+	return list, nil
+}
+
+// Chains returns the registered chain files sorted by source, then target
+func Chains() []Chain {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+	list := make([]Chain, 0, len(chains))
+	for _, lazy := range chains {
+		list = append(list, lazy.chain)
+	}
+	slices.SortFunc(list, func(a, b Chain) int {
+		if c := strings.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.To, b.To)
+	})
+	return list
+}
+
+// HasChain reports whether coordinates can be lifted from one assembly to another
+func HasChain(from, to string) bool {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+	_, ok := chains[[2]string{from, to}]
+	return ok
+}
+
+// GetIndex returns the index of the chain file from one assembly to another,
+// reading it on first use
+func GetIndex(from, to string) (*Index, error) {
+	chainsMu.RLock()
+	lazy, ok := chains[[2]string{from, to}]
+	chainsMu.RUnlock()
+	if !ok {
+		return nil, UnknownChainError(from, to)
+	}
+
+	lazy.once.Do(func() {
+		slog.Info("Loading chain file", "from", from, "to", to, "path", lazy.chain.Path)
+		lazy.index, lazy.err = LoadChain(lazy.chain.Path)
+		if lazy.err == nil {
+			slog.Info("Loaded chain file", "from", from, "to", to, "chains", lazy.index.Chains())
+		}
+	})
+	if lazy.err != nil {
+		// Allow a later request to retry, e.g. once the file is in place
+		chainsMu.Lock()
+		if chains[[2]string{from, to}] == lazy {
+			chains[[2]string{from, to}] = &lazyIndex{chain: lazy.chain}
+		}
+		chainsMu.Unlock()
+		return nil, fmt.Errorf("failed to load chain from %s to %s: %w", from, to, lazy.err)
+	}
+	return lazy.index, nil
+}
+
+// Lift converts regions from one assembly to another, keeping their order
+func Lift(from, to string, regions []Region, opts Options) ([]Result, error) {
+	idx, err := GetIndex(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return idx.LiftAll(regions, opts), nil
+}
+
+// UnknownChainError reports a missing chain file with the available ones
+func UnknownChainError(from, to string) error {
+	var pairs []string
+	for _, c := range Chains() {
+		pairs = append(pairs, c.From+":"+c.To)
+	}
+	if len(pairs) == 0 {
+		return fmt.Errorf("no chain from %s to %s, none are configured", from, to)
+	}
+	return fmt.Errorf("no chain from %s to %s, expected one of %s", from, to, strings.Join(pairs, ", "))
+}
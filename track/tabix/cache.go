@@ -0,0 +1,54 @@
+package tabix
+
+import (
+	"fmt"
+	"gb-api/cache"
+	"gb-api/config"
+	"log/slog"
+)
+
+// cache of opened indexes and headers, keyed by file and index location
+var TabixIndexCache *cache.Cache[*Tabix]
+
+func init() {
+	cacheSize := config.GetCacheSize()
+
+	indexCache, err := cache.NewCache[*Tabix](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	TabixIndexCache = indexCache
+}
+
+// GetCachedTabix returns an opened file, reusing a cached index and header
+func GetCachedTabix(path string, indexPath string) (*Tabix, error) {
+	cacheId := path + "-" + indexPath
+	if cached, ok := TabixIndexCache.Get(cacheId); ok {
+		return cached, nil
+	}
+	t, err := New(path, indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	TabixIndexCache.Add(cacheId, t)
+	return t, nil
+}
+
+// GetCachedTabixData reads lines overlapping a region, reusing a cached index.
+// indexPath may be empty to use path+".tbi" or path+".csi".
+func GetCachedTabixData(path string, indexPath string, chrom string, start, end int) ([]TabixData, error) {
+	t, err := GetCachedTabix(path, indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open tabix file, %w", err)
+	}
+
+	slog.Debug("Tabix query", "path", path, "chrom", chrom, "start", start, "end", end)
+	data, err := t.Query(chrom, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read tabix data, %w", err)
+	}
+	slog.Debug("Returning tabix lines", "count", len(data))
+
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package tabix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"gb-api/track/bigdata"
+
+	"github.com/biogo/hts/bgzf"
+	"github.com/biogo/hts/tabix"
+	"github.com/brentp/bix"
+)
+
+// Index unifies .tbi and .csi indexes (same method set as bix.Index)
+type Index = bix.Index
+
+// tbiIndex adapts a biogo tabix index to the Index interface
+type tbiIndex struct {
+	*tabix.Index
+}
+
+func (t tbiIndex) Chunks(chrom string, start, end int) ([]bgzf.Chunk, error) {
+	return t.Index.Chunks(chrom, start, end)
+}
+
+func (t tbiIndex) NameColumn() int  { return int(t.Index.NameColumn) }
+func (t tbiIndex) BeginColumn() int { return int(t.Index.BeginColumn) }
+func (t tbiIndex) EndColumn() int   { return int(t.Index.EndColumn) }
+func (t tbiIndex) ZeroBased() bool  { return t.Index.ZeroBased }
+func (t tbiIndex) MetaChar() rune   { return t.Index.MetaChar }
+func (t tbiIndex) Skip() int        { return int(t.Index.Skip) }
+
+// loadIndex reads a .tbi or .csi index. An empty indexPath tries
+// path+".tbi" first and falls back to path+".csi".
+func loadIndex(path string, indexPath string) (Index, error) {
+	candidates := []string{indexPath}
+	if indexPath == "" {
+		candidates = []string{path + ".tbi", path + ".csi"}
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		data, err := bigdata.ReadSource(candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return parseIndex(candidate, data)
+	}
+	return nil, fmt.Errorf("failed to read index for %s: %w", path, lastErr)
+}
+
+// parseIndex decodes raw (bgzipped) index bytes, choosing the format from the magic number
+func parseIndex(name string, data []byte) (Index, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress index %s: %w", name, err)
+	}
+	defer gz.Close()
+
+	raw := bytes.Buffer{}
+	if _, err := raw.ReadFrom(gz); err != nil {
+		return nil, fmt.Errorf("failed to decompress index %s: %w", name, err)
+	}
+
+	magic := raw.Bytes()
+	if len(magic) < 4 {
+		return nil, fmt.Errorf("index %s is too short", name)
+	}
+
+	switch string(magic[:3]) {
+	case "TBI":
+		idx, err := tabix.ReadFrom(&raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tabix index %s: %w", name, err)
+		}
+		if idx == nil {
+			return nil, fmt.Errorf("tabix index %s has no references", name)
+		}
+		return tbiIndex{idx}, nil
+	case "CSI":
+		idx, err := bix.NewCSI(&raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse csi index %s: %w", name, err)
+		}
+		return idx, nil
+	default:
+		return nil, fmt.Errorf("unrecognised index format for %s", name)
+	}
+}
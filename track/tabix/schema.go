@@ -0,0 +1,205 @@
+package tabix
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ColumnType controls how a column's text is converted in parsed features
+type ColumnType string
+
+const (
+	ColumnString     ColumnType = "string"
+	ColumnInt        ColumnType = "int"
+	ColumnFloat      ColumnType = "float"
+	ColumnAttributes ColumnType = "attributes" // GTF `key "value";` or GFF3 `key=value;` pairs
+)
+
+// Column names and types a single tab-separated column
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type,omitempty"` // Defaults to "string"
+}
+
+// TabixFeature is a line parsed with a column schema
+type TabixFeature struct {
+	Chr    string         `json:"chr"`
+	Start  int32          `json:"start"`
+	End    int32          `json:"end"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Built-in schemas. Columns named chrom, start or end are already reported
+// as the feature coordinates and are not repeated in Fields.
+var bedColumns = []Column{
+	{Name: "chrom"},
+	{Name: "start", Type: ColumnInt},
+	{Name: "end", Type: ColumnInt},
+	{Name: "name"},
+	{Name: "score", Type: ColumnFloat},
+	{Name: "strand"},
+	{Name: "thickStart", Type: ColumnInt},
+	{Name: "thickEnd", Type: ColumnInt},
+	{Name: "itemRgb"},
+	{Name: "blockCount", Type: ColumnInt},
+	{Name: "blockSizes"},
+	{Name: "blockStarts"},
+}
+
+var gffColumns = []Column{
+	{Name: "chrom"},
+	{Name: "source"},
+	{Name: "type"},
+	{Name: "start", Type: ColumnInt},
+	{Name: "end", Type: ColumnInt},
+	{Name: "score", Type: ColumnFloat},
+	{Name: "strand"},
+	{Name: "phase", Type: ColumnInt},
+	{Name: "attributes", Type: ColumnAttributes},
+}
+
+var Formats = map[string][]Column{
+	"bed": bedColumns,
+	"bedgraph": {
+		{Name: "chrom"},
+		{Name: "start", Type: ColumnInt},
+		{Name: "end", Type: ColumnInt},
+		{Name: "value", Type: ColumnFloat},
+	},
+	// ENCODE bedMethyl columns followed by the extra modkit pileup counts
+	"bedmethyl": append(append([]Column{}, bedColumns[:9]...),
+		Column{Name: "coverage", Type: ColumnInt},
+		Column{Name: "percentModified", Type: ColumnFloat},
+		Column{Name: "nMod", Type: ColumnInt},
+		Column{Name: "nCanonical", Type: ColumnInt},
+		Column{Name: "nOtherMod", Type: ColumnInt},
+		Column{Name: "nDelete", Type: ColumnInt},
+		Column{Name: "nFail", Type: ColumnInt},
+		Column{Name: "nDiff", Type: ColumnInt},
+		Column{Name: "nNoCall", Type: ColumnInt},
+	),
+	"gff":     gffColumns,
+	"gtf":     gffColumns,
+	"generic": {},
+}
+
+const DefaultFormat = "bed"
+
+// SchemaFor returns the column schema for a request. Custom columns take
+// precedence over the named format; an empty format means DefaultFormat.
+func SchemaFor(format string, columns []Column) ([]Column, error) {
+	if len(columns) > 0 {
+		for i, c := range columns {
+			if c.Name == "" {
+				return nil, fmt.Errorf("column %d has no name", i+1)
+			}
+			switch c.Type {
+			case "", ColumnString, ColumnInt, ColumnFloat, ColumnAttributes:
+			default:
+				return nil, fmt.Errorf("column %s has unknown type %s", c.Name, c.Type)
+			}
+		}
+		return columns, nil
+	}
+
+	if format == "" {
+		format = DefaultFormat
+	}
+	schema, ok := Formats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+	return schema, nil
+}
+
+// ParseFeatures converts raw lines into features using a column schema.
+// Columns beyond the schema are named col<N> (1-based) and kept as strings,
+// and "." marks a missing value.
+func ParseFeatures(data []TabixData, schema []Column) ([]TabixFeature, error) {
+	out := make([]TabixFeature, len(data))
+	for i, d := range data {
+		fields := make(map[string]any, len(d.Fields))
+		for j, raw := range d.Fields {
+			column := Column{Name: "col" + strconv.Itoa(j+1)}
+			if j < len(schema) {
+				column = schema[j]
+			}
+			if column.Name == "chrom" || column.Name == "start" || column.Name == "end" {
+				continue
+			}
+			if raw == "." || raw == "" {
+				continue
+			}
+
+			value, err := parseColumn(column, raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			fields[column.Name] = value
+		}
+
+		out[i] = TabixFeature{
+			Chr:    d.Chr,
+			Start:  d.Start,
+			End:    d.End,
+			Fields: fields,
+		}
+	}
+	return out, nil
+}
+
+func parseColumn(column Column, raw string) (any, error) {
+	switch column.Type {
+	case ColumnInt:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s as int: %s", column.Name, raw)
+		}
+		return v, nil
+	case ColumnFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s as float: %s", column.Name, raw)
+		}
+		return v, nil
+	case ColumnAttributes:
+		return ParseAttributes(raw), nil
+	default:
+		return raw, nil
+	}
+}
+
+// ParseAttributes parses a GTF (`key "value";`) or GFF3 (`key=value;`)
+// attribute column. Repeated keys are joined with commas.
+func ParseAttributes(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, pair := range strings.Split(raw, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		var key, value string
+		if k, v, ok := strings.Cut(pair, "="); ok && !strings.Contains(k, " ") {
+			key = k
+			if unescaped, err := url.PathUnescape(v); err == nil {
+				v = unescaped
+			}
+			value = v
+		} else if k, v, ok := strings.Cut(pair, " "); ok {
+			key = k
+			value = strings.Trim(strings.TrimSpace(v), `"`)
+		} else {
+			key = pair
+		}
+
+		if existing, ok := attrs[key]; ok {
+			attrs[key] = existing + "," + value
+		} else {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
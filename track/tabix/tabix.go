@@ -0,0 +1,251 @@
+package tabix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gb-api/track/bigdata"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/biogo/hts/bgzf"
+	"github.com/biogo/hts/bgzf/index"
+)
+
+// Tabix is an opened bgzipped, tabix-indexed file
+type Tabix struct {
+	Path   string
+	Index  Index
+	Header []string // Meta lines (e.g. "#" or "##" lines) at the start of the file
+
+	vcf bool       // VCF records span REF (or INFO END) rather than one base
+	mu  sync.Mutex // Index chunk lookups sort bins lazily and are not safe for concurrent use
+}
+
+// TabixData represents a single line of a tabix-indexed file.
+// Start and End are 0-based, half-open regardless of the file's convention.
+type TabixData struct {
+	Chr    string   `json:"chr"`
+	Start  int32    `json:"start"`
+	End    int32    `json:"end"`
+	Fields []string `json:"fields"` // All tab-separated columns of the line
+}
+
+// New opens the index for path and reads the file header.
+// indexPath may be empty to use path+".tbi" or path+".csi".
+func New(path string, indexPath string) (*Tabix, error) {
+	idx, err := loadIndex(path, indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tabix{Path: path, Index: idx}
+	if err := t.readHeader(); err != nil {
+		return nil, err
+	}
+	t.vcf = len(t.Header) > 0 && strings.HasPrefix(t.Header[0], "##fileformat=VCF")
+	return t, nil
+}
+
+// readHeader collects the skipped and meta-character lines at the start of the file
+func (t *Tabix) readHeader() error {
+	src, err := bigdata.OpenSource(t.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", t.Path, err)
+	}
+	defer src.Close()
+
+	bg, err := bgzf.NewReader(src, 1)
+	if err != nil {
+		return fmt.Errorf("failed to read bgzip data from %s: %w", t.Path, err)
+	}
+	defer bg.Close()
+
+	buf := bufio.NewReader(bg)
+	metaChar := t.Index.MetaChar()
+	for i := 0; ; i++ {
+		line, err := buf.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read header from %s: %w", t.Path, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if i >= t.Index.Skip() && (line == "" || rune(line[0]) != metaChar) {
+			return nil
+		}
+		t.Header = append(t.Header, line)
+	}
+}
+
+// ReadTabix reads lines overlapping a region without caching the index
+// (use GetCachedTabixData for cached reads)
+func ReadTabix(path string, chr string, start int, end int) ([]TabixData, error) {
+	t, err := New(path, "")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open tabix file, %w", err)
+	}
+	return t.Query(chr, start, end)
+}
+
+// Query returns all lines overlapping [start, end) on chrom
+func (t *Tabix) Query(chrom string, start, end int) ([]TabixData, error) {
+	chunks, err := t.chunks(chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return []TabixData{}, nil
+	}
+
+	src, err := bigdata.OpenSource(t.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", t.Path, err)
+	}
+	defer src.Close()
+
+	bg, err := bgzf.NewReader(src, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bgzip data from %s: %w", t.Path, err)
+	}
+	defer bg.Close()
+
+	cr, err := index.NewChunkReader(bg, chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek in %s: %w", t.Path, err)
+	}
+	defer cr.Close()
+
+	data := []TabixData{}
+	buf := bufio.NewReader(cr)
+	metaChar := t.Index.MetaChar()
+	for {
+		line, readErr := buf.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("error reading line: %w", readErr)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" && rune(line[0]) != metaChar {
+			fields := strings.Split(line, "\t")
+			lineChrom, lineStart, lineEnd, err := t.parseLine(fields)
+			if err != nil {
+				return nil, err
+			}
+
+			if stripChr(lineChrom) == stripChr(chrom) {
+				// Lines are sorted by start, so nothing further can overlap
+				if lineStart >= end {
+					break
+				}
+				if lineEnd > start {
+					data = append(data, TabixData{
+						Chr:    chrom,
+						Start:  int32(lineStart),
+						End:    int32(lineEnd),
+						Fields: fields,
+					})
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	return data, nil
+}
+
+// chunks returns the bgzf chunks covering the region, allowing the file to
+// name chromosomes with or without a "chr" prefix
+func (t *Tabix) chunks(chrom string, start, end int) ([]bgzf.Chunk, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, name := range chromAliases(chrom) {
+		chunks, err := t.Index.Chunks(name, start, end)
+		switch {
+		case errors.Is(err, index.ErrNoReference):
+			continue
+		case errors.Is(err, index.ErrInvalid):
+			// Region starts past the last indexed interval
+			return nil, nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to query index: %w", err)
+		}
+		return chunks, nil
+	}
+	return nil, nil
+}
+
+// chromAliases returns chrom followed by its name with the "chr" prefix toggled
+func chromAliases(chrom string) []string {
+	if strings.HasPrefix(chrom, "chr") {
+		return []string{chrom, strings.TrimPrefix(chrom, "chr")}
+	}
+	return []string{chrom, "chr" + chrom}
+}
+
+// stripChr removes a leading "chr" so chromosome names compare across conventions
+func stripChr(chrom string) string {
+	return strings.TrimPrefix(chrom, "chr")
+}
+
+// parseLine returns the chromosome and 0-based, half-open coordinates of a line
+func (t *Tabix) parseLine(fields []string) (string, int, int, error) {
+	nameCol := t.Index.NameColumn() - 1
+	if nameCol < 0 || nameCol >= len(fields) {
+		return "", 0, 0, fmt.Errorf("line has %d columns, missing name column %d", len(fields), nameCol+1)
+	}
+	chrom := fields[nameCol]
+
+	beginCol := t.Index.BeginColumn() - 1
+	if beginCol < 0 || beginCol >= len(fields) {
+		return "", 0, 0, fmt.Errorf("line has %d columns, missing begin column %d", len(fields), beginCol+1)
+	}
+	start, err := strconv.Atoi(fields[beginCol])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid start position: %v", err)
+	}
+	if !t.Index.ZeroBased() {
+		start--
+	}
+
+	endCol := t.Index.EndColumn() - 1
+	if endCol < 0 || endCol == beginCol {
+		if t.vcf {
+			return chrom, start, vcfEnd(fields, start), nil
+		}
+		return chrom, start, start + 1, nil
+	}
+	if endCol >= len(fields) {
+		return "", 0, 0, fmt.Errorf("line has %d columns, missing end column %d", len(fields), endCol+1)
+	}
+	end, err := strconv.Atoi(fields[endCol])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid end position: %v", err)
+	}
+	return chrom, start, end, nil
+}
+
+// vcfEnd returns the 0-based exclusive end of a VCF record: INFO END when
+// present (symbolic alleles), otherwise the end of the REF allele
+func vcfEnd(fields []string, start int) int {
+	if len(fields) > 7 {
+		for _, kv := range strings.Split(fields[7], ";") {
+			if v, ok := strings.CutPrefix(kv, "END="); ok {
+				if end, err := strconv.Atoi(v); err == nil && end > start {
+					return end
+				}
+			}
+		}
+	}
+	if len(fields) > 3 && len(fields[3]) > 0 {
+		return start + len(fields[3])
+	}
+	return start + 1
+}
@@ -0,0 +1,189 @@
+package tabix
+
+import (
+	"gb-api/track/tabix/tabixtest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+var bedLines = []string{
+	"chr1\t100\t200\tpeak1\t500\t+",
+	"chr1\t150\t400\tpeak2\t250\t-",
+	"chr1\t1000\t1100\tpeak3\t.\t+",
+	"chr2\t50\t60\tpeak4\t10\t+",
+}
+
+func writeBedFixture(t *testing.T) string {
+	t.Helper()
+	return tabixtest.WriteFile(t, t.TempDir(), "peaks.bed.gz", []string{"#chrom\tstart\tend"}, bedLines, tabixtest.BED)
+}
+
+func TestQuery(t *testing.T) {
+	path := writeBedFixture(t)
+	tbx, err := New(path, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if len(tbx.Header) != 1 || tbx.Header[0] != "#chrom\tstart\tend" {
+		t.Errorf("unexpected header: %q", tbx.Header)
+	}
+
+	tests := []struct {
+		name      string
+		chrom     string
+		start     int
+		end       int
+		wantNames []string
+	}{
+		{name: "overlapping features", chrom: "chr1", start: 180, end: 300, wantNames: []string{"peak1", "peak2"}},
+		{name: "end is exclusive", chrom: "chr1", start: 0, end: 100, wantNames: []string{}},
+		{name: "start is exclusive of feature end", chrom: "chr1", start: 400, end: 1000, wantNames: []string{}},
+		{name: "single feature", chrom: "chr1", start: 1050, end: 1060, wantNames: []string{"peak3"}},
+		{name: "other chromosome", chrom: "chr2", start: 0, end: 100, wantNames: []string{"peak4"}},
+		{name: "chromosome without chr prefix", chrom: "2", start: 0, end: 100, wantNames: []string{"peak4"}},
+		{name: "chromosome not in file", chrom: "chr3", start: 0, end: 100, wantNames: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tbx.Query(tt.chrom, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(data) != len(tt.wantNames) {
+				t.Fatalf("expected %d lines, got %d: %+v", len(tt.wantNames), len(data), data)
+			}
+			for i, d := range data {
+				if d.Fields[3] != tt.wantNames[i] {
+					t.Errorf("line %d: expected %s, got %s", i, tt.wantNames[i], d.Fields[3])
+				}
+				if d.Chr != tt.chrom {
+					t.Errorf("expected chrom %s, got %s", tt.chrom, d.Chr)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryOneBasedCoordinates(t *testing.T) {
+	lines := []string{
+		"chr19\tHAVANA\tgene\t101\t200\t.\t+\t.\tgene_id \"G1\"; gene_name \"ONE\";",
+		"chr19\tHAVANA\texon\t151\t160\t.\t+\t.\tgene_id \"G1\"; exon_number 1;",
+	}
+	path := tabixtest.WriteFile(t, t.TempDir(), "genes.gtf.gz", nil, lines, tabixtest.GFF)
+
+	data, err := ReadTabix(path, "chr19", 100, 101)
+	if err != nil {
+		t.Fatalf("ReadTabix() error = %v", err)
+	}
+	if len(data) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(data))
+	}
+	if data[0].Start != 100 || data[0].End != 200 {
+		t.Errorf("expected 0-based coordinates 100-200, got %d-%d", data[0].Start, data[0].End)
+	}
+}
+
+func TestQueryRemote(t *testing.T) {
+	path := writeBedFixture(t)
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(path))))
+	defer server.Close()
+
+	data, err := GetCachedTabixData(server.URL+"/"+filepath.Base(path), "", "chr1", 180, 300)
+	if err != nil {
+		t.Fatalf("GetCachedTabixData() error = %v", err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(data))
+	}
+}
+
+func TestMissingIndex(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.bed.gz"), "")
+	if err == nil {
+		t.Error("expected error for missing index")
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	data := []TabixData{
+		{Chr: "chr1", Start: 100, End: 200, Fields: []string{"chr1", "100", "200", "peak1", "5.5", "+", "extra"}},
+		{Chr: "chr1", Start: 300, End: 400, Fields: []string{"chr1", "300", "400", "peak2", "."}},
+	}
+	schema, err := SchemaFor("", nil)
+	if err != nil {
+		t.Fatalf("SchemaFor() error = %v", err)
+	}
+
+	features, err := ParseFeatures(data, schema[:6])
+	if err != nil {
+		t.Fatalf("ParseFeatures() error = %v", err)
+	}
+
+	if features[0].Fields["name"] != "peak1" {
+		t.Errorf("expected name peak1, got %v", features[0].Fields["name"])
+	}
+	if features[0].Fields["score"] != 5.5 {
+		t.Errorf("expected score 5.5, got %v", features[0].Fields["score"])
+	}
+	if features[0].Fields["col7"] != "extra" {
+		t.Errorf("expected unnamed column col7, got %v", features[0].Fields["col7"])
+	}
+	if _, ok := features[0].Fields["chrom"]; ok {
+		t.Error("coordinate columns should not be repeated in fields")
+	}
+	if _, ok := features[1].Fields["score"]; ok {
+		t.Error("missing values should be omitted")
+	}
+
+	bad := []TabixData{{Chr: "chr1", Start: 0, End: 1, Fields: []string{"chr1", "0", "1", "x", "notanumber"}}}
+	if _, err := ParseFeatures(bad, schema); err == nil {
+		t.Error("expected error for non-numeric score")
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		columns []Column
+		wantLen int
+		wantErr bool
+	}{
+		{name: "default is bed", format: "", wantLen: len(bedColumns)},
+		{name: "bedgraph", format: "bedGraph", wantLen: 4},
+		{name: "bedmethyl", format: "bedmethyl", wantLen: 18},
+		{name: "gff", format: "gff", wantLen: 9},
+		{name: "unknown format", format: "vcf", wantErr: true},
+		{name: "custom columns", format: "bed", columns: []Column{{Name: "chrom"}, {Name: "pos", Type: ColumnInt}}, wantLen: 2},
+		{name: "custom column without name", columns: []Column{{Type: ColumnInt}}, wantErr: true},
+		{name: "custom column with bad type", columns: []Column{{Name: "x", Type: "bool"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := SchemaFor(tt.format, tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SchemaFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(schema) != tt.wantLen {
+				t.Errorf("expected %d columns, got %d", tt.wantLen, len(schema))
+			}
+		})
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	gtf := ParseAttributes(`gene_id "ENSG1"; gene_name "APOE"; tag "basic"; tag "CCDS";`)
+	if gtf["gene_name"] != "APOE" || gtf["tag"] != "basic,CCDS" {
+		t.Errorf("unexpected GTF attributes: %v", gtf)
+	}
+
+	gff := ParseAttributes("ID=gene:ENSG1;Name=APOE;Note=a%3Bb")
+	if gff["ID"] != "gene:ENSG1" || gff["Name"] != "APOE" || gff["Note"] != "a;b" {
+		t.Errorf("unexpected GFF3 attributes: %v", gff)
+	}
+}
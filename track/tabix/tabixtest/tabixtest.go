@@ -0,0 +1,222 @@
+// Package tabixtest writes small bgzipped, tabix-indexed files for tests.
+package tabixtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bgzf"
+)
+
+// Preset mirrors the column presets of `tabix -p`
+type Preset struct {
+	Format      int32
+	ZeroBased   bool
+	NameColumn  int32
+	BeginColumn int32
+	EndColumn   int32
+}
+
+var (
+	BED = Preset{Format: 0, ZeroBased: true, NameColumn: 1, BeginColumn: 2, EndColumn: 3}
+	GFF = Preset{Format: 0, ZeroBased: false, NameColumn: 1, BeginColumn: 4, EndColumn: 5}
+	VCF = Preset{Format: 2, ZeroBased: false, NameColumn: 1, BeginColumn: 2, EndColumn: 0}
+)
+
+const tileWidth = 1 << 14 // Linear index tile size from the tabix spec
+
+// indexedLine is a line's coordinates and its location in the BGZF file
+type indexedLine struct {
+	start, end int
+	begin, fin int64 // Compressed offsets of the line's block and the next block
+}
+
+// WriteFile writes header and sorted tab-separated lines to dir/name as BGZF,
+// with each line in its own block, plus a matching name.tbi index.
+// It returns the path of the data file.
+func WriteFile(t testing.TB, dir, name string, header []string, lines []string, preset Preset) string {
+	t.Helper()
+
+	out := &bytes.Buffer{} // Its length is the compressed offset used for index chunks
+	bg := bgzf.NewWriter(out, 1)
+
+	flush := func() int64 {
+		if err := bg.Flush(); err != nil {
+			t.Fatalf("flush bgzf: %v", err)
+		}
+		if err := bg.Wait(); err != nil {
+			t.Fatalf("wait bgzf: %v", err)
+		}
+		return int64(out.Len())
+	}
+
+	if len(header) > 0 {
+		if _, err := bg.Write([]byte(strings.Join(header, "\n") + "\n")); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		flush()
+	}
+
+	var names []string
+	byChrom := map[string][]indexedLine{}
+	for _, line := range lines {
+		begin := int64(out.Len())
+		if _, err := bg.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("write line: %v", err)
+		}
+		fin := flush()
+
+		fields := strings.Split(line, "\t")
+		start, err := strconv.Atoi(fields[preset.BeginColumn-1])
+		if err != nil {
+			t.Fatalf("bad start in %q: %v", line, err)
+		}
+		if !preset.ZeroBased {
+			start--
+		}
+		end := start + 1
+		if preset.EndColumn > 0 {
+			end, err = strconv.Atoi(fields[preset.EndColumn-1])
+			if err != nil {
+				t.Fatalf("bad end in %q: %v", line, err)
+			}
+		} else if preset.Format == 2 {
+			// Like htslib, VCF records span REF or INFO END
+			end = start + len(fields[3])
+			for _, kv := range strings.Split(fields[7], ";") {
+				if v, ok := strings.CutPrefix(kv, "END="); ok {
+					if end, err = strconv.Atoi(v); err != nil {
+						t.Fatalf("bad END in %q: %v", line, err)
+					}
+				}
+			}
+		}
+
+		chrom := fields[preset.NameColumn-1]
+		if _, ok := byChrom[chrom]; !ok {
+			names = append(names, chrom)
+		}
+		byChrom[chrom] = append(byChrom[chrom], indexedLine{start: start, end: end, begin: begin, fin: fin})
+	}
+	if err := bg.Close(); err != nil {
+		t.Fatalf("close bgzf: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("write data: %v", err)
+	}
+
+	var compressed bytes.Buffer
+	ibg := bgzf.NewWriter(&compressed, 1)
+	if _, err := ibg.Write(encodeIndex(preset, names, byChrom)); err != nil {
+		t.Fatalf("compress index: %v", err)
+	}
+	if err := ibg.Close(); err != nil {
+		t.Fatalf("compress index: %v", err)
+	}
+	if err := os.WriteFile(path+".tbi", compressed.Bytes(), 0644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+
+	return path
+}
+
+// encodeIndex serialises an uncompressed TBI index with one chunk per line.
+// biogo's tabix.Index.Add assigns a new reference ID per record, so the
+// index is written by hand.
+func encodeIndex(preset Preset, names []string, byChrom map[string][]indexedLine) []byte {
+	var buf bytes.Buffer
+	write := func(v any) { binary.Write(&buf, binary.LittleEndian, v) }
+
+	buf.WriteString("TBI\x01")
+	write(int32(len(names)))
+	format := preset.Format
+	if preset.ZeroBased {
+		format |= 0x10000
+	}
+	write(format)
+	write(preset.NameColumn)
+	write(preset.BeginColumn)
+	write(preset.EndColumn)
+	write(int32('#'))
+	write(int32(0)) // skip
+	nameLen := 0
+	for _, n := range names {
+		nameLen += len(n) + 1
+	}
+	write(int32(nameLen))
+	for _, n := range names {
+		buf.WriteString(n)
+		buf.WriteByte(0)
+	}
+
+	for _, chrom := range names {
+		lines := byChrom[chrom]
+
+		var binOrder []uint32
+		bins := map[uint32][]indexedLine{}
+		var intervals []int64
+		for _, l := range lines {
+			bin := reg2bin(l.start, l.end)
+			if _, ok := bins[bin]; !ok {
+				binOrder = append(binOrder, bin)
+			}
+			bins[bin] = append(bins[bin], l)
+
+			for tile := l.start / tileWidth; tile <= (l.end-1)/tileWidth; tile++ {
+				for len(intervals) <= tile {
+					intervals = append(intervals, -1)
+				}
+				if intervals[tile] < 0 {
+					intervals[tile] = l.begin
+				}
+			}
+		}
+
+		write(int32(len(binOrder)))
+		for _, bin := range binOrder {
+			write(bin)
+			write(int32(len(bins[bin])))
+			for _, l := range bins[bin] {
+				write(uint64(l.begin) << 16)
+				write(uint64(l.fin) << 16)
+			}
+		}
+
+		// Empty tiles take the offset of the next non-empty one
+		write(int32(len(intervals)))
+		for i := range intervals {
+			off := intervals[i]
+			for j := i; off < 0 && j < len(intervals); j++ {
+				off = intervals[j]
+			}
+			write(uint64(off) << 16)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// reg2bin is the UCSC binning scheme from the SAM/tabix specs
+func reg2bin(beg, end int) uint32 {
+	end--
+	switch {
+	case beg>>14 == end>>14:
+		return uint32(((1<<15)-1)/7 + (beg >> 14))
+	case beg>>17 == end>>17:
+		return uint32(((1<<12)-1)/7 + (beg >> 17))
+	case beg>>20 == end>>20:
+		return uint32(((1<<9)-1)/7 + (beg >> 20))
+	case beg>>23 == end>>23:
+		return uint32(((1<<6)-1)/7 + (beg >> 23))
+	case beg>>26 == end>>26:
+		return uint32(((1<<3)-1)/7 + (beg >> 26))
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package transcript
+
+import (
+	"fmt"
+	"gb-api/track/bigdata"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_DATA_DIR = "./track/transcript/data"
+	DEFAULT_ASSEMBLY = "grch38"
+)
+
+// Annotation is a sorted, bgzipped and tabix-indexed GTF or GFF3 for one
+// assembly, from GENCODE, Ensembl or RefSeq. Files given as http(s) URLs are
+// read with range requests, next to a .tbi or .csi index.
+type Annotation struct {
+	Assembly string `json:"assembly"` // Identifier used in requests, e.g. "grch38"
+	Genome   string `json:"genome"`   // Reference genome, e.g. "GRCh38"
+	Release  string `json:"release"`  // Annotation release, e.g. "GENCODE v40"
+	Path     string `json:"-"`        // Relative to the data directory unless absolute or a URL
+}
+
+// DefaultAnnotations are registered until Configure is called: the GENCODE
+// release shipped under the data directory, built with the steps in script.md
+var DefaultAnnotations = []Annotation{
+	{Assembly: "grch38", Genome: "GRCh38", Release: "GENCODE v40", Path: "v40/sorted.gtf.gz"},
+}
+
+// KnownReleases describe assemblies that can be registered with
+// TRANSCRIPT_ANNOTATIONS, whose files must be built or hosted separately
+var KnownReleases = []Annotation{
+	{Assembly: "grch38", Genome: "GRCh38", Release: "GENCODE v40"},
+	{Assembly: "grch38-v45", Genome: "GRCh38", Release: "GENCODE v45"},
+	{Assembly: "mm10", Genome: "GRCm38", Release: "GENCODE vM25"},
+	{Assembly: "mm39", Genome: "GRCm39", Release: "GENCODE vM36"},
+}
+
+// registry maps assembly identifiers to their annotation files
+type registry struct {
+	mu              sync.RWMutex
+	annotations     map[string]Annotation
+	defaultAssembly string
+}
+
+var annotations = &registry{}
+
+func init() {
+	if err := Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY); err != nil {
+		panic(err)
+	}
+}
+
+// Configure replaces the registered annotations. Relative paths are resolved
+// against dataDir, and defaultAssembly is used for requests without one.
+// Preloaded annotations that are no longer registered are released.
+func Configure(dataDir string, list []Annotation, defaultAssembly string) error {
+	byAssembly := make(map[string]Annotation, len(list))
+	for _, a := range list {
+		if a.Assembly == "" || a.Path == "" {
+			return fmt.Errorf("annotation needs an assembly and a path: %+v", a)
+		}
+		if _, ok := byAssembly[a.Assembly]; ok {
+			return fmt.Errorf("duplicate annotation for assembly %s", a.Assembly)
+		}
+		if !filepath.IsAbs(a.Path) && !bigdata.IsRemote(a.Path) {
+			a.Path = filepath.Join(dataDir, a.Path)
+		}
+		byAssembly[a.Assembly] = a
+	}
+	if _, ok := byAssembly[defaultAssembly]; !ok {
+		return fmt.Errorf("default assembly %s is not configured", defaultAssembly)
+	}
+
+	annotations.mu.Lock()
+	annotations.annotations = byAssembly
+	annotations.defaultAssembly = defaultAssembly
+	annotations.mu.Unlock()
+	dropIndexes(byAssembly)
+	return nil
+}
+
+// ParseAnnotations reads a comma-separated list of assembly=path entries,
+// e.g. "grch38=v40/sorted.gtf.gz,mm10=https://data.example.org/vM25.gtf.gz"
+func ParseAnnotations(spec string) ([]Annotation, error) {
+	var list []Annotation
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		assembly, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid annotation %q, expected assembly=path", entry)
+		}
+		a := Annotation{Assembly: strings.TrimSpace(assembly), Path: strings.TrimSpace(path)}
+		// Keep the genome and release of a known assembly when only its file moves
+		for _, d := range KnownReleases {
+			if d.Assembly == a.Assembly {
+				a.Genome, a.Release = d.Genome, d.Release
+			}
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+// Assemblies returns the registered assembly identifiers in sorted order
+func Assemblies() []string {
+	annotations.mu.RLock()
+	defer annotations.mu.RUnlock()
+	ids := make([]string, 0, len(annotations.annotations))
+	for id := range annotations.annotations {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// LookupAnnotation returns the annotation of an assembly, or of the default
+// assembly when it is empty
+func LookupAnnotation(assembly string) (Annotation, error) {
+	annotations.mu.RLock()
+	if assembly == "" {
+		assembly = annotations.defaultAssembly
+	}
+	a, ok := annotations.annotations[assembly]
+	annotations.mu.RUnlock()
+	if !ok {
+		return Annotation{}, UnknownAssemblyError(assembly)
+	}
+	return a, nil
+}
+
+// UnknownAssemblyError reports an unregistered assembly with the available ones
+func UnknownAssemblyError(assembly string) error {
+	return fmt.Errorf("unknown assembly %s, expected one of %s", assembly, strings.Join(Assemblies(), ", "))
+}
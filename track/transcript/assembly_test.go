@@ -0,0 +1,67 @@
+package transcript
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureAnnotations(t *testing.T) {
+	t.Cleanup(func() { Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY) })
+
+	list, err := ParseAnnotations("grch38=v45/sorted.gtf.gz, hg19=/data/hg19.gtf.gz")
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if err := Configure("/annotations", list, "hg19"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	if got := Assemblies(); strings.Join(got, ",") != "grch38,hg19" {
+		t.Errorf("expected grch38 and hg19, got %v", got)
+	}
+	a, err := LookupAnnotation("grch38")
+	if err != nil {
+		t.Fatalf("LookupAnnotation() error = %v", err)
+	}
+	if a.Path != filepath.Join("/annotations", "v45/sorted.gtf.gz") || a.Release != "GENCODE v40" {
+		t.Errorf("unexpected annotation %+v", a)
+	}
+	if a, _ := LookupAnnotation(""); a.Assembly != "hg19" || a.Path != "/data/hg19.gtf.gz" {
+		t.Errorf("expected the default assembly, got %+v", a)
+	}
+
+	_, err = LookupAnnotation("mm10")
+	if err == nil || !strings.Contains(err.Error(), "grch38, hg19") {
+		t.Errorf("expected an unknown assembly error listing the available ones, got %v", err)
+	}
+	if _, err := GetTranscripts("mm10", "chr1", 0, 100); err == nil {
+		t.Errorf("expected GetTranscripts to reject an unknown assembly")
+	}
+}
+
+func TestDefaultAnnotations(t *testing.T) {
+	// Only the release shipped under the data directory is registered
+	if got := Assemblies(); strings.Join(got, ",") != DEFAULT_ASSEMBLY {
+		t.Errorf("expected only %s by default, got %v", DEFAULT_ASSEMBLY, got)
+	}
+	list, err := ParseAnnotations("mm10=https://data.example.org/vM25/sorted.gtf.gz")
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if list[0].Genome != "GRCm38" || list[0].Release != "GENCODE vM25" {
+		t.Errorf("expected the known mm10 release, got %+v", list[0])
+	}
+}
+
+func TestConfigureAnnotationsErrors(t *testing.T) {
+	if err := Configure(DEFAULT_DATA_DIR, DefaultAnnotations, "hg19"); err == nil {
+		t.Errorf("expected an error for an unconfigured default")
+	}
+	if err := Configure(DEFAULT_DATA_DIR, append(DefaultAnnotations, DefaultAnnotations[0]), DEFAULT_ASSEMBLY); err == nil {
+		t.Errorf("expected an error for a duplicate assembly")
+	}
+	if _, err := ParseAnnotations("grch38"); err == nil {
+		t.Errorf("expected an error for an entry without a path")
+	}
+}
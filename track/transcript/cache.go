@@ -0,0 +1,246 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"gb-api/cache"
+	"gb-api/config"
+	"gb-api/track/tabix"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/brentp/bix"
+)
+
+// tabixHandle is an annotation file held open with its parsed index. Queries
+// share one bgzf reader, so they are serialised by mu.
+type tabixHandle struct {
+	mu  sync.Mutex
+	tbx *bix.Bix // nil once closed
+}
+
+// errHandleClosed is returned by queries on a handle closed since it was got
+var errHandleClosed = errors.New("tabix handle was closed")
+
+// open handles, keyed by annotation path. Only registered annotations are
+// opened, so the set stays small; handles are only closed when their file
+// changes.
+var (
+	handlesMu sync.Mutex
+	handles   = map[string]*tabixHandle{}
+)
+
+// cache of built genes, keyed by annotation path and chromosome
+var GeneCache *cache.RangeDataCache[Gene]
+
+func init() {
+	cacheSize := config.GetCacheSize()
+
+	geneCache, err := cache.NewCache[[]cache.RangeData[Gene]](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	GeneCache = geneCache
+}
+
+// getTabixHandle returns the open handle of an annotation file, opening it once
+func getTabixHandle(path string) (*tabixHandle, error) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	if h, ok := handles[path]; ok {
+		return h, nil
+	}
+	tbx, err := bix.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tabix file: %v", err)
+	}
+	h := &tabixHandle{tbx: tbx}
+	handles[path] = h
+	return h, nil
+}
+
+// close releases the file of a handle once its running query is done
+func (h *tabixHandle) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tbx == nil {
+		return
+	}
+	if err := h.tbx.Close(); err != nil {
+		slog.Warn("Could not close annotation", "error", err)
+	}
+	h.tbx = nil
+}
+
+// query reads the records overlapping pos. Like bix, starts are compared
+// 0-based and ends inclusively.
+func (h *tabixHandle) query(pos Position) ([]Record, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tbx == nil {
+		return nil, errHandleClosed
+	}
+
+	cr, err := h.tbx.ChunkedReader(pos.chrom, pos.start, pos.end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tabix file: %v", err)
+	}
+	defer cr.Close()
+
+	var records []Record
+	buf := bufio.NewReader(cr)
+	for {
+		line, err := buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading line: %v", err)
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && line[0] != '#' {
+			record, parseErr := parseFields(bytes.Split(line, []byte{'\t'}))
+			if parseErr != nil {
+				return nil, fmt.Errorf("error parsing record: %v", parseErr)
+			}
+			// Lines are sorted, so nothing after this one overlaps
+			if record.Start-1 >= pos.end {
+				break
+			}
+			if record.End >= pos.start {
+				records = append(records, record)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return records, nil
+}
+
+// queryRemote reads the records overlapping pos from a bgzipped annotation
+// URL with a .tbi or .csi index, through the range requests and index cache
+// of the tabix package
+func queryRemote(path string, pos Position) ([]Record, error) {
+	lines, err := tabix.GetCachedTabixData(path, "", pos.chrom, pos.start, pos.end)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(lines))
+	for _, line := range lines {
+		fields := make([][]byte, len(line.Fields))
+		for i, f := range line.Fields {
+			fields[i] = []byte(f)
+		}
+		record, err := parseFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing record: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// getCachedGenes returns the genes of an annotation overlapping [start, end),
+// only reading the parts of the region that are not cached yet
+func getCachedGenes(path string, chrom string, start, end int) ([]Gene, error) {
+	cacheId := path + "-" + chrom
+	rangesToFetch := []cache.Range{{Start: start, End: end}}
+
+	cachedData, hit := GeneCache.Get(cacheId)
+	if hit {
+		rangesToFetch = cache.FindRanges(start, end, cachedData)
+	}
+	slog.Debug("Gene cache", "path", path, "chrom", chrom, "hit", hit, "rangesToFetch", len(rangesToFetch))
+
+	rangeData := cachedData
+	for _, r := range rangesToFetch {
+		genes, err := readCompleteGenes(path, chrom, r.Start, r.End)
+		if err != nil {
+			return nil, err
+		}
+		rangeData = append(rangeData, cache.RangeData[Gene]{Start: r.Start, End: r.End, Data: genes})
+	}
+
+	if len(rangesToFetch) > 0 {
+		// Copy before sorting and merging so readers of the cached slices are
+		// not disturbed; capping capacity makes merges reallocate
+		copied := make([]cache.RangeData[Gene], len(rangeData))
+		for i, r := range rangeData {
+			copied[i] = cache.RangeData[Gene]{Start: r.Start, End: r.End, Data: r.Data[:len(r.Data):len(r.Data)]}
+		}
+		rangeData = copied
+		sort.Slice(rangeData, func(i, j int) bool {
+			return rangeData[i].Start < rangeData[j].Start
+		})
+		rangeData = cache.MergeRanges(rangeData)
+		GeneCache.Add(cacheId, rangeData)
+	}
+
+	// Genes spanning several fetched ranges are stored once per range
+	seen := map[string]bool{}
+	genes := []Gene{}
+	for _, r := range rangeData {
+		if r.End <= start || r.Start >= end {
+			continue
+		}
+		for _, g := range r.Data {
+			key := g.ID + "-" + g.Name
+			if seen[key] || !overlaps(g.GenomicRange, start, end) {
+				continue
+			}
+			seen[key] = true
+			genes = append(genes, g)
+		}
+	}
+	sort.SliceStable(genes, func(i, j int) bool {
+		return genes[i].Name < genes[j].Name
+	})
+	return genes, nil
+}
+
+// readCompleteGenes builds the genes overlapping [start, end). A region only
+// returns the records of a gene that overlap it, so the query is widened to
+// the full extent of the genes and transcripts found before building them.
+// Transcripts count for files without gene records.
+func readCompleteGenes(path string, chrom string, start, end int) ([]Gene, error) {
+	records, err := GetRecords(path, chrom+":"+strconv.Itoa(start)+"-"+strconv.Itoa(end))
+	if err != nil {
+		return nil, err
+	}
+
+	spanStart, spanEnd := start, end
+	for _, r := range records {
+		if r.Feature == "gene" || r.Feature == "transcript" {
+			spanStart = min(spanStart, r.Start-1)
+			spanEnd = max(spanEnd, r.End)
+		}
+	}
+	if spanStart < start || spanEnd > end {
+		records, err = GetRecords(path, chrom+":"+strconv.Itoa(spanStart)+"-"+strconv.Itoa(spanEnd))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	built, err := buildGenes(records)
+	if err != nil {
+		return nil, err
+	}
+	// Genes only reached through the widened query may be incomplete
+	genes := []Gene{}
+	for _, g := range built {
+		if overlaps(g.GenomicRange, start, end) {
+			genes = append(genes, g)
+		}
+	}
+	return genes, nil
+}
+
+// overlaps reports whether a 1-based inclusive range overlaps [start, end),
+// matching the bounds used by tabix queries
+func overlaps(r GenomicRange, start, end int) bool {
+	return r.Start-1 < end && r.End >= start
+}
@@ -0,0 +1,170 @@
+package transcript
+
+import (
+	"fmt"
+	"gb-api/track/tabix/tabixtest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gtfLine formats a GTF record with attributes given as key, value pairs
+func gtfLine(chrom, feature string, start, end int, strand string, attrs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&b, "%s \"%s\"; ", attrs[i], attrs[i+1])
+	}
+	return strings.Join([]string{chrom, "TEST", feature, fmt.Sprint(start), fmt.Sprint(end), ".", strand, ".", strings.TrimSpace(b.String())}, "\t")
+}
+
+// testGTFLines has GENE1 (+, two exons far apart) and GENE2 (-, one exon)
+var testGTFLines = []string{
+	gtfLine("chr1", "gene", 1001, 9000, "+", "gene_id", "G1.1", "gene_name", "GENE1", "gene_type", "protein_coding"),
+	gtfLine("chr1", "transcript", 1001, 9000, "+", "gene_id", "G1.1", "gene_name", "GENE1", "transcript_id", "T1.1", "transcript_name", "GENE1-201", "tag", "MANE_Select"),
+	gtfLine("chr1", "exon", 1001, 1200, "+", "gene_id", "G1.1", "gene_name", "GENE1", "transcript_id", "T1.1", "transcript_name", "GENE1-201", "exon_number", "1"),
+	gtfLine("chr1", "exon", 8801, 9000, "+", "gene_id", "G1.1", "gene_name", "GENE1", "transcript_id", "T1.1", "transcript_name", "GENE1-201", "exon_number", "2"),
+	gtfLine("chr1", "gene", 12001, 13000, "-", "gene_id", "G2.1", "gene_name", "GENE2", "gene_type", "lncRNA"),
+	gtfLine("chr1", "transcript", 12001, 13000, "-", "gene_id", "G2.1", "gene_name", "GENE2", "transcript_id", "T2.1", "transcript_name", "GENE2-201"),
+	gtfLine("chr1", "exon", 12001, 13000, "-", "gene_id", "G2.1", "gene_name", "GENE2", "transcript_id", "T2.1", "transcript_name", "GENE2-201", "exon_number", "1"),
+}
+
+func writeTestGTF(t *testing.T, lines []string) string {
+	t.Helper()
+	return tabixtest.WriteFile(t, t.TempDir(), "test.gtf.gz", nil, lines, tabixtest.GFF)
+}
+
+func TestGetCachedGenes(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+
+	// The region only touches the first exon, but the gene is built completely
+	genes, err := getCachedGenes(path, "chr1", 1000, 1100)
+	if err != nil {
+		t.Fatalf("getCachedGenes() error = %v", err)
+	}
+	if len(genes) != 1 || genes[0].Name != "GENE1" || len(genes[0].Transcripts[0].Exons) != 2 {
+		t.Fatalf("expected complete GENE1, got %+v", genes)
+	}
+
+	// Panning reuses the cached range and only reads the new part
+	genes, err = getCachedGenes(path, "chr1", 1050, 12500)
+	if err != nil {
+		t.Fatalf("getCachedGenes() error = %v", err)
+	}
+	if len(genes) != 2 || genes[0].Name != "GENE1" || genes[1].Name != "GENE2" {
+		t.Fatalf("expected GENE1 and GENE2 once each, got %+v", genes)
+	}
+	cached, ok := GeneCache.Get(path + "-chr1")
+	if !ok || len(cached) != 1 || cached[0].Start != 1000 || cached[0].End != 12500 {
+		t.Errorf("expected one merged cached range, got %+v", cached)
+	}
+
+	genes, err = getCachedGenes(path, "chr1", 9500, 11000)
+	if err != nil {
+		t.Fatalf("getCachedGenes() error = %v", err)
+	}
+	if len(genes) != 0 {
+		t.Errorf("expected no genes between GENE1 and GENE2, got %+v", genes)
+	}
+}
+
+func TestGetCachedGenesWithoutGeneRecords(t *testing.T) {
+	// UCSC's RefSeq GTFs start each gene at its transcripts
+	path := writeTestGTF(t, []string{
+		gtfLine("chr1", "transcript", 1001, 9000, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_1.1"),
+		gtfLine("chr1", "exon", 1001, 1200, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_1.1", "exon_number", "1"),
+		gtfLine("chr1", "transcript", 2001, 9500, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_2.1"),
+		gtfLine("chr1", "exon", 2001, 2200, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_2.1", "exon_number", "1"),
+		gtfLine("chr1", "exon", 8801, 9000, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_1.1", "exon_number", "2"),
+		gtfLine("chr1", "exon", 9301, 9500, "+", "gene_id", "GENE1", "gene_name", "GENE1", "transcript_id", "NM_2.1", "exon_number", "2"),
+	})
+
+	// The region only touches the first exon of each transcript
+	genes, err := getCachedGenes(path, "chr1", 1000, 2100)
+	if err != nil {
+		t.Fatalf("getCachedGenes() error = %v", err)
+	}
+	if len(genes) != 1 || genes[0].ID != "GENE1" || genes[0].Start != 1001 || genes[0].End != 9500 || genes[0].Strand != "+" {
+		t.Fatalf("expected GENE1 spanning its transcripts, got %+v", genes)
+	}
+	for _, tr := range genes[0].Transcripts {
+		if len(tr.Exons) != 2 {
+			t.Errorf("expected complete transcript %s, got %+v", tr.ID, tr.Exons)
+		}
+	}
+}
+
+func TestConcurrentRecords(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Gene, transcript and one exon of either gene
+			pos, want := "chr1:1000-2000", 3
+			if i%2 == 1 {
+				pos = "chr1:12000-12100"
+			}
+			records, err := GetRecords(path, pos)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(records) != want {
+				errs <- fmt.Errorf("%s: expected %d records, got %d", pos, want, len(records))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRemoteAnnotation(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(path))))
+	defer server.Close()
+	url := server.URL + "/" + filepath.Base(path)
+
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("/unused", []Annotation{{Assembly: "remote", Path: url}}, "remote"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if a, _ := LookupAnnotation("remote"); a.Path != url {
+		t.Fatalf("expected the URL to be kept, got %s", a.Path)
+	}
+
+	genes, err := GetTranscripts("remote", "chr1", 1000, 1100)
+	if err != nil {
+		t.Fatalf("GetTranscripts() error = %v", err)
+	}
+	want, err := readCompleteGenes(path, "chr1", 1000, 1100)
+	if err != nil {
+		t.Fatalf("readCompleteGenes() error = %v", err)
+	}
+	if !reflect.DeepEqual(genes, want) {
+		t.Errorf("expected %+v, got %+v", want, genes)
+	}
+
+	results, err := Search("remote", "GENE2", 0)
+	if err != nil || len(results) == 0 || results[0].Name != "GENE2" {
+		t.Errorf("expected GENE2 from the remote search index, got %+v, %v", results, err)
+	}
+
+	if err := Preload([]string{"remote"}); err != nil {
+		t.Fatalf("Preload() error = %v", err)
+	}
+	if genes, _ := GetTranscripts("remote", "chr1", 0, 20000); len(genes) != 2 {
+		t.Errorf("expected both genes from the preloaded URL, got %+v", genes)
+	}
+}
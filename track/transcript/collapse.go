@@ -0,0 +1,118 @@
+package transcript
+
+import "sort"
+
+// Display modes of the transcript track
+const (
+	ModeExpanded  = "expanded"  // One row entry per transcript (default)
+	ModeCollapsed = "collapsed" // One merged model per gene
+)
+
+// CollapseGenes replaces the transcripts of each gene with a single union
+// model, as in the collapsed modes of UCSC and IGV. Its exons are the union of
+// all exons, its coding parts the union of all CDSs, and the rest of its exons
+// are UTRs when the gene has any coding transcript. The model takes the ID and
+// name of the gene, so layouts pack genes rather than transcripts.
+func CollapseGenes(genes []Gene) []Gene {
+	collapsed := make([]Gene, 0, len(genes))
+	for _, g := range genes {
+		if len(g.Transcripts) > 0 {
+			g.Transcripts = []Transcript{collapseTranscripts(g)}
+		}
+		collapsed = append(collapsed, g)
+	}
+	return collapsed
+}
+
+func collapseTranscripts(g Gene) Transcript {
+	var exons, cdss []GenomicRange
+	for _, t := range g.Transcripts {
+		for _, e := range t.Exons {
+			exons = append(exons, e.GenomicRange)
+			for _, c := range e.CDSs {
+				cdss = append(cdss, c.GenomicRange)
+			}
+		}
+	}
+	exons = unionRanges(exons)
+	cdss = unionRanges(cdss)
+
+	model := Transcript{
+		Feature: Feature{ID: g.ID, Name: g.Name, GenomicRange: g.GenomicRange},
+		Type:    g.Type,
+		Exons:   make([]Exon, 0, len(exons)),
+	}
+	var coding *GenomicRange
+	if len(cdss) > 0 {
+		coding = &GenomicRange{Chrom: cdss[0].Chrom, Start: cdss[0].Start, End: cdss[len(cdss)-1].End}
+	}
+	for _, r := range exons {
+		exon := Exon{Feature: Feature{GenomicRange: r}}
+		var coded []GenomicRange
+		for _, cds := range cdss {
+			if cds.Start <= r.End && cds.End >= r.Start {
+				part := GenomicRange{Chrom: r.Chrom, Start: max(cds.Start, r.Start), End: min(cds.End, r.End)}
+				coded = append(coded, part)
+				exon.CDSs = append(exon.CDSs, CDS{GenomicRange: part})
+			}
+		}
+		if coding != nil {
+			for _, u := range subtractRanges(r, coded) {
+				exon.UTRs = append(exon.UTRs, UTR{GenomicRange: u, Kind: utrKind(u, coding, g.Strand)})
+			}
+		}
+		model.Exons = append(model.Exons, exon)
+	}
+	// The union is not a real reading frame, but phases keep the parts consistent
+	assignPhases(model.Exons, g.Strand)
+	model.Introns = intronsOf(model.Exons)
+
+	// Number exons along the strand like annotated ones
+	for i := range model.Exons {
+		if g.Strand == "-" {
+			model.Exons[i].ExonNumber = len(model.Exons) - i
+		} else {
+			model.Exons[i].ExonNumber = i + 1
+		}
+	}
+	return model
+}
+
+// unionRanges merges overlapping and adjacent 1-based inclusive ranges,
+// returning them sorted by start
+func unionRanges(ranges []GenomicRange) []GenomicRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := make([]GenomicRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []GenomicRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtractRanges returns the parts of r not covered by the sorted,
+// non-overlapping ranges in cut
+func subtractRanges(r GenomicRange, cut []GenomicRange) []GenomicRange {
+	var parts []GenomicRange
+	start := r.Start
+	for _, c := range cut {
+		if c.Start > start {
+			parts = append(parts, GenomicRange{Chrom: r.Chrom, Start: start, End: c.Start - 1})
+		}
+		start = max(start, c.End+1)
+	}
+	if start <= r.End {
+		parts = append(parts, GenomicRange{Chrom: r.Chrom, Start: start, End: r.End})
+	}
+	return parts
+}
@@ -0,0 +1,82 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseGenes(t *testing.T) {
+	r := func(start, end int) GenomicRange { return GenomicRange{Chrom: "chr1", Start: start, End: end} }
+	exon := func(e GenomicRange, cds ...GenomicRange) Exon {
+		exon := Exon{Feature: Feature{GenomicRange: e}}
+		for _, c := range cds {
+			exon.CDSs = append(exon.CDSs, CDS{GenomicRange: c})
+		}
+		return exon
+	}
+	genes := []Gene{{
+		Feature: Feature{ID: "G1", Name: "GENE1", GenomicRange: r(100, 1000)},
+		Strand:  "-",
+		Type:    "protein_coding",
+		Transcripts: []Transcript{
+			{Feature: Feature{ID: "T1"}, Exons: []Exon{exon(r(100, 200), r(150, 200)), exon(r(500, 600), r(500, 550))}},
+			{Feature: Feature{ID: "T2"}, Exons: []Exon{exon(r(180, 300), r(180, 250)), exon(r(900, 1000))}},
+			{Feature: Feature{ID: "T3"}, Exons: []Exon{exon(r(301, 350))}},
+		},
+	}}
+
+	collapsed := CollapseGenes(genes)
+	if len(collapsed) != 1 || len(collapsed[0].Transcripts) != 1 {
+		t.Fatalf("expected one model, got %+v", collapsed)
+	}
+	model := collapsed[0].Transcripts[0]
+	if model.ID != "G1" || model.Name != "GENE1" || model.Start != 100 || model.End != 1000 {
+		t.Errorf("expected the model to take the gene's ID and span, got %+v", model.Feature)
+	}
+
+	// Adjacent exons 180-300 and 301-350 merge with the overlapping 100-200
+	wantExons := []GenomicRange{r(100, 350), r(500, 600), r(900, 1000)}
+	wantCDSs := [][]GenomicRange{{r(150, 250)}, {r(500, 550)}, nil}
+	// Minus strand: the coding span is 150-550, so UTRs to its right are 5'
+	wantUTRs := [][]UTR{
+		{{GenomicRange: r(100, 149), Kind: UTRThreePrime}, {GenomicRange: r(251, 350), Kind: UTRThreePrime}},
+		{{GenomicRange: r(551, 600), Kind: UTRFivePrime}},
+		{{GenomicRange: r(900, 1000), Kind: UTRFivePrime}},
+	}
+	if len(model.Exons) != len(wantExons) {
+		t.Fatalf("expected %d exons, got %+v", len(wantExons), model.Exons)
+	}
+	for i, e := range model.Exons {
+		var cdss []GenomicRange
+		for _, c := range e.CDSs {
+			cdss = append(cdss, c.GenomicRange)
+		}
+		if e.GenomicRange != wantExons[i] || !reflect.DeepEqual(cdss, wantCDSs[i]) || !reflect.DeepEqual(e.UTRs, wantUTRs[i]) {
+			t.Errorf("exon %d: expected %v cds %v utrs %v, got %+v", i, wantExons[i], wantCDSs[i], wantUTRs[i], e)
+		}
+	}
+	// Minus strand exons are numbered from the right
+	if model.Exons[0].ExonNumber != 3 || model.Exons[2].ExonNumber != 1 {
+		t.Errorf("unexpected exon numbers %+v", model.Exons)
+	}
+	if !reflect.DeepEqual(model.Introns, []GenomicRange{r(351, 499), r(601, 899)}) {
+		t.Errorf("unexpected introns %+v", model.Introns)
+	}
+	if len(genes[0].Transcripts) != 3 {
+		t.Errorf("input modified")
+	}
+}
+
+func TestCollapseNonCoding(t *testing.T) {
+	genes := []Gene{{
+		Feature: Feature{ID: "G2", GenomicRange: GenomicRange{Start: 1, End: 500}},
+		Transcripts: []Transcript{
+			{Exons: []Exon{{Feature: Feature{GenomicRange: GenomicRange{Start: 1, End: 100}}}}},
+			{Exons: []Exon{{Feature: Feature{GenomicRange: GenomicRange{Start: 400, End: 500}}}}},
+		},
+	}}
+	model := CollapseGenes(genes)[0].Transcripts[0]
+	if len(model.Exons) != 2 || model.Exons[0].UTRs != nil || model.Exons[1].CDSs != nil {
+		t.Errorf("expected two plain exons, got %+v", model.Exons)
+	}
+}
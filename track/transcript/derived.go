@@ -0,0 +1,157 @@
+package transcript
+
+import "sort"
+
+// Kinds of features derived from gene models
+const (
+	DerivedTSS        = "tss"        // First base of each transcript
+	DerivedPromoter   = "promoter"   // Window around each TSS
+	DerivedGeneBody   = "gene_body"  // Span of each gene
+	DerivedIntron     = "intron"     // Introns of each transcript
+	DerivedIntergenic = "intergenic" // Parts of the region outside every gene
+)
+
+const (
+	DEFAULT_PROMOTER_UPSTREAM   = 2000
+	DEFAULT_PROMOTER_DOWNSTREAM = 2000
+)
+
+// DerivedKinds are the supported derived feature kinds
+var DerivedKinds = []string{DerivedTSS, DerivedPromoter, DerivedGeneBody, DerivedIntron, DerivedIntergenic}
+
+// DeriveOptions selects the derived features to emit
+type DeriveOptions struct {
+	Kind       string
+	Upstream   *int // Promoter bases 5' of the TSS, nil uses DEFAULT_PROMOTER_UPSTREAM
+	Downstream *int // Promoter bases 3' of the TSS, including it, nil uses DEFAULT_PROMOTER_DOWNSTREAM
+}
+
+// DerivedFeature is a BED-like feature derived from a gene or transcript.
+// Start and End are 0-based and half-open, like request coordinates.
+type DerivedFeature struct {
+	Chrom        string `json:"chrom"`
+	Start        int    `json:"start"`
+	End          int    `json:"end"`
+	Name         string `json:"name,omitempty"` // Transcript name, or gene name for gene bodies
+	Strand       string `json:"strand"`
+	Kind         string `json:"kind"`
+	GeneID       string `json:"geneId,omitempty"`
+	GeneName     string `json:"geneName,omitempty"`
+	TranscriptID string `json:"transcriptId,omitempty"`
+}
+
+// GetDerivedFeatures returns the derived features overlapping a region of the
+// annotation of an assembly, from the genes and transcripts passing filter.
+// Promoters reach beyond their gene, so genes are read with the promoter
+// window as flanks.
+func GetDerivedFeatures(assembly string, chrom string, start, end int, filter Filter, opts DeriveOptions) ([]DerivedFeature, error) {
+	flank := 0
+	if opts.Kind == DerivedPromoter {
+		flank = max(opts.promoterWindow())
+	}
+	genes, err := GetTranscripts(assembly, chrom, max(0, start-flank), end+flank)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveFeatures(FilterGenes(genes, filter), chrom, start, end, opts), nil
+}
+
+// promoterWindow returns the promoter bases 5' and 3' of the TSS, using the
+// defaults for those not set. Either may be 0 for a one-sided window.
+func (opts DeriveOptions) promoterWindow() (upstream, downstream int) {
+	upstream, downstream = DEFAULT_PROMOTER_UPSTREAM, DEFAULT_PROMOTER_DOWNSTREAM
+	if opts.Upstream != nil {
+		upstream = *opts.Upstream
+	}
+	if opts.Downstream != nil {
+		downstream = *opts.Downstream
+	}
+	return upstream, downstream
+}
+
+// DeriveFeatures derives features of one kind from genes, keeping those
+// overlapping [start, end) sorted by position. Transcripts of a gene sharing
+// a TSS, promoter or intron yield one feature, named after the first of them.
+func DeriveFeatures(genes []Gene, chrom string, start, end int, opts DeriveOptions) []DerivedFeature {
+	upstream, downstream := opts.promoterWindow()
+	features := []DerivedFeature{}
+	if opts.Kind == DerivedIntergenic {
+		return intergenic(genes, chrom, start, end)
+	}
+
+	for _, g := range genes {
+		seen := map[[2]int]bool{}
+		add := func(f DerivedFeature) {
+			f.Chrom, f.Strand, f.Kind = g.Chrom, g.Strand, opts.Kind
+			f.GeneID, f.GeneName = g.ID, g.Name
+			f.Start = max(0, f.Start)
+			key := [2]int{f.Start, f.End}
+			if seen[key] || f.Start >= f.End || f.Start >= end || f.End <= start {
+				return
+			}
+			seen[key] = true
+			features = append(features, f)
+		}
+
+		if opts.Kind == DerivedGeneBody {
+			gs := span(g.GenomicRange)
+			add(DerivedFeature{Start: gs.Start, End: gs.End, Name: g.Name})
+			continue
+		}
+		for _, t := range g.Transcripts {
+			f := DerivedFeature{Name: t.Name, TranscriptID: t.ID}
+			switch opts.Kind {
+			case DerivedTSS:
+				tss := tssOf(t, g.Strand)
+				f.Start, f.End = tss, tss+1
+				add(f)
+			case DerivedPromoter:
+				tss := tssOf(t, g.Strand)
+				if g.Strand == "-" {
+					f.Start, f.End = tss-downstream+1, tss+upstream+1
+				} else {
+					f.Start, f.End = tss-upstream, tss+downstream
+				}
+				add(f)
+			case DerivedIntron:
+				for _, intron := range t.Introns {
+					is := span(intron)
+					f.Start, f.End = is.Start, is.End
+					add(f)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(features, func(i, j int) bool {
+		if features[i].Start != features[j].Start {
+			return features[i].Start < features[j].Start
+		}
+		return features[i].End < features[j].End
+	})
+	return features
+}
+
+// tssOf is the 0-based position of the first transcribed base
+func tssOf(t Transcript, strand string) int {
+	if strand == "-" {
+		return t.End - 1
+	}
+	return t.Start - 1
+}
+
+// intergenic returns the parts of [start, end) not covered by any gene
+func intergenic(genes []Gene, chrom string, start, end int) []DerivedFeature {
+	spans := make([]GenomicRange, 0, len(genes))
+	for _, g := range genes {
+		spans = append(spans, g.GenomicRange)
+	}
+	// Work in 1-based inclusive coordinates, like the gene spans
+	region := GenomicRange{Chrom: chrom, Start: start + 1, End: end}
+	features := []DerivedFeature{}
+	for _, gap := range subtractRanges(region, unionRanges(spans)) {
+		gs := span(gap)
+		features = append(features, DerivedFeature{Chrom: chrom, Start: gs.Start, End: gs.End, Strand: ".", Kind: DerivedIntergenic})
+	}
+	return features
+}
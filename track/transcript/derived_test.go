@@ -0,0 +1,125 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDerivedFeatures(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	type span struct{ start, end int }
+	tests := []struct {
+		name       string
+		start, end int
+		filter     Filter
+		opts       DeriveOptions
+		want       []span
+	}{
+		{
+			name: "tss on both strands",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedTSS},
+			want: []span{{1000, 1001}, {12999, 13000}},
+		},
+		{
+			name: "promoters flip with the strand",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedPromoter, Upstream: intPtr(100), Downstream: intPtr(50)},
+			want: []span{{900, 1050}, {12950, 13100}},
+		},
+		{
+			name:  "promoter of a gene outside the region",
+			start: 13050,
+			end:   14000,
+			opts:  DeriveOptions{Kind: DerivedPromoter, Upstream: intPtr(100), Downstream: intPtr(50)},
+			want:  []span{{12950, 13100}},
+		},
+		{
+			name: "one-sided promoters",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedPromoter, Upstream: intPtr(0), Downstream: intPtr(50)},
+			want: []span{{1000, 1050}, {12950, 13000}},
+		},
+		{
+			name: "gene bodies",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedGeneBody},
+			want: []span{{1000, 9000}, {12000, 13000}},
+		},
+		{
+			name: "introns",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedIntron},
+			want: []span{{1200, 8800}},
+		},
+		{
+			name: "intergenic",
+			end:  20000,
+			opts: DeriveOptions{Kind: DerivedIntergenic},
+			want: []span{{0, 1000}, {9000, 12000}, {13000, 20000}},
+		},
+		{
+			name:   "intergenic with filtered genes",
+			end:    20000,
+			filter: Filter{GeneTypes: []string{"protein_coding"}},
+			opts:   DeriveOptions{Kind: DerivedIntergenic},
+			want:   []span{{0, 1000}, {9000, 20000}},
+		},
+		{
+			name:   "tss of filtered genes",
+			end:    20000,
+			filter: Filter{GeneTypes: []string{"lncRNA"}},
+			opts:   DeriveOptions{Kind: DerivedTSS},
+			want:   []span{{12999, 13000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features, err := GetDerivedFeatures("test", "chr1", tt.start, tt.end, tt.filter, tt.opts)
+			if err != nil {
+				t.Fatalf("GetDerivedFeatures() error = %v", err)
+			}
+			got := []span{}
+			for _, f := range features {
+				if f.Kind != tt.opts.Kind {
+					t.Errorf("expected kind %s, got %+v", tt.opts.Kind, f)
+				}
+				got = append(got, span{f.Start, f.End})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeriveFeaturesSharedTSS(t *testing.T) {
+	r := func(start, end int) GenomicRange { return GenomicRange{Chrom: "chr1", Start: start, End: end} }
+	genes := []Gene{{
+		Feature: Feature{ID: "G1", Name: "GENE1", GenomicRange: r(101, 1000)},
+		Strand:  "+",
+		Transcripts: []Transcript{
+			{Feature: Feature{ID: "T1", Name: "GENE1-201", GenomicRange: r(101, 1000)}},
+			{Feature: Feature{ID: "T2", Name: "GENE1-202", GenomicRange: r(101, 500)}},
+			{Feature: Feature{ID: "T3", Name: "GENE1-203", GenomicRange: r(301, 1000)}},
+		},
+	}}
+	features := DeriveFeatures(genes, "chr1", 0, 2000, DeriveOptions{Kind: DerivedTSS})
+	if len(features) != 2 || features[0].TranscriptID != "T1" || features[1].TranscriptID != "T3" {
+		t.Errorf("expected one TSS per position, got %+v", features)
+	}
+	if f := features[0]; f.GeneName != "GENE1" || f.Strand != "+" || f.Name != "GENE1-201" {
+		t.Errorf("unexpected feature %+v", f)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,97 @@
+package transcript
+
+import (
+	"slices"
+	"strings"
+)
+
+// Filter selects the genes and transcripts returned for a region. The zero
+// value keeps everything.
+type Filter struct {
+	GeneTypes    []string // Keep genes of these gene_types, e.g. protein_coding, lncRNA
+	Tags         []string // Keep transcripts with any of these tags, e.g. MANE_Select, basic, CCDS
+	SupportLevel int      // Keep transcripts with a transcript_support_level of at most this, 0 keeps all
+	OnePerGene   bool     // Keep one transcript per gene: the MANE Select one, else the longest CDS
+}
+
+// IsZero reports whether the filter keeps every gene and transcript
+func (f Filter) IsZero() bool {
+	return len(f.GeneTypes) == 0 && len(f.Tags) == 0 && f.SupportLevel == 0 && !f.OnePerGene
+}
+
+// FilterGenes returns the genes and transcripts passing f. Genes left without
+// transcripts are dropped. The input, which may be cached, is not modified.
+func FilterGenes(genes []Gene, f Filter) []Gene {
+	if f.IsZero() {
+		return genes
+	}
+	filtered := make([]Gene, 0, len(genes))
+	for _, g := range genes {
+		if len(f.GeneTypes) > 0 && !slices.Contains(f.GeneTypes, g.Type) {
+			continue
+		}
+		transcripts := make([]Transcript, 0, len(g.Transcripts))
+		for _, t := range g.Transcripts {
+			if f.keep(t) {
+				transcripts = append(transcripts, t)
+			}
+		}
+		if f.OnePerGene && len(transcripts) > 1 {
+			transcripts = []Transcript{representative(transcripts)}
+		}
+		if len(transcripts) == 0 {
+			continue
+		}
+		g.Transcripts = transcripts
+		filtered = append(filtered, g)
+	}
+	return filtered
+}
+
+// keep applies the per-transcript criteria. Transcripts whose support was not
+// assessed, such as those of RefSeq, are dropped once a level is required.
+func (f Filter) keep(t Transcript) bool {
+	if f.SupportLevel > 0 && (t.SupportLevel == 0 || t.SupportLevel > f.SupportLevel) {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range f.Tags {
+		if hasTag(t.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// representative picks the transcript shown for a gene: the MANE Select one,
+// else the one with the longest CDS, then the longest span, then by name
+func representative(transcripts []Transcript) Transcript {
+	return slices.MinFunc(transcripts, func(a, b Transcript) int {
+		if a.Canonical != b.Canonical {
+			if a.Canonical {
+				return -1
+			}
+			return 1
+		}
+		if ca, cb := CDSLength(a), CDSLength(b); ca != cb {
+			return cb - ca
+		}
+		if la, lb := a.End-a.Start, b.End-b.Start; la != lb {
+			return lb - la
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// CDSLength is the number of coding bases of a transcript
+func CDSLength(t Transcript) int {
+	length := 0
+	for _, e := range t.Exons {
+		for _, cds := range e.CDSs {
+			length += cds.End - cds.Start + 1
+		}
+	}
+	return length
+}
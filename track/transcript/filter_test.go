@@ -0,0 +1,88 @@
+package transcript
+
+import "testing"
+
+func filterTestGenes() []Gene {
+	exon := func(start, end int, cds ...GenomicRange) Exon {
+		exon := Exon{Feature: Feature{GenomicRange: GenomicRange{Start: start, End: end}}}
+		for _, c := range cds {
+			exon.CDSs = append(exon.CDSs, CDS{GenomicRange: c})
+		}
+		return exon
+	}
+	transcript := func(name string, tags string, level int, exons ...Exon) Transcript {
+		return Transcript{
+			Feature:      Feature{ID: name, Name: name, GenomicRange: GenomicRange{Start: exons[0].Start, End: exons[len(exons)-1].End}},
+			Exons:        exons,
+			Canonical:    hasTag(tags, "MANE_Select"),
+			SupportLevel: level,
+			Tags:         tags,
+		}
+	}
+	return []Gene{
+		{Feature: Feature{Name: "CODING"}, Type: "protein_coding", Transcripts: []Transcript{
+			transcript("CODING-201", "basic", 2, exon(1, 100, GenomicRange{Start: 51, End: 100}), exon(1001, 2000, GenomicRange{Start: 1001, End: 1100})),
+			transcript("CODING-202", "basic,CCDS", 1, exon(1, 100, GenomicRange{Start: 1, End: 100}), exon(1001, 1500, GenomicRange{Start: 1001, End: 1200})),
+			transcript("CODING-203", "", 0, exon(1, 3000)),
+		}},
+		{Feature: Feature{Name: "MANE"}, Type: "protein_coding", Transcripts: []Transcript{
+			transcript("MANE-201", "basic", 1, exon(5001, 6000, GenomicRange{Start: 5001, End: 6000})),
+			transcript("MANE-202", "basic,MANE_Select", 1, exon(5001, 5500, GenomicRange{Start: 5101, End: 5200})),
+		}},
+		{Feature: Feature{Name: "LNC"}, Type: "lncRNA", Transcripts: []Transcript{
+			transcript("LNC-201", "basic", 3, exon(8001, 9000)),
+		}},
+	}
+}
+
+func transcriptNames(genes []Gene) []string {
+	var names []string
+	for _, g := range genes {
+		for _, t := range g.Transcripts {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func TestFilterGenes(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"none", Filter{}, []string{"CODING-201", "CODING-202", "CODING-203", "MANE-201", "MANE-202", "LNC-201"}},
+		{"gene type", Filter{GeneTypes: []string{"lncRNA"}}, []string{"LNC-201"}},
+		{"tags", Filter{Tags: []string{"CCDS", "MANE_Select"}}, []string{"CODING-202", "MANE-202"}},
+		{"support level", Filter{SupportLevel: 2}, []string{"CODING-201", "CODING-202", "MANE-201", "MANE-202"}},
+		// MANE wins over a longer CDS; without MANE the longest CDS wins
+		{"one per gene", Filter{OnePerGene: true}, []string{"CODING-202", "MANE-202", "LNC-201"}},
+		{"combined", Filter{GeneTypes: []string{"protein_coding"}, Tags: []string{"basic"}, OnePerGene: true}, []string{"CODING-202", "MANE-202"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			genes := filterTestGenes()
+			got := transcriptNames(FilterGenes(genes, tt.filter))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+			// The input may be cached and must not change
+			if n := len(transcriptNames(genes)); n != 6 {
+				t.Errorf("input modified, %d transcripts left", n)
+			}
+		})
+	}
+}
+
+func TestParseSupportLevel(t *testing.T) {
+	for value, want := range map[string]int{"1": 1, "5": 5, "NA": 0, "": 0, "2 (assigned to previous version 3)": 2} {
+		if got := parseSupportLevel(value); got != want {
+			t.Errorf("parseSupportLevel(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
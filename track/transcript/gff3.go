@@ -0,0 +1,362 @@
+package transcript
+
+import (
+	"maps"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Genes are built from records named the way GENCODE GTFs name them. Records
+// of other releases are normalized to those names when they are read:
+//
+//   - GFF3 files (GENCODE, Ensembl, RefSeq) link features through ID and
+//     Parent, so gene and transcript attributes are copied down the hierarchy.
+//   - Ensembl uses gene_biotype/transcript_biotype (biotype in GFF3) and
+//     prefixes GFF3 IDs with "gene:" and "transcript:".
+//   - RefSeq names genes with "gene", has no transcript names, keeps the
+//     numeric gene ID in Dbxref and writes tags with spaces ("MANE Select").
+
+// geneFeatures are the GFF3 types of top-level genes
+var geneFeatures = map[string]bool{
+	"gene":                      true,
+	"ncRNA_gene":                true,
+	"pseudogene":                true,
+	"transposable_element_gene": true,
+}
+
+// transcriptTypes names the biotype of GFF3 transcripts that have none
+var transcriptTypes = map[string]string{
+	"mRNA":    "protein_coding",
+	"lnc_RNA": "lncRNA",
+}
+
+// partFeature maps the GFF3 type of a transcript part to its GTF feature, or
+// returns "" for anything that is not part of a transcript model
+func partFeature(feature string) string {
+	switch feature {
+	case "exon", "CDS", "start_codon", "stop_codon", "UTR":
+		return feature
+	case "five_prime_UTR", "three_prime_UTR":
+		return "UTR"
+	}
+	return ""
+}
+
+// isGFF3Attributes reports whether an attribute column uses GFF3 key=value
+// pairs rather than GTF key "value" pairs
+func isGFF3Attributes(attributes string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(attributes), ";")
+	eq := strings.IndexByte(first, '=')
+	sp := strings.IndexByte(first, ' ')
+	return eq > 0 && (sp < 0 || eq < sp)
+}
+
+// parseGFF3Attributes reads key=value pairs. Values are URL-escaped and may
+// be comma-separated lists, which are kept comma-joined like repeated GTF keys.
+func parseGFF3Attributes(attributes string) map[string]string {
+	attrMap := make(map[string]string)
+	for pair := range strings.SplitSeq(attributes, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		values := strings.Split(value, ",")
+		for i, v := range values {
+			values[i] = unescapeAttribute(v)
+		}
+		attrMap[unescapeAttribute(key)] = strings.Join(values, ",")
+	}
+	return attrMap
+}
+
+func unescapeAttribute(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// normalizeRecords rewrites GFF3, Ensembl and RefSeq records with the GENCODE
+// attribute names and features that buildGenes expects
+func normalizeRecords(records []Record) []Record {
+	for _, r := range records {
+		if _, ok := r.Attributes["Parent"]; ok {
+			records = resolveHierarchy(records)
+			break
+		}
+	}
+	for i := range records {
+		applyAliases(records[i].Attributes)
+	}
+	numberExons(records)
+	return records
+}
+
+// resolveHierarchy turns GFF3 genes, their transcripts and the transcripts'
+// parts into GTF-style records carrying the attributes of their ancestors.
+// Features outside a gene model, such as regions or alignments, are dropped.
+func resolveHierarchy(records []Record) []Record {
+	byID := make(map[string]int, len(records))
+	hasChildren := map[string]bool{}
+	for i, r := range records {
+		if id := r.Attributes["ID"]; id != "" {
+			byID[id] = i
+		}
+		for parent := range strings.SplitSeq(r.Attributes["Parent"], ",") {
+			hasChildren[parent] = true
+		}
+	}
+	isGene := func(r Record) bool {
+		return r.Attributes["Parent"] == "" && (geneFeatures[r.Feature] || hasChildren[r.Attributes["ID"]]) && partFeature(r.Feature) == ""
+	}
+
+	geneAttrs := map[string]map[string]string{}
+	transcriptAttrs := map[string]map[string]string{}
+	geneOf := func(i int) map[string]string {
+		id := records[i].Attributes["ID"]
+		if attrs, ok := geneAttrs[id]; ok {
+			return attrs
+		}
+		attrs := gff3GeneAttributes(records[i].Attributes)
+		geneAttrs[id] = attrs
+		return attrs
+	}
+	// transcriptOf returns the attributes of a transcript, or nil when the
+	// record is not the child of a gene
+	transcriptOf := func(i int) map[string]string {
+		r := records[i]
+		id := r.Attributes["ID"]
+		if attrs, ok := transcriptAttrs[id]; ok {
+			return attrs
+		}
+		if partFeature(r.Feature) != "" || isGene(r) {
+			return nil
+		}
+		parent, ok := byID[r.Attributes["Parent"]]
+		if !ok || !isGene(records[parent]) {
+			return nil
+		}
+		attrs := gff3TranscriptAttributes(r, geneOf(parent))
+		transcriptAttrs[id] = attrs
+		return attrs
+	}
+
+	resolved := make([]Record, 0, len(records))
+	for i, r := range records {
+		if isGene(r) {
+			r.Feature = "gene"
+			r.Attributes = merged(r.Attributes, geneOf(i))
+			resolved = append(resolved, r)
+			continue
+		}
+		if attrs := transcriptOf(i); attrs != nil {
+			r.Feature = "transcript"
+			r.Attributes = merged(r.Attributes, attrs)
+			resolved = append(resolved, r)
+			continue
+		}
+		feature := partFeature(r.Feature)
+		if feature == "" {
+			continue
+		}
+		// A part shared by several transcripts is repeated for each of them
+		for parent := range strings.SplitSeq(r.Attributes["Parent"], ",") {
+			p, ok := byID[parent]
+			if !ok {
+				continue
+			}
+			attrs := transcriptOf(p)
+			if attrs == nil {
+				continue
+			}
+			part := r
+			part.Feature = feature
+			part.Attributes = merged(r.Attributes, attrs)
+			if feature == "exon" {
+				part.Attributes["exon_id"] = firstAttribute(r.Attributes, "exon_id", "Name")
+				if part.Attributes["exon_id"] == "" {
+					part.Attributes["exon_id"] = trimIDPrefix(r.Attributes["ID"])
+				}
+				part.Attributes["exon_number"] = gff3ExonNumber(r.Attributes)
+			}
+			resolved = append(resolved, part)
+		}
+	}
+	return resolved
+}
+
+func gff3GeneAttributes(a map[string]string) map[string]string {
+	id := firstAttribute(a, "gene_id")
+	if id == "" {
+		id = dbxref(a["Dbxref"], "GeneID")
+	}
+	if id == "" {
+		id = trimIDPrefix(a["ID"])
+	}
+	name := firstAttribute(a, "gene_name", "Name", "gene")
+	if name == "" {
+		name = id
+	}
+	return map[string]string{
+		"gene_id":   id,
+		"gene_name": name,
+		"gene_type": firstAttribute(a, "gene_type", "gene_biotype", "biotype"),
+	}
+}
+
+func gff3TranscriptAttributes(r Record, gene map[string]string) map[string]string {
+	a := r.Attributes
+	id := firstAttribute(a, "transcript_id")
+	if id == "" {
+		id = trimIDPrefix(a["ID"])
+	}
+	name := firstAttribute(a, "transcript_name", "Name")
+	if name == "" {
+		name = id
+	}
+	transcriptType := firstAttribute(a, "transcript_type", "transcript_biotype", "biotype")
+	if transcriptType == "" {
+		transcriptType = transcriptTypes[r.Feature]
+	}
+	if transcriptType == "" {
+		transcriptType = r.Feature
+	}
+	attrs := merged(gene, map[string]string{
+		"transcript_id":   id,
+		"transcript_name": name,
+		"transcript_type": transcriptType,
+	})
+	if tag := a["tag"]; tag != "" {
+		attrs["tag"] = tag
+	}
+	return attrs
+}
+
+// refSeqExonID matches the exon number at the end of RefSeq exon IDs,
+// e.g. exon-NM_000041.4-3
+var refSeqExonID = regexp.MustCompile(`-(\d+)$`)
+
+// gff3ExonNumber reads the exon number of a GFF3 exon, from Ensembl's rank or
+// a RefSeq ID. Without either the exon is numbered later by position.
+func gff3ExonNumber(a map[string]string) string {
+	if n := firstAttribute(a, "exon_number", "rank"); n != "" {
+		return n
+	}
+	if strings.HasPrefix(a["ID"], "exon-") {
+		if m := refSeqExonID.FindStringSubmatch(a["ID"]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// applyAliases fills the GENCODE attributes of a GTF record from the names
+// Ensembl and RefSeq use for them
+func applyAliases(a map[string]string) {
+	setDefault := func(name string, aliases ...string) {
+		if a[name] == "" {
+			if v := firstAttribute(a, aliases...); v != "" {
+				a[name] = v
+			}
+		}
+	}
+	setDefault("gene_name", "gene", "gene_id")
+	setDefault("gene_type", "gene_biotype")
+	setDefault("transcript_name", "transcript_id")
+	setDefault("transcript_type", "transcript_biotype")
+	if tag := a["tag"]; tag != "" {
+		a["tag"] = strings.ReplaceAll(tag, " ", "_")
+	}
+}
+
+// numberExons numbers the exons of transcripts that lack exon numbers in
+// transcription order, then assigns CDS and UTR records to the exon that
+// contains them. Only the exons present are numbered, so records should
+// cover complete transcripts.
+func numberExons(records []Record) {
+	exonsByTranscript := map[string][]int{}
+	for i, r := range records {
+		if r.Feature == "exon" {
+			id := r.Attributes["transcript_id"]
+			exonsByTranscript[id] = append(exonsByTranscript[id], i)
+		}
+	}
+
+	for _, exons := range exonsByTranscript {
+		numbered := true
+		for _, i := range exons {
+			if records[i].Attributes["exon_number"] == "" {
+				numbered = false
+				break
+			}
+		}
+		if numbered {
+			continue
+		}
+		sort.Slice(exons, func(x, y int) bool {
+			if records[exons[0]].Strand == "-" {
+				return records[exons[x]].Start > records[exons[y]].Start
+			}
+			return records[exons[x]].Start < records[exons[y]].Start
+		})
+		for n, i := range exons {
+			records[i].Attributes["exon_number"] = strconv.Itoa(n + 1)
+		}
+	}
+
+	for i, r := range records {
+		if (r.Feature != "CDS" && r.Feature != "UTR") || r.Attributes["exon_number"] != "" {
+			continue
+		}
+		for _, e := range exonsByTranscript[r.Attributes["transcript_id"]] {
+			if records[e].Start <= r.Start && r.End <= records[e].End {
+				records[i].Attributes["exon_number"] = records[e].Attributes["exon_number"]
+				break
+			}
+		}
+	}
+}
+
+// firstAttribute returns the first non-empty attribute among names
+func firstAttribute(a map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := a[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// dbxref returns the identifier of a database in a GFF3 Dbxref list,
+// e.g. 348 for GeneID in "GeneID:348,HGNC:HGNC:613"
+func dbxref(refs string, db string) string {
+	for ref := range strings.SplitSeq(refs, ",") {
+		if id, ok := strings.CutPrefix(ref, db+":"); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// trimIDPrefix removes the type prefix of Ensembl ("gene:") and RefSeq
+// ("gene-", "rna-") GFF3 IDs
+func trimIDPrefix(id string) string {
+	for _, prefix := range []string{"gene:", "transcript:", "gene-", "rna-"} {
+		if rest, ok := strings.CutPrefix(id, prefix); ok {
+			return rest
+		}
+	}
+	return id
+}
+
+// merged returns a copy of base with the entries of extra added
+func merged(base, extra map[string]string) map[string]string {
+	m := make(map[string]string, len(base)+len(extra))
+	maps.Copy(m, base)
+	maps.Copy(m, extra)
+	return m
+}
@@ -0,0 +1,174 @@
+package transcript
+
+import (
+	"gb-api/track/tabix/tabixtest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gff3Line formats a GFF3 record with its attribute column given verbatim
+func gff3Line(chrom, feature string, start, end int, strand, attrs string) string {
+	return strings.Join([]string{chrom, "TEST", feature, strconv.Itoa(start), strconv.Itoa(end), ".", strand, ".", attrs}, "\t")
+}
+
+// Ensembl GFF3: prefixed IDs, biotype, rank and five/three prime UTRs on the minus strand
+var ensemblGFF3Lines = []string{
+	gff3Line("19", "ncRNA_gene", 1001, 2000, "-", "ID=gene:ENSG00000000002;Name=LNC1;biotype=lncRNA;gene_id=ENSG00000000002;version=1"),
+	gff3Line("19", "lnc_RNA", 1001, 2000, "-", "ID=transcript:ENST00000000002;Parent=gene:ENSG00000000002;Name=LNC1-201;biotype=lncRNA;transcript_id=ENST00000000002"),
+	gff3Line("19", "exon", 1001, 2000, "-", "Parent=transcript:ENST00000000002;Name=ENSE00000000003;exon_id=ENSE00000000003;rank=1"),
+	gff3Line("19", "gene", 5001, 9000, "-", "ID=gene:ENSG00000000001;Name=GENE1;biotype=protein_coding;gene_id=ENSG00000000001;version=4"),
+	gff3Line("19", "mRNA", 5001, 9000, "-", "ID=transcript:ENST00000000001;Parent=gene:ENSG00000000001;Name=GENE1-201;biotype=protein_coding;tag=basic,Ensembl_canonical,MANE_Select;transcript_id=ENST00000000001"),
+	gff3Line("19", "three_prime_UTR", 5001, 5100, "-", "Parent=transcript:ENST00000000001"),
+	gff3Line("19", "exon", 5001, 5500, "-", "Parent=transcript:ENST00000000001;Name=ENSE00000000002;exon_id=ENSE00000000002;rank=2"),
+	gff3Line("19", "CDS", 5101, 5500, "-", "ID=CDS:ENSP00000000001;Parent=transcript:ENST00000000001;phase=0;protein_id=ENSP00000000001"),
+	gff3Line("19", "CDS", 8001, 8900, "-", "ID=CDS:ENSP00000000001;Parent=transcript:ENST00000000001;phase=0;protein_id=ENSP00000000001"),
+	gff3Line("19", "exon", 8001, 9000, "-", "Parent=transcript:ENST00000000001;Name=ENSE00000000001;exon_id=ENSE00000000001;rank=1"),
+	gff3Line("19", "five_prime_UTR", 8901, 9000, "-", "Parent=transcript:ENST00000000001"),
+}
+
+// RefSeq GFF3: gene symbols, Dbxref gene IDs, escaped values, spaced tags and
+// exons numbered only through their IDs
+var refSeqGFF3Lines = []string{
+	gff3Line("NC_000019.10", "region", 1, 58617616, "+", "ID=NC_000019.10:1..58617616;Dbxref=taxon:9606;chromosome=19"),
+	gff3Line("NC_000019.10", "gene", 1001, 5000, "+", "ID=gene-APOE;Dbxref=GeneID:348,HGNC:HGNC:613;Name=APOE;description=apolipoprotein E%2C variant;gbkey=Gene;gene=APOE;gene_biotype=protein_coding"),
+	gff3Line("NC_000019.10", "mRNA", 1001, 5000, "+", "ID=rna-NM_000041.4;Parent=gene-APOE;Dbxref=GeneID:348;Name=NM_000041.4;gbkey=mRNA;gene=APOE;tag=MANE Select;transcript_id=NM_000041.4"),
+	gff3Line("NC_000019.10", "mRNA", 1001, 5000, "+", "ID=rna-XM_000001.1;Parent=gene-APOE;Dbxref=GeneID:348;Name=XM_000001.1;gbkey=mRNA;gene=APOE;transcript_id=XM_000001.1"),
+	gff3Line("NC_000019.10", "exon", 1001, 1500, "+", "ID=exon-NM_000041.4-1;Parent=rna-NM_000041.4;gene=APOE;transcript_id=NM_000041.4"),
+	gff3Line("NC_000019.10", "exon", 1001, 1500, "+", "ID=exon-XM_000001.1-1;Parent=rna-XM_000001.1;gene=APOE;transcript_id=XM_000001.1"),
+	gff3Line("NC_000019.10", "CDS", 1201, 1500, "+", "ID=cds-NP_000032.1;Parent=rna-NM_000041.4,rna-XM_000001.1;gene=APOE;protein_id=NP_000032.1"),
+	gff3Line("NC_000019.10", "exon", 4001, 5000, "+", "ID=exon-NM_000041.4-2;Parent=rna-NM_000041.4;gene=APOE;transcript_id=NM_000041.4"),
+	gff3Line("NC_000019.10", "CDS", 4001, 4500, "+", "ID=cds-NP_000032.1;Parent=rna-NM_000041.4;gene=APOE;protein_id=NP_000032.1"),
+	gff3Line("NC_000019.10", "exon", 4501, 5000, "+", "ID=exon-XM_000001.1-2;Parent=rna-XM_000001.1;gene=APOE;transcript_id=XM_000001.1"),
+}
+
+func TestParseGFF3Attributes(t *testing.T) {
+	record, err := parseFields([][]byte{
+		[]byte("chr1"), []byte("TEST"), []byte("gene"), []byte("1"), []byte("10"), []byte("."), []byte("+"), []byte("."),
+		[]byte("ID=gene-A;Note=50%25 similar%3B partial;Alias=A1,A%2C2;tag=MANE Select"),
+	})
+	if err != nil {
+		t.Fatalf("parseFields() error = %v", err)
+	}
+	want := map[string]string{"ID": "gene-A", "Note": "50% similar; partial", "Alias": "A1,A,2", "tag": "MANE Select"}
+	for k, v := range want {
+		if record.Attributes[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, record.Attributes[k])
+		}
+	}
+
+	if isGFF3Attributes(`gene_id "G1"; note "a=b";`) {
+		t.Errorf("expected GTF attributes not to be read as GFF3")
+	}
+}
+
+func TestEnsemblGFF3Genes(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "ensembl.gff3.gz", nil, ensemblGFF3Lines, tabixtest.GFF)
+	genes, err := ReadGTF(path, "19:1-10000")
+	if err != nil {
+		t.Fatalf("ReadGTF() error = %v", err)
+	}
+	if len(genes) != 2 {
+		t.Fatalf("expected 2 genes, got %+v", genes)
+	}
+
+	gene := genes[0]
+	if gene.Name != "GENE1" || gene.ID != "ENSG00000000001" || gene.Type != "protein_coding" || gene.Strand != "-" {
+		t.Errorf("unexpected gene %+v", gene)
+	}
+	if len(gene.Transcripts) != 1 {
+		t.Fatalf("expected one transcript, got %+v", gene.Transcripts)
+	}
+	tx := gene.Transcripts[0]
+	if tx.Name != "GENE1-201" || tx.ID != "ENST00000000001" || !tx.Canonical {
+		t.Errorf("unexpected transcript %+v", tx)
+	}
+	if len(tx.Exons) != 2 {
+		t.Fatalf("expected two exons, got %+v", tx.Exons)
+	}
+	for _, e := range tx.Exons {
+		if len(e.CDSs) != 1 || len(e.UTRs) != 1 {
+			t.Errorf("expected a CDS and a UTR on exon %d, got %+v", e.ExonNumber, e)
+		}
+	}
+	if first := tx.Exons[1]; first.ExonNumber != 1 || first.ID != "ENSE00000000001" || first.UTRs[0].Start != 8901 {
+		t.Errorf("unexpected first exon %+v", first)
+	}
+
+	lnc := genes[1]
+	if lnc.Name != "LNC1" || lnc.Type != "lncRNA" || lnc.Transcripts[0].Name != "LNC1-201" || lnc.Transcripts[0].Exons[0].ExonNumber != 1 {
+		t.Errorf("unexpected lncRNA gene %+v", lnc)
+	}
+}
+
+func TestRefSeqGFF3Genes(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "refseq.gff3.gz", nil, refSeqGFF3Lines, tabixtest.GFF)
+	genes, err := ReadGTF(path, "NC_000019.10:1-10000")
+	if err != nil {
+		t.Fatalf("ReadGTF() error = %v", err)
+	}
+	if len(genes) != 1 {
+		t.Fatalf("expected APOE only, got %+v", genes)
+	}
+	gene := genes[0]
+	if gene.Name != "APOE" || gene.ID != "348" || gene.Type != "protein_coding" {
+		t.Errorf("unexpected gene %+v", gene)
+	}
+	if len(gene.Transcripts) != 2 {
+		t.Fatalf("expected two transcripts, got %+v", gene.Transcripts)
+	}
+
+	mane, predicted := gene.Transcripts[0], gene.Transcripts[1]
+	if mane.Name != "NM_000041.4" || !mane.Canonical || predicted.Name != "XM_000001.1" || predicted.Canonical {
+		t.Errorf("unexpected transcripts %+v", gene.Transcripts)
+	}
+	var cds int
+	for _, e := range mane.Exons {
+		cds += len(e.CDSs)
+		if e.ID != "exon-NM_000041.4-"+strconv.Itoa(e.ExonNumber) {
+			t.Errorf("unexpected exon %+v", e)
+		}
+	}
+	if cds != 2 {
+		t.Errorf("expected two CDS parts on NM_000041.4, got %d", cds)
+	}
+	// The CDS with two parents belongs to both transcripts
+	if len(predicted.Exons) != 2 || len(predicted.Exons[0].CDSs) != 1 {
+		t.Errorf("expected the shared CDS on XM_000001.1, got %+v", predicted.Exons)
+	}
+}
+
+func TestRefSeqGTFGenes(t *testing.T) {
+	lines := []string{
+		gtfLine("NC_000019.10", "gene", 1001, 5000, "+", "gene_id", "APOE", "gene", "APOE", "gene_biotype", "protein_coding"),
+		gtfLine("NC_000019.10", "transcript", 1001, 5000, "+", "gene_id", "APOE", "transcript_id", "NM_000041.4", "gene", "APOE", "tag", "MANE Select", "transcript_biotype", "mRNA"),
+		gtfLine("NC_000019.10", "exon", 1001, 1500, "+", "gene_id", "APOE", "transcript_id", "NM_000041.4", "exon_number", "1"),
+		gtfLine("NC_000019.10", "exon", 4001, 5000, "+", "gene_id", "APOE", "transcript_id", "NM_000041.4", "exon_number", "2"),
+	}
+	genes, err := ReadGTF(writeTestGTF(t, lines), "NC_000019.10:1-10000")
+	if err != nil {
+		t.Fatalf("ReadGTF() error = %v", err)
+	}
+	if len(genes) != 1 || genes[0].Name != "APOE" || genes[0].Type != "protein_coding" {
+		t.Fatalf("unexpected genes %+v", genes)
+	}
+	tx := genes[0].Transcripts
+	if len(tx) != 1 || tx[0].Name != "NM_000041.4" || !tx[0].Canonical || len(tx[0].Exons) != 2 {
+		t.Errorf("unexpected transcripts %+v", tx)
+	}
+}
+
+func TestSearchGFF3(t *testing.T) {
+	idx, err := readSearchIndex(strings.NewReader(strings.Join(refSeqGFF3Lines, "\n")))
+	if err != nil {
+		t.Fatalf("readSearchIndex() error = %v", err)
+	}
+	results := idx.search("NM_000041", 10)
+	if len(results) != 1 || results[0].Kind != "transcript" || results[0].GeneName != "APOE" || !results[0].MANE {
+		t.Errorf("expected the MANE transcript of APOE, got %+v", results)
+	}
+	results = idx.search("APOE", 10)
+	if len(results) == 0 || results[0].Kind != "gene" || results[0].ID != "348" || !results[0].MANE {
+		t.Errorf("expected the APOE gene first, got %+v", results)
+	}
+}
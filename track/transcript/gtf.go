@@ -28,18 +28,40 @@ type Gene struct {
 
 type Transcript struct {
 	Feature
-	Exons     []Exon `json:"exons"`
-	Canonical bool   `json:"canonical,omitempty"`
-	Tags      string `json:"-"` // Raw tag string from GTF, not serialized
+	Exons        []Exon         `json:"exons"`
+	Introns      []GenomicRange `json:"introns,omitempty"` // Gaps between consecutive exons, by position
+	Canonical    bool           `json:"canonical,omitempty"`
+	Type         string         `json:"type,omitempty"`         // transcript_type, e.g. protein_coding
+	SupportLevel int            `json:"supportLevel,omitempty"` // transcript_support_level 1-5, 0 when not assessed
+	Tags         string         `json:"-"`                      // Raw tag string from GTF, not serialized
 }
 
 type Exon struct {
 	Feature
-	ExonNumber int            `json:"exon_number"`
-	UTRs       []GenomicRange `json:"utrs,omitempty"`
-	CDSs       []GenomicRange `json:"cdss,omitempty"`
-	StartCodon *GenomicRange  `json:"start_codon,omitempty"`
-	StopCodon  *GenomicRange  `json:"stop_codon,omitempty"`
+	ExonNumber int           `json:"exon_number"`
+	UTRs       []UTR         `json:"utrs,omitempty"`
+	CDSs       []CDS         `json:"cdss,omitempty"`
+	StartCodon *GenomicRange `json:"start_codon,omitempty"` // The part of the codon in this exon
+	StopCodon  *GenomicRange `json:"stop_codon,omitempty"`
+}
+
+// UTR kinds
+const (
+	UTRFivePrime  = "five_prime"
+	UTRThreePrime = "three_prime"
+)
+
+// UTR is an untranslated part of an exon, 5' or 3' of the coding sequence
+type UTR struct {
+	GenomicRange
+	Kind string `json:"kind,omitempty"` // five_prime or three_prime
+}
+
+// CDS is a coding part of an exon. Phase is the number of bases to skip from
+// its 5' end to reach the first complete codon, as in the GTF frame column.
+type CDS struct {
+	GenomicRange
+	Phase int `json:"phase"`
 }
 
 func ReadGTF(filePath string, posStr string) ([]Gene, error) {
@@ -50,28 +72,32 @@ func ReadGTF(filePath string, posStr string) ([]Gene, error) {
 	return buildGenes(records)
 }
 
+// buildGenes builds the genes of records, sorted by name. Distinct genes can
+// share a name, like the many Y_RNA genes, so records are grouped by gene ID.
 func buildGenes(records []Record) ([]Gene, error) {
 	var Genes []Gene
-	genesByName := filterByAttribute(records, "gene_name")
+	genesByID := filterByAttribute(records, "gene_id")
 
-	// Sort gene names for deterministic order
-	geneNames := make([]string, 0, len(genesByName))
-	for geneName := range genesByName {
-		geneNames = append(geneNames, geneName)
+	// Sort gene IDs for deterministic order
+	geneIDs := make([]string, 0, len(genesByID))
+	for geneID := range genesByID {
+		geneIDs = append(geneIDs, geneID)
 	}
-	sort.Strings(geneNames)
+	sort.Strings(geneIDs)
 
-	for _, geneName := range geneNames {
-		geneObj, err := buildGene(geneName, genesByName[geneName])
+	for _, geneID := range geneIDs {
+		geneRecords := genesByID[geneID]
+		geneObj, err := buildGene(geneRecords[0].Attributes["gene_name"], geneRecords)
 		if err != nil {
 			return nil, err
 		}
 		Genes = append(Genes, geneObj)
 	}
+	sort.SliceStable(Genes, func(i, j int) bool { return Genes[i].Name < Genes[j].Name })
 	return Genes, nil
 }
 
-func buildExons(transcriptRecords []Record, startCodon, stopCodon []Record, strand string) ([]Exon, error) {
+func buildExons(transcriptRecords []Record, strand string) ([]Exon, error) {
 	var exons []Exon
 
 	// Get all exon feature records
@@ -84,6 +110,21 @@ func buildExons(transcriptRecords []Record, startCodon, stopCodon []Record, stra
 	allCDSs := filterByFeature(transcriptRecords, "CDS")
 	cdsByExonNumber := filterByAttribute(allCDSs, "exon_number")
 
+	// Codons split by an intron have one record per exon
+	startCodons := filterByFeature(transcriptRecords, "start_codon")
+	stopCodons := filterByFeature(transcriptRecords, "stop_codon")
+
+	// UTRs are 5' or 3' depending on their side of the coding span
+	var coding *GenomicRange
+	for _, c := range allCDSs {
+		if coding == nil {
+			coding = &GenomicRange{Chrom: c.Chrom, Start: c.Start, End: c.End}
+		}
+		coding.Start = min(coding.Start, c.Start)
+		coding.End = max(coding.End, c.End)
+	}
+
+	framesKnown := true
 	for _, exonRecord := range exonRecords {
 		// Parse exon number
 		exonNumber := exonRecord.Attributes["exon_number"]
@@ -104,67 +145,107 @@ func buildExons(transcriptRecords []Record, startCodon, stopCodon []Record, stra
 				},
 			},
 		}
-
-		// Assign codons based on strand direction
-		switch strand {
-		case "+":
-			// Forward strand: start codon on first exon, stop codon on last exon
-			if exonNumberValue == 1 && len(startCodon) > 0 {
-				exonObj.StartCodon = &GenomicRange{
-					Chrom: startCodon[0].Chrom,
-					Start: startCodon[0].Start,
-					End:   startCodon[0].End,
-				}
-			}
-			if exonNumberValue == len(exonRecords) && len(stopCodon) > 0 {
-				exonObj.StopCodon = &GenomicRange{
-					Chrom: stopCodon[0].Chrom,
-					Start: stopCodon[0].Start,
-					End:   stopCodon[0].End,
-				}
-			}
-		case "-":
-			// Reverse strand: start codon on last exon, stop codon on first exon
-			if exonNumberValue == len(exonRecords) && len(startCodon) > 0 {
-				exonObj.StartCodon = &GenomicRange{
-					Chrom: startCodon[0].Chrom,
-					Start: startCodon[0].Start,
-					End:   startCodon[0].End,
-				}
-			}
-			if exonNumberValue == 1 && len(stopCodon) > 0 {
-				exonObj.StopCodon = &GenomicRange{
-					Chrom: stopCodon[0].Chrom,
-					Start: stopCodon[0].Start,
-					End:   stopCodon[0].End,
-				}
-			}
-		}
+		exonObj.StartCodon = codonIn(startCodons, exonObj.GenomicRange)
+		exonObj.StopCodon = codonIn(stopCodons, exonObj.GenomicRange)
 
 		// Add UTRs for this exon
 		for _, utrRecord := range utrsByExonNumber[exonNumber] {
-			exonObj.UTRs = append(exonObj.UTRs, GenomicRange{
-				Chrom: utrRecord.Chrom,
-				Start: utrRecord.Start,
-				End:   utrRecord.End,
-			})
+			utr := GenomicRange{Chrom: utrRecord.Chrom, Start: utrRecord.Start, End: utrRecord.End}
+			exonObj.UTRs = append(exonObj.UTRs, UTR{GenomicRange: utr, Kind: utrKind(utr, coding, strand)})
 		}
 
 		// Add CDSs for this exon
 		for _, cdsRecord := range cdsByExonNumber[exonNumber] {
-			exonObj.CDSs = append(exonObj.CDSs, GenomicRange{
-				Chrom: cdsRecord.Chrom,
-				Start: cdsRecord.Start,
-				End:   cdsRecord.End,
+			phase, err := strconv.Atoi(cdsRecord.Frame)
+			if err != nil || phase < 0 || phase > 2 {
+				framesKnown = false
+			}
+			exonObj.CDSs = append(exonObj.CDSs, CDS{
+				GenomicRange: GenomicRange{
+					Chrom: cdsRecord.Chrom,
+					Start: cdsRecord.Start,
+					End:   cdsRecord.End,
+				},
+				Phase: phase,
 			})
 		}
 
 		exons = append(exons, exonObj)
 	}
 
+	// Without frames in the file, phases follow from the coding bases before each part
+	if !framesKnown {
+		assignPhases(exons, strand)
+	}
 	return exons, nil
 }
 
+// codonIn returns the part of a codon inside an exon, if any
+func codonIn(codons []Record, exon GenomicRange) *GenomicRange {
+	for _, c := range codons {
+		if c.Start >= exon.Start && c.End <= exon.End {
+			return &GenomicRange{Chrom: c.Chrom, Start: c.Start, End: c.End}
+		}
+	}
+	return nil
+}
+
+// utrKind classifies a UTR by its side of the coding span in the direction of
+// transcription. Without a coding span it cannot be classified.
+func utrKind(utr GenomicRange, coding *GenomicRange, strand string) string {
+	if coding == nil {
+		return ""
+	}
+	upstream := utr.End < coding.Start
+	if strand == "-" {
+		upstream = utr.Start > coding.End
+	}
+	if upstream {
+		return UTRFivePrime
+	}
+	return UTRThreePrime
+}
+
+// assignPhases sets the phase of every coding part from the number of coding
+// bases before it in transcription order, assuming the CDS starts in frame
+func assignPhases(exons []Exon, strand string) {
+	var parts []*CDS
+	for i := range exons {
+		for j := range exons[i].CDSs {
+			parts = append(parts, &exons[i].CDSs[j])
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		if strand == "-" {
+			return parts[i].Start > parts[j].Start
+		}
+		return parts[i].Start < parts[j].Start
+	})
+	codingBases := 0
+	for _, p := range parts {
+		p.Phase = (3 - codingBases%3) % 3
+		codingBases += p.End - p.Start + 1
+	}
+}
+
+// intronsOf returns the gaps between consecutive exons, by position
+func intronsOf(exons []Exon) []GenomicRange {
+	sorted := make([]GenomicRange, len(exons))
+	for i, e := range exons {
+		sorted[i] = e.GenomicRange
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var introns []GenomicRange
+	for i := 1; i < len(sorted); i++ {
+		intron := GenomicRange{Chrom: sorted[i].Chrom, Start: sorted[i-1].End + 1, End: sorted[i].Start - 1}
+		if intron.End >= intron.Start {
+			introns = append(introns, intron)
+		}
+	}
+	return introns
+}
+
 func buildTranscripts(geneRecords []Record, strand string) ([]Transcript, error) {
 	var transcripts []Transcript
 
@@ -210,21 +291,19 @@ func buildTranscripts(geneRecords []Record, strand string) ([]Transcript, error)
 					End:   transcriptRecord.End,
 				},
 			},
-			Canonical: canonical,
-			Tags:      tagStr,
+			Canonical:    canonical,
+			Type:         transcriptRecord.Attributes["transcript_type"],
+			SupportLevel: parseSupportLevel(transcriptRecord.Attributes["transcript_support_level"]),
+			Tags:         tagStr,
 		}
 
-		// Extract start and stop codons for this transcript
-		// Note: codons can span splice junctions, resulting in multiple records
-		startCodon := filterByFeature(transcriptRecords, "start_codon")
-		stopCodon := filterByFeature(transcriptRecords, "stop_codon")
-
 		// Build all exons for this transcript
-		exons, err := buildExons(transcriptRecords, startCodon, stopCodon, strand)
+		exons, err := buildExons(transcriptRecords, strand)
 		if err != nil {
 			return nil, err
 		}
 		transcriptObj.Exons = exons
+		transcriptObj.Introns = intronsOf(exons)
 
 		transcripts = append(transcripts, transcriptObj)
 	}
@@ -233,11 +312,7 @@ func buildTranscripts(geneRecords []Record, strand string) ([]Transcript, error)
 }
 
 func buildGene(geneName string, geneRecords []Record) (Gene, error) {
-	// Extract gene feature records (may be multiple if gene spans regions)
-	geneFeatureRecords := filterByFeature(geneRecords, "gene")
-
-	// Use the first gene record for metadata (they should all have same gene_id, gene_name, etc.)
-	geneRecord := geneFeatureRecords[0]
+	geneRecord := geneRecordOf(geneRecords)
 
 	// Create Gene object
 	geneObj := Gene{
@@ -264,6 +339,41 @@ func buildGene(geneName string, geneRecords []Record) (Gene, error) {
 	return geneObj, nil
 }
 
+// geneRecordOf returns the gene record of a gene's records. Gene records may
+// be repeated if the gene spans regions; they all carry the same gene_id,
+// gene_name and so on, so the first is used. Files without gene records,
+// such as UCSC's RefSeq GTFs, get one spanning the gene's transcripts.
+func geneRecordOf(geneRecords []Record) Record {
+	if genes := filterByFeature(geneRecords, "gene"); len(genes) > 0 {
+		return genes[0]
+	}
+	spanning := filterByFeature(geneRecords, "transcript")
+	if len(spanning) == 0 {
+		spanning = geneRecords
+	}
+	gene := spanning[0]
+	gene.Feature = "gene"
+	for _, r := range spanning[1:] {
+		gene.Start = min(gene.Start, r.Start)
+		gene.End = max(gene.End, r.End)
+	}
+	return gene
+}
+
+// parseSupportLevel reads a transcript_support_level such as "1" or
+// "2 (assigned to previous version 3)"; "NA" and missing levels are 0
+func parseSupportLevel(value string) int {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	level, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0
+	}
+	return level
+}
+
 func filterByAttribute(records []Record, field string) map[string][]Record {
 	var result = make(map[string][]Record)
 	for _, record := range records {
@@ -0,0 +1,138 @@
+package transcript
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// gtfRecords parses GTF lines into normalized records, setting the frame of
+// CDS lines from frames in order when given
+func gtfRecords(t *testing.T, lines []string, frames ...string) []Record {
+	t.Helper()
+	var records []Record
+	for _, line := range lines {
+		r, err := parseFields(bytes.Split([]byte(line), []byte{'\t'}))
+		if err != nil {
+			t.Fatalf("parseFields() error = %v", err)
+		}
+		if r.Feature == "CDS" && len(frames) > 0 {
+			r.Frame, frames = frames[0], frames[1:]
+		}
+		records = append(records, r)
+	}
+	return normalizeRecords(records)
+}
+
+// minusLines is a minus-strand transcript with three exons. Its start codon
+// is split between exons 1 and 2, and its stop codon lies in exon 3.
+func minusLines() []string {
+	attrs := func(exon string) []string {
+		return []string{"gene_id", "G1", "gene_name", "MINUS", "transcript_id", "T1", "transcript_name", "MINUS-201", "exon_number", exon}
+	}
+	return []string{
+		gtfLine("chr1", "gene", 101, 1000, "-", "gene_id", "G1", "gene_name", "MINUS", "gene_type", "protein_coding"),
+		gtfLine("chr1", "transcript", 101, 1000, "-", "gene_id", "G1", "gene_name", "MINUS", "transcript_id", "T1", "transcript_name", "MINUS-201"),
+		gtfLine("chr1", "exon", 101, 200, "-", attrs("3")...),
+		gtfLine("chr1", "UTR", 101, 147, "-", attrs("3")...),
+		gtfLine("chr1", "stop_codon", 148, 150, "-", attrs("3")...),
+		gtfLine("chr1", "CDS", 151, 200, "-", attrs("3")...),
+		gtfLine("chr1", "exon", 501, 600, "-", attrs("2")...),
+		gtfLine("chr1", "CDS", 501, 600, "-", attrs("2")...),
+		gtfLine("chr1", "start_codon", 599, 600, "-", attrs("2")...),
+		gtfLine("chr1", "exon", 901, 1000, "-", attrs("1")...),
+		gtfLine("chr1", "CDS", 901, 901, "-", attrs("1")...),
+		gtfLine("chr1", "start_codon", 901, 901, "-", attrs("1")...),
+		gtfLine("chr1", "UTR", 902, 1000, "-", attrs("1")...),
+	}
+}
+
+func TestBuildMinusStrand(t *testing.T) {
+	genes, err := buildGenes(gtfRecords(t, minusLines()))
+	if err != nil {
+		t.Fatalf("buildGenes() error = %v", err)
+	}
+	tx := genes[0].Transcripts[0]
+	exons := map[int]Exon{}
+	for _, e := range tx.Exons {
+		exons[e.ExonNumber] = e
+	}
+
+	// UTRs are classified by strand: the 5' UTR is on the right
+	if u := exons[1].UTRs; len(u) != 1 || u[0].Kind != UTRFivePrime {
+		t.Errorf("expected a 5' UTR on exon 1, got %+v", u)
+	}
+	if u := exons[3].UTRs; len(u) != 1 || u[0].Kind != UTRThreePrime {
+		t.Errorf("expected a 3' UTR on exon 3, got %+v", u)
+	}
+
+	// Both parts of the split start codon are attached, to their own exons
+	if c := exons[1].StartCodon; c == nil || c.Start != 901 || c.End != 901 {
+		t.Errorf("expected the first start codon base on exon 1, got %+v", c)
+	}
+	if c := exons[2].StartCodon; c == nil || c.Start != 599 || c.End != 600 {
+		t.Errorf("expected two start codon bases on exon 2, got %+v", c)
+	}
+	if c := exons[3].StopCodon; c == nil || c.Start != 148 {
+		t.Errorf("expected the stop codon on exon 3, got %+v", c)
+	}
+	if exons[1].StopCodon != nil || exons[3].StartCodon != nil {
+		t.Errorf("unexpected codons %+v", tx.Exons)
+	}
+
+	// Without frames, phases follow the coding bases: 1 base, then 100
+	phases := []int{exons[1].CDSs[0].Phase, exons[2].CDSs[0].Phase, exons[3].CDSs[0].Phase}
+	if !reflect.DeepEqual(phases, []int{0, 2, 1}) {
+		t.Errorf("expected phases [0 2 1], got %v", phases)
+	}
+
+	want := []GenomicRange{{Chrom: "chr1", Start: 201, End: 500}, {Chrom: "chr1", Start: 601, End: 900}}
+	if !reflect.DeepEqual(tx.Introns, want) {
+		t.Errorf("expected introns %+v, got %+v", want, tx.Introns)
+	}
+}
+
+func TestBuildFramesFromFile(t *testing.T) {
+	// Frames in the file win, e.g. for a CDS that is incomplete at its 5' end
+	genes, err := buildGenes(gtfRecords(t, minusLines(), "1", "2", "0"))
+	if err != nil {
+		t.Fatalf("buildGenes() error = %v", err)
+	}
+	phases := map[int]int{}
+	for _, e := range genes[0].Transcripts[0].Exons {
+		phases[e.ExonNumber] = e.CDSs[0].Phase
+	}
+	// CDS lines are in file order: exon 3, 2, then 1
+	if phases[3] != 1 || phases[2] != 2 || phases[1] != 0 {
+		t.Errorf("expected phases from the file, got %v", phases)
+	}
+}
+
+func TestBuildSplitStopCodon(t *testing.T) {
+	attrs := func(exon string) []string {
+		return []string{"gene_id", "G2", "gene_name", "PLUS", "transcript_id", "T2", "transcript_name", "PLUS-201", "exon_number", exon}
+	}
+	lines := []string{
+		gtfLine("chr1", "gene", 101, 400, "+", "gene_id", "G2", "gene_name", "PLUS", "gene_type", "protein_coding"),
+		gtfLine("chr1", "transcript", 101, 400, "+", "gene_id", "G2", "gene_name", "PLUS", "transcript_id", "T2", "transcript_name", "PLUS-201"),
+		gtfLine("chr1", "exon", 101, 200, "+", attrs("1")...),
+		gtfLine("chr1", "UTR", 101, 110, "+", attrs("1")...),
+		gtfLine("chr1", "CDS", 111, 198, "+", attrs("1")...),
+		gtfLine("chr1", "start_codon", 111, 113, "+", attrs("1")...),
+		gtfLine("chr1", "stop_codon", 199, 200, "+", attrs("1")...),
+		gtfLine("chr1", "exon", 301, 400, "+", attrs("2")...),
+		gtfLine("chr1", "stop_codon", 301, 301, "+", attrs("2")...),
+		gtfLine("chr1", "UTR", 302, 400, "+", attrs("2")...),
+	}
+	genes, err := buildGenes(gtfRecords(t, lines))
+	if err != nil {
+		t.Fatalf("buildGenes() error = %v", err)
+	}
+	exons := genes[0].Transcripts[0].Exons
+	if exons[0].StopCodon == nil || exons[1].StopCodon == nil || exons[1].StopCodon.Start != 301 {
+		t.Errorf("expected the stop codon on both exons, got %+v", exons)
+	}
+	if exons[0].UTRs[0].Kind != UTRFivePrime || exons[1].UTRs[0].Kind != UTRThreePrime {
+		t.Errorf("unexpected UTR kinds %+v %+v", exons[0].UTRs, exons[1].UTRs)
+	}
+}
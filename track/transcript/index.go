@@ -0,0 +1,388 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"gb-api/track/bigdata"
+	"gb-api/track/tabix"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/biogo/hts/bgzf"
+)
+
+// geneTree is an implicit interval tree over the genes of one chromosome:
+// genes sorted by start form a balanced binary tree around each midpoint, and
+// maxEnd[i] is the largest end in the subtree rooted at i
+type geneTree struct {
+	genes  []Gene
+	maxEnd []int
+}
+
+func newGeneTree(genes []Gene) *geneTree {
+	sort.Slice(genes, func(i, j int) bool { return genes[i].Start < genes[j].Start })
+	t := &geneTree{genes: genes, maxEnd: make([]int, len(genes))}
+	t.index(0, len(genes))
+	return t
+}
+
+// index fills maxEnd for the subtree over [lo, hi), returning its largest end
+func (t *geneTree) index(lo, hi int) int {
+	if lo >= hi {
+		return 0
+	}
+	mid := (lo + hi) / 2
+	t.maxEnd[mid] = max(t.genes[mid].End, t.index(lo, mid), t.index(mid+1, hi))
+	return t.maxEnd[mid]
+}
+
+// query appends the genes overlapping [start, end) to genes
+func (t *geneTree) query(lo, hi, start, end int, genes []Gene) []Gene {
+	if lo >= hi {
+		return genes
+	}
+	mid := (lo + hi) / 2
+	// Nothing in this subtree reaches the region
+	if t.maxEnd[mid] < start {
+		return genes
+	}
+	genes = t.query(lo, mid, start, end, genes)
+	// Genes from mid onwards start at or after it, so may all be past the region
+	if t.genes[mid].Start-1 >= end {
+		return genes
+	}
+	if overlaps(t.genes[mid].GenomicRange, start, end) {
+		genes = append(genes, t.genes[mid])
+	}
+	return t.query(mid+1, hi, start, end, genes)
+}
+
+// geneIndex is every gene of an annotation file held in memory
+type geneIndex struct {
+	chroms   map[string]*geneTree
+	genes    int
+	bytes    int64
+	version  bigdata.SourceVersion
+	loadedAt time.Time
+}
+
+// IndexStats describes an annotation held in memory
+type IndexStats struct {
+	Path     string    `json:"path"`
+	Chroms   int       `json:"chroms"`
+	Genes    int       `json:"genes"`
+	Bytes    int64     `json:"bytes"` // Approximate size of the built genes
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// in-memory indexes, keyed by annotation path. Paths are only indexed when
+// preloaded, so other annotations keep reading through tabix.
+var (
+	indexMu sync.RWMutex
+	indexes = map[string]*geneIndex{}
+)
+
+// versions of annotation files read through tabix or for search, keyed by
+// path and recorded on first read, so changes to files that are not
+// preloaded are noticed too
+var (
+	sourcesMu      sync.Mutex
+	sourceVersions = map[string]bigdata.SourceVersion{}
+)
+
+// recordSource notes the version of an annotation file about to be read, if
+// it is not known yet. Files that cannot be checked are left to the read to
+// report.
+func recordSource(path string) {
+	sourcesMu.Lock()
+	_, known := sourceVersions[path]
+	sourcesMu.Unlock()
+	if known {
+		return
+	}
+	version, err := bigdata.StatSource(path)
+	if err != nil {
+		return
+	}
+	sourcesMu.Lock()
+	if _, known := sourceVersions[path]; !known {
+		sourceVersions[path] = version
+	}
+	sourcesMu.Unlock()
+}
+
+// Preload reads the annotations of the given assemblies into memory, so
+// region queries are answered without tabix or attribute parsing
+func Preload(assemblies []string) error {
+	for _, assembly := range assemblies {
+		annotation, err := LookupAnnotation(assembly)
+		if err != nil {
+			return err
+		}
+		if err := loadIndex(annotation.Path); err != nil {
+			return fmt.Errorf("failed to preload %s: %w", assembly, err)
+		}
+	}
+	return nil
+}
+
+// WatchIndexes reloads annotations whose file changed, checking every
+// interval until ctx is done. Queries keep using the previous index while a
+// new one is built.
+func WatchIndexes(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ReloadChangedIndexes()
+		}
+	}
+}
+
+// ReloadChangedIndexes rebuilds the in-memory index of every preloaded
+// annotation whose file size or modification time changed, and drops what
+// was read from other changed annotations. Remote files are checked with a
+// HEAD request, which also compares their ETag.
+func ReloadChangedIndexes() {
+	indexMu.RLock()
+	versions := make(map[string]bigdata.SourceVersion, len(indexes))
+	preloaded := make(map[string]bool, len(indexes))
+	for path, idx := range indexes {
+		versions[path] = idx.version
+		preloaded[path] = true
+	}
+	indexMu.RUnlock()
+	sourcesMu.Lock()
+	for path, version := range sourceVersions {
+		if !preloaded[path] {
+			versions[path] = version
+		}
+	}
+	sourcesMu.Unlock()
+
+	var changed []string
+	for path, loaded := range versions {
+		version, err := bigdata.StatSource(path)
+		if err != nil {
+			slog.Warn("Could not check annotation", "path", path, "error", err)
+			continue
+		}
+		if !version.Equal(loaded) {
+			changed = append(changed, path)
+		}
+	}
+
+	for _, path := range changed {
+		if !preloaded[path] {
+			slog.Info("Annotation changed, dropping what was read", "path", path)
+			invalidate(path)
+			continue
+		}
+		slog.Info("Annotation changed, reloading", "path", path)
+		if err := loadIndex(path); err != nil {
+			// Keep serving the previous index, e.g. while the file is being copied
+			slog.Error("Could not reload annotation", "path", path, "error", err)
+			continue
+		}
+		invalidate(path)
+	}
+}
+
+// GetIndexStats returns the annotations held in memory, sorted by path
+func GetIndexStats() []IndexStats {
+	indexMu.RLock()
+	defer indexMu.RUnlock()
+	stats := make([]IndexStats, 0, len(indexes))
+	for path, idx := range indexes {
+		stats = append(stats, IndexStats{
+			Path:     path,
+			Chroms:   len(idx.chroms),
+			Genes:    idx.genes,
+			Bytes:    idx.bytes,
+			LoadedAt: idx.loadedAt,
+		})
+	}
+	slices.SortFunc(stats, func(a, b IndexStats) int { return strings.Compare(a.Path, b.Path) })
+	return stats
+}
+
+// indexedGenes returns the genes overlapping [start, end) from the in-memory
+// index of an annotation, and false when it is not preloaded
+func indexedGenes(path string, chrom string, start, end int) ([]Gene, bool) {
+	indexMu.RLock()
+	idx, ok := indexes[path]
+	indexMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	genes := []Gene{}
+	if tree, ok := idx.chroms[chrom]; ok {
+		genes = tree.query(0, len(tree.genes), start, end, genes)
+	}
+	sort.SliceStable(genes, func(i, j int) bool {
+		return genes[i].Name < genes[j].Name
+	})
+	return genes, true
+}
+
+// dropIndexes releases the indexes of paths that are no longer registered
+func dropIndexes(registered map[string]Annotation) {
+	paths := map[string]bool{}
+	for _, a := range registered {
+		paths[a.Path] = true
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	for path := range indexes {
+		if !paths[path] {
+			delete(indexes, path)
+		}
+	}
+}
+
+// invalidate drops what was read from the previous version of a file: its
+// tabix handle or index, search index, cached genes and recorded version
+func invalidate(path string) {
+	handlesMu.Lock()
+	handle := handles[path]
+	delete(handles, path)
+	handlesMu.Unlock()
+	if handle != nil {
+		handle.close()
+	}
+	tabix.TabixIndexCache.Remove(path + "-")
+
+	sourcesMu.Lock()
+	delete(sourceVersions, path)
+	sourcesMu.Unlock()
+
+	searchMu.Lock()
+	delete(searchIndexes, path)
+	searchMu.Unlock()
+
+	for _, key := range GeneCache.Keys() {
+		if strings.HasPrefix(key, path+"-") {
+			GeneCache.Remove(key)
+		}
+	}
+}
+
+// loadIndex builds the index of an annotation file and swaps it in
+func loadIndex(path string) error {
+	version, err := bigdata.StatSource(path)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	idx, err := buildIndex(path)
+	if err != nil {
+		return err
+	}
+	idx.version, idx.loadedAt = version, time.Now()
+
+	indexMu.Lock()
+	indexes[path] = idx
+	indexMu.Unlock()
+	slog.Info("Loaded annotation into memory", "path", path, "chroms", len(idx.chroms),
+		"genes", idx.genes, "approxMB", idx.bytes>>20, "took", time.Since(started))
+	return nil
+}
+
+// buildIndex reads a whole bgzipped GTF or GFF3, building the genes of one
+// chromosome at a time since files are sorted by position
+func buildIndex(path string) (*geneIndex, error) {
+	f, err := bigdata.OpenStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bg, err := bgzf.NewReader(f, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer bg.Close()
+	return readIndex(bg)
+}
+
+func readIndex(r io.Reader) (*geneIndex, error) {
+	idx := &geneIndex{chroms: map[string]*geneTree{}}
+	var chrom string
+	var records []Record
+	flush := func() error {
+		if len(records) == 0 {
+			return nil
+		}
+		if _, ok := idx.chroms[chrom]; ok {
+			return fmt.Errorf("annotation is not sorted: %s appears twice", chrom)
+		}
+		genes, err := buildGenes(normalizeRecords(records))
+		if err != nil {
+			return fmt.Errorf("%s: %w", chrom, err)
+		}
+		idx.chroms[chrom] = newGeneTree(genes)
+		idx.genes += len(genes)
+		for _, g := range genes {
+			idx.bytes += geneBytes(g)
+		}
+		records = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		record, err := parseFields(bytes.Split(line, []byte{'\t'}))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing record: %v", err)
+		}
+		if record.Chrom != chrom {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			chrom = record.Chrom
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// geneBytes approximates the memory held by a gene and its parts
+func geneBytes(g Gene) int64 {
+	size := int64(unsafe.Sizeof(g)) + int64(len(g.ID)+len(g.Name)+len(g.Chrom)+len(g.Strand)+len(g.Type))
+	for _, t := range g.Transcripts {
+		size += int64(unsafe.Sizeof(t)) + int64(len(t.ID)+len(t.Name)+len(t.Type)+len(t.Tags))
+		size += int64(len(t.Introns)) * int64(unsafe.Sizeof(GenomicRange{}))
+		for _, e := range t.Exons {
+			size += int64(unsafe.Sizeof(e)) + int64(len(e.ID))
+			size += int64(len(e.UTRs))*int64(unsafe.Sizeof(UTR{})) + int64(len(e.CDSs))*int64(unsafe.Sizeof(CDS{}))
+			if e.StartCodon != nil {
+				size += int64(unsafe.Sizeof(GenomicRange{}))
+			}
+			if e.StopCodon != nil {
+				size += int64(unsafe.Sizeof(GenomicRange{}))
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,205 @@
+package transcript
+
+import (
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"gb-api/track/tabix/tabixtest"
+)
+
+func TestGeneTreeQuery(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var genes []Gene
+	for i := range 500 {
+		start := rng.Intn(100_000) + 1
+		g := Gene{Feature: Feature{ID: string(rune('a' + i%26)), GenomicRange: GenomicRange{Start: start, End: start + rng.Intn(5_000)}}}
+		genes = append(genes, g)
+	}
+	tree := newGeneTree(genes)
+
+	for range 200 {
+		start := rng.Intn(100_000)
+		end := start + rng.Intn(10_000) + 1
+		got := tree.query(0, len(tree.genes), start, end, nil)
+		var want []Gene
+		for _, g := range tree.genes {
+			if overlaps(g.GenomicRange, start, end) {
+				want = append(want, g)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("[%d, %d): expected %d genes, got %d", start, end, len(want), len(got))
+		}
+	}
+}
+
+func TestPreload(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if err := Preload([]string{"test"}); err != nil {
+		t.Fatalf("Preload() error = %v", err)
+	}
+
+	// The index answers like tabix, with complete genes
+	for _, region := range [][2]int{{1000, 1100}, {1050, 12500}, {9000, 12000}, {20000, 30000}} {
+		got, err := GetTranscripts("test", "chr1", region[0], region[1])
+		if err != nil {
+			t.Fatalf("GetTranscripts() error = %v", err)
+		}
+		want, err := readCompleteGenes(path, "chr1", region[0], region[1])
+		if err != nil {
+			t.Fatalf("readCompleteGenes() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: expected %+v, got %+v", region, want, got)
+		}
+	}
+	if genes, _ := GetTranscripts("test", "chr2", 0, 1000); len(genes) != 0 {
+		t.Errorf("expected no genes on chr2, got %+v", genes)
+	}
+
+	stats := GetIndexStats()
+	if len(stats) != 1 || stats[0].Path != path || stats[0].Genes != 2 || stats[0].Bytes <= 0 {
+		t.Errorf("unexpected index stats %+v", stats)
+	}
+
+	// Registering other annotations releases the index
+	Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	if stats := GetIndexStats(); len(stats) != 0 {
+		t.Errorf("expected no indexes after reconfiguring, got %+v", stats)
+	}
+}
+
+func TestPreloadDuplicateNames(t *testing.T) {
+	// Two loci sharing a gene name stay separate genes
+	path := writeTestGTF(t, []string{
+		gtfLine("chr1", "gene", 1001, 2000, "+", "gene_id", "G1", "gene_name", "DUP"),
+		gtfLine("chr1", "transcript", 1001, 2000, "+", "gene_id", "G1", "gene_name", "DUP", "transcript_id", "T1", "transcript_name", "DUP-201"),
+		gtfLine("chr1", "exon", 1001, 2000, "+", "gene_id", "G1", "gene_name", "DUP", "transcript_id", "T1", "transcript_name", "DUP-201", "exon_number", "1"),
+		gtfLine("chr1", "gene", 50001, 51000, "+", "gene_id", "G2", "gene_name", "DUP"),
+		gtfLine("chr1", "transcript", 50001, 51000, "+", "gene_id", "G2", "gene_name", "DUP", "transcript_id", "T2", "transcript_name", "DUP-201"),
+		gtfLine("chr1", "exon", 50001, 51000, "+", "gene_id", "G2", "gene_name", "DUP", "transcript_id", "T2", "transcript_name", "DUP-201", "exon_number", "1"),
+	})
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	// Tabix and the preloaded index group records the same way
+	for _, preload := range []bool{false, true} {
+		if preload {
+			if err := Preload([]string{"test"}); err != nil {
+				t.Fatalf("Preload() error = %v", err)
+			}
+		}
+		genes, err := GetTranscripts("test", "chr1", 0, 60000)
+		if err != nil {
+			t.Fatalf("GetTranscripts() error = %v", err)
+		}
+		if len(genes) != 2 || genes[0].ID != "G1" || genes[1].ID != "G2" || len(genes[1].Transcripts) != 1 {
+			t.Errorf("preload %v: expected both loci, got %+v", preload, genes)
+		}
+	}
+
+	genes, err := GetTranscripts("test", "chr1", 49000, 60000)
+	if err != nil {
+		t.Fatalf("GetTranscripts() error = %v", err)
+	}
+	if len(genes) != 1 || genes[0].ID != "G2" || genes[0].Transcripts[0].ID != "T2" {
+		t.Errorf("expected only the second locus, got %+v", genes)
+	}
+}
+
+func TestReloadChangedIndexes(t *testing.T) {
+	dir := t.TempDir()
+	path := tabixtest.WriteFile(t, dir, "test.gtf.gz", nil, testGTFLines[:4], tabixtest.GFF)
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if err := Preload([]string{"test"}); err != nil {
+		t.Fatalf("Preload() error = %v", err)
+	}
+	if genes, _ := GetTranscripts("test", "chr1", 0, 20000); len(genes) != 1 {
+		t.Fatalf("expected GENE1 only, got %+v", genes)
+	}
+
+	// An unchanged file is not reloaded
+	loadedAt := GetIndexStats()[0].LoadedAt
+	ReloadChangedIndexes()
+	if GetIndexStats()[0].LoadedAt != loadedAt {
+		t.Errorf("expected the unchanged annotation to be kept")
+	}
+
+	// Replace the file with one that adds GENE2
+	tabixtest.WriteFile(t, dir, "test.gtf.gz", nil, testGTFLines, tabixtest.GFF)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ReloadChangedIndexes()
+	genes, err := GetTranscripts("test", "chr1", 0, 20000)
+	if err != nil {
+		t.Fatalf("GetTranscripts() error = %v", err)
+	}
+	if len(genes) != 2 || genes[1].Name != "GENE2" {
+		t.Errorf("expected GENE1 and GENE2 after reload, got %+v", genes)
+	}
+}
+
+func TestReloadChangedAnnotation(t *testing.T) {
+	// Annotations read through tabix are checked as well as preloaded ones
+	dir := t.TempDir()
+	path := tabixtest.WriteFile(t, dir, "test.gtf.gz", nil, testGTFLines[:4], tabixtest.GFF)
+	t.Cleanup(func() {
+		Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY)
+	})
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if genes, _ := GetTranscripts("test", "chr1", 0, 20000); len(genes) != 1 {
+		t.Fatalf("expected GENE1 only, got %+v", genes)
+	}
+	handle, err := getTabixHandle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unchanged file keeps its handle
+	ReloadChangedIndexes()
+	if h, _ := getTabixHandle(path); h != handle {
+		t.Errorf("expected the unchanged annotation to keep its handle")
+	}
+
+	tabixtest.WriteFile(t, dir, "test.gtf.gz", nil, testGTFLines, tabixtest.GFF)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ReloadChangedIndexes()
+	if handle.tbx != nil {
+		t.Errorf("expected the handle of the previous file to be closed")
+	}
+	genes, err := GetTranscripts("test", "chr1", 0, 20000)
+	if err != nil {
+		t.Fatalf("GetTranscripts() error = %v", err)
+	}
+	if len(genes) != 2 || genes[1].Name != "GENE2" {
+		t.Errorf("expected GENE1 and GENE2 after reload, got %+v", genes)
+	}
+	if stats := GetIndexStats(); len(stats) != 0 {
+		t.Errorf("expected the annotation to stay unloaded, got %+v", stats)
+	}
+}
@@ -0,0 +1,46 @@
+package transcript
+
+import "sort"
+
+// Intron is the gap between consecutive exons of a transcript, in the same
+// 1-based, inclusive coordinates as exons
+type Intron struct {
+	GenomicRange
+	Strand string `json:"strand"`
+	Gene   string `json:"gene"`
+}
+
+// Introns returns the unique introns of each gene across its transcripts,
+// sorted by position. Overlapping genes sharing an intron each keep theirs.
+func Introns(genes []Gene) []Intron {
+	type key struct {
+		chrom      string
+		start, end int
+		strand     string
+		gene       string
+	}
+	seen := make(map[key]bool)
+	var introns []Intron
+
+	for _, gene := range genes {
+		for _, transcript := range gene.Transcripts {
+			for _, r := range intronsOf(transcript.Exons) {
+				intron := Intron{GenomicRange: r, Strand: gene.Strand, Gene: gene.Name}
+				k := key{intron.Chrom, intron.Start, intron.End, intron.Strand, intron.Gene}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				introns = append(introns, intron)
+			}
+		}
+	}
+
+	sort.SliceStable(introns, func(i, j int) bool {
+		if introns[i].Start != introns[j].Start {
+			return introns[i].Start < introns[j].Start
+		}
+		return introns[i].End < introns[j].End
+	})
+	return introns
+}
@@ -0,0 +1,45 @@
+package transcript
+
+import (
+	"testing"
+)
+
+func exon(start, end int) Exon {
+	return Exon{Feature: Feature{GenomicRange: GenomicRange{Chrom: "chr1", Start: start, End: end}}}
+}
+
+func TestIntrons(t *testing.T) {
+	genes := []Gene{
+		{
+			Feature: Feature{Name: "PLUS"},
+			Strand:  "+",
+			Transcripts: []Transcript{
+				{Exons: []Exon{exon(100, 200), exon(301, 400), exon(501, 600)}},
+				// Shares the first intron
+				{Exons: []Exon{exon(150, 200), exon(301, 350)}},
+			},
+		},
+		{
+			Feature: Feature{Name: "MINUS"},
+			Strand:  "-",
+			// Minus-strand exons are numbered from the right
+			Transcripts: []Transcript{{Exons: []Exon{exon(2001, 2100), exon(1001, 1100)}}},
+		},
+	}
+
+	introns := Introns(genes)
+
+	want := []Intron{
+		{GenomicRange: GenomicRange{Chrom: "chr1", Start: 201, End: 300}, Strand: "+", Gene: "PLUS"},
+		{GenomicRange: GenomicRange{Chrom: "chr1", Start: 401, End: 500}, Strand: "+", Gene: "PLUS"},
+		{GenomicRange: GenomicRange{Chrom: "chr1", Start: 1101, End: 2000}, Strand: "-", Gene: "MINUS"},
+	}
+	if len(introns) != len(want) {
+		t.Fatalf("Expected %d introns, got %d: %+v", len(want), len(introns), introns)
+	}
+	for i := range want {
+		if introns[i] != want[i] {
+			t.Errorf("Intron %d: expected %+v, got %+v", i, want[i], introns[i])
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package transcript
 
+import "gb-api/track/layout"
+
 // Legacy types for backwards compatibility
 type LegacyData struct {
 	Gene []LegacyGene `json:"gene"`
@@ -9,6 +11,8 @@ type LegacyDataWithLayout struct {
 	Gene      []LegacyGene `json:"gene"`
 	TotalRows int          `json:"totalRows,omitempty"`
 	PaddingBp int          `json:"paddingBp,omitempty"`
+	Overflow  []int        `json:"overflow,omitempty"` // Transcripts per row beyond maxRows, which have no row
+	Hidden    int          `json:"hidden,omitempty"`   // Transcripts beyond maxRows
 }
 
 type LegacyGene struct {
@@ -57,9 +61,14 @@ func LegacyWithLayout(genes []Gene, paddingBp int, err error) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return LegacyWithPacking(genes, layout.Options{PaddingBp: paddingBp}), nil
+}
+
+// LegacyWithPacking transforms genes into the legacy response format, packing
+// rows with the given options
+func LegacyWithPacking(genes []Gene, opts layout.Options) LegacyDataWithLayout {
 
 	legacyGenes := make([]LegacyGene, 0, len(genes))
-	allTranscripts := make([]LegacyTranscript, 0)
 
 	// Build legacy structure
 	for _, gene := range genes {
@@ -113,24 +122,23 @@ func LegacyWithLayout(genes []Gene, paddingBp int, err error) (any, error) {
 			}
 			legacyTranscript.Exons = legacyExons
 			legacyTranscripts = append(legacyTranscripts, legacyTranscript)
-			allTranscripts = append(allTranscripts, legacyTranscript)
 		}
 		legacyGene.Transcripts = legacyTranscripts
 		legacyGenes = append(legacyGenes, legacyGene)
 	}
 
-	if paddingBp <= 0 {
-		paddingBp = 100 // Default padding in base pairs
+	if opts.PaddingBp <= 0 {
+		opts.PaddingBp = layout.DEFAULT_PADDING_BP
 	}
 
 	// Compute row layout for all transcripts
-	rowMap := PackTranscripts(allTranscripts, paddingBp)
+	packed := packGenes(genes, opts)
 
-	// Assign rows back to transcripts in genes
+	// Assign rows back to transcripts in genes; those beyond maxRows have none
 	for i := range legacyGenes {
 		for j := range legacyGenes[i].Transcripts {
 			txID := legacyGenes[i].Transcripts[j].ID
-			if row, exists := rowMap[txID]; exists {
+			if row, exists := packed.Rows[txID]; exists {
 				legacyGenes[i].Transcripts[j].Row = &row
 			}
 		}
@@ -138,7 +146,9 @@ func LegacyWithLayout(genes []Gene, paddingBp int, err error) (any, error) {
 
 	return LegacyDataWithLayout{
 		Gene:      legacyGenes,
-		TotalRows: GetTotalRows(rowMap),
-		PaddingBp: paddingBp,
-	}, nil
+		TotalRows: packed.TotalRows,
+		PaddingBp: opts.PaddingBp,
+		Overflow:  packed.Overflow,
+		Hidden:    packed.Hidden,
+	}
 }
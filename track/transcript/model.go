@@ -0,0 +1,179 @@
+package transcript
+
+import (
+	"gb-api/track/layout"
+	"sort"
+	"strings"
+)
+
+// Versions of the transcript response
+const (
+	VERSION_LEGACY = 1 // LegacyDataWithLayout, the default
+	VERSION_MODEL  = 2 // TranscriptData
+)
+
+// TranscriptData is the v2 transcript response. Unlike the legacy shape it
+// keeps the full structure of each transcript. Starts are 0-based and ends
+// exclusive, like request coordinates.
+type TranscriptData struct {
+	Version   int         `json:"version"`
+	Genes     []GeneModel `json:"genes"`
+	TotalRows int         `json:"totalRows"`
+	PaddingBp int         `json:"paddingBp"`
+	Overflow  []int       `json:"overflow,omitempty"` // Transcripts per row beyond maxRows, which have no row
+	Hidden    int         `json:"hidden,omitempty"`   // Transcripts beyond maxRows
+}
+
+type GeneModel struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Chrom       string            `json:"chrom"`
+	Start       int               `json:"start"`
+	End         int               `json:"end"`
+	Strand      string            `json:"strand"`
+	Type        string            `json:"type,omitempty"`
+	Transcripts []TranscriptModel `json:"transcripts"`
+}
+
+type TranscriptModel struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	Start        int         `json:"start"`
+	End          int         `json:"end"`
+	Type         string      `json:"type,omitempty"`
+	Tags         []string    `json:"tags,omitempty"`
+	Canonical    bool        `json:"canonical"`              // MANE Select
+	SupportLevel int         `json:"supportLevel,omitempty"` // 1-5, omitted when not assessed
+	Row          *int        `json:"row,omitempty"`          // Layout row, 0 = top; none beyond maxRows
+	CDS          *Span       `json:"cds,omitempty"`          // Coding span, from the first to the last coding base
+	StartCodon   []Span      `json:"startCodon,omitempty"`   // One part per exon the codon spans
+	StopCodon    []Span      `json:"stopCodon,omitempty"`
+	Exons        []ExonModel `json:"exons"` // In genomic order
+	Introns      []Span      `json:"introns,omitempty"`
+}
+
+// ExonModel is an exon with its coding and untranslated parts
+type ExonModel struct {
+	ID     string    `json:"id,omitempty"`
+	Number int       `json:"number"` // In transcription order, 1 = first
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	CDS    []CDSPart `json:"cds,omitempty"`
+	UTRs   []UTRPart `json:"utrs,omitempty"`
+}
+
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CDSPart is a coding segment. Phase is the number of bases to skip from its
+// 5' end to reach the first complete codon, as in the GFF3 phase column.
+type CDSPart struct {
+	Span
+	Phase int `json:"phase"`
+}
+
+type UTRPart struct {
+	Span
+	Kind string `json:"kind,omitempty"` // five_prime or three_prime
+}
+
+// span converts a 1-based inclusive range to a 0-based half-open span
+func span(r GenomicRange) Span {
+	return Span{Start: r.Start - 1, End: r.End}
+}
+
+// ModelWithLayout builds the v2 response for genes, with rows packed like
+// the legacy shape
+func ModelWithLayout(genes []Gene, opts layout.Options) TranscriptData {
+	if opts.PaddingBp <= 0 {
+		opts.PaddingBp = layout.DEFAULT_PADDING_BP
+	}
+	packed := packGenes(genes, opts)
+
+	data := TranscriptData{
+		Version:   VERSION_MODEL,
+		Genes:     make([]GeneModel, 0, len(genes)),
+		TotalRows: packed.TotalRows,
+		PaddingBp: opts.PaddingBp,
+		Overflow:  packed.Overflow,
+		Hidden:    packed.Hidden,
+	}
+	for _, g := range genes {
+		gs := span(g.GenomicRange)
+		gene := GeneModel{
+			ID:          g.ID,
+			Name:        g.Name,
+			Chrom:       g.Chrom,
+			Start:       gs.Start,
+			End:         gs.End,
+			Strand:      g.Strand,
+			Type:        g.Type,
+			Transcripts: make([]TranscriptModel, 0, len(g.Transcripts)),
+		}
+		for _, t := range g.Transcripts {
+			model := transcriptModel(t)
+			if row, ok := packed.Rows[t.ID]; ok {
+				model.Row = &row
+			}
+			gene.Transcripts = append(gene.Transcripts, model)
+		}
+		data.Genes = append(data.Genes, gene)
+	}
+	return data
+}
+
+func transcriptModel(t Transcript) TranscriptModel {
+	ts := span(t.GenomicRange)
+	model := TranscriptModel{
+		ID:           t.ID,
+		Name:         t.Name,
+		Start:        ts.Start,
+		End:          ts.End,
+		Type:         t.Type,
+		Canonical:    t.Canonical,
+		SupportLevel: t.SupportLevel,
+		Exons:        make([]ExonModel, 0, len(t.Exons)),
+	}
+	if t.Tags != "" {
+		model.Tags = strings.Split(t.Tags, ",")
+	}
+
+	exons := make([]Exon, len(t.Exons))
+	copy(exons, t.Exons)
+	sort.Slice(exons, func(i, j int) bool { return exons[i].Start < exons[j].Start })
+
+	for _, e := range exons {
+		for _, c := range e.CDSs {
+			cs := span(c.GenomicRange)
+			if model.CDS == nil {
+				model.CDS = &Span{Start: cs.Start, End: cs.End}
+			}
+			model.CDS.Start = min(model.CDS.Start, cs.Start)
+			model.CDS.End = max(model.CDS.End, cs.End)
+		}
+	}
+
+	for _, e := range exons {
+		es := span(e.GenomicRange)
+		exon := ExonModel{ID: e.ID, Number: e.ExonNumber, Start: es.Start, End: es.End}
+		for _, c := range e.CDSs {
+			exon.CDS = append(exon.CDS, CDSPart{Span: span(c.GenomicRange), Phase: c.Phase})
+		}
+		for _, u := range e.UTRs {
+			exon.UTRs = append(exon.UTRs, UTRPart{Span: span(u.GenomicRange), Kind: u.Kind})
+		}
+		if e.StartCodon != nil {
+			model.StartCodon = append(model.StartCodon, span(*e.StartCodon))
+		}
+		if e.StopCodon != nil {
+			model.StopCodon = append(model.StopCodon, span(*e.StopCodon))
+		}
+		model.Exons = append(model.Exons, exon)
+	}
+	for _, intron := range t.Introns {
+		model.Introns = append(model.Introns, span(intron))
+	}
+	return model
+}
@@ -0,0 +1,65 @@
+package transcript
+
+import (
+	"gb-api/track/layout"
+	"reflect"
+	"testing"
+)
+
+func TestModelWithLayout(t *testing.T) {
+	r := func(start, end int) GenomicRange { return GenomicRange{Chrom: "chr1", Start: start, End: end} }
+	// Minus strand: exon 1 is on the right
+	gene := Gene{
+		Feature: Feature{ID: "G1", Name: "GENE1", GenomicRange: r(101, 1000)},
+		Strand:  "-",
+		Type:    "protein_coding",
+		Transcripts: []Transcript{{
+			Feature:      Feature{ID: "T1", Name: "GENE1-201", GenomicRange: r(101, 1000)},
+			Canonical:    true,
+			Type:         "protein_coding",
+			SupportLevel: 1,
+			Tags:         "basic,MANE_Select",
+			Exons: []Exon{
+				{Feature: Feature{ID: "E1", GenomicRange: r(901, 1000)}, ExonNumber: 1, CDSs: []CDS{{GenomicRange: r(901, 950)}}, UTRs: []UTR{{GenomicRange: r(951, 1000), Kind: UTRFivePrime}}, StartCodon: &GenomicRange{Chrom: "chr1", Start: 948, End: 950}},
+				{Feature: Feature{ID: "E2", GenomicRange: r(101, 200)}, ExonNumber: 2, CDSs: []CDS{{GenomicRange: r(151, 200), Phase: 1}}, UTRs: []UTR{{GenomicRange: r(101, 147), Kind: UTRThreePrime}}, StopCodon: &GenomicRange{Chrom: "chr1", Start: 148, End: 150}},
+			},
+			Introns: []GenomicRange{r(201, 900)},
+		}},
+	}
+
+	data := ModelWithLayout([]Gene{gene}, layout.Options{})
+	if data.Version != VERSION_MODEL || data.TotalRows != 1 || data.PaddingBp != 100 {
+		t.Errorf("unexpected response header %+v", data)
+	}
+	g := data.Genes[0]
+	if g.Start != 100 || g.End != 1000 || g.Type != "protein_coding" {
+		t.Errorf("expected 0-based gene coordinates, got %+v", g)
+	}
+
+	tx := g.Transcripts[0]
+	if !tx.Canonical || tx.SupportLevel != 1 || !reflect.DeepEqual(tx.Tags, []string{"basic", "MANE_Select"}) || tx.Row == nil || *tx.Row != 0 {
+		t.Errorf("unexpected transcript %+v", tx)
+	}
+	if tx.CDS == nil || *tx.CDS != (Span{Start: 150, End: 950}) {
+		t.Errorf("unexpected coding span %+v", tx.CDS)
+	}
+	if !reflect.DeepEqual(tx.StartCodon, []Span{{Start: 947, End: 950}}) || !reflect.DeepEqual(tx.StopCodon, []Span{{Start: 147, End: 150}}) {
+		t.Errorf("unexpected codons %+v %+v", tx.StartCodon, tx.StopCodon)
+	}
+
+	if !reflect.DeepEqual(tx.Introns, []Span{{Start: 200, End: 900}}) {
+		t.Errorf("unexpected introns %+v", tx.Introns)
+	}
+
+	// Exons come in genomic order
+	left, right := tx.Exons[0], tx.Exons[1]
+	if left.ID != "E2" || left.Number != 2 || right.ID != "E1" {
+		t.Fatalf("unexpected exon order %+v", tx.Exons)
+	}
+	if right.CDS[0].Phase != 0 || left.CDS[0].Phase != 1 {
+		t.Errorf("unexpected phases %+v %+v", right.CDS, left.CDS)
+	}
+	if right.UTRs[0].Kind != UTRFivePrime || left.UTRs[0].Kind != UTRThreePrime {
+		t.Errorf("unexpected UTR kinds %+v %+v", right.UTRs, left.UTRs)
+	}
+}
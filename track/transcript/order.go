@@ -1,8 +1,6 @@
 package transcript
 
-import (
-	"sort"
-)
+import "gb-api/track/layout"
 
 // TranscriptLayout represents a transcript with its assigned row
 type TranscriptLayout struct {
@@ -10,17 +8,9 @@ type TranscriptLayout struct {
 	Row int
 }
 
-// transcriptItem is used internally for the packing algorithm
-type transcriptItem struct {
-	id          string
-	start       int
-	end         int
-	layoutStart int
-	layoutEnd   int
-}
-
 // PackTranscripts assigns row indices to transcripts using UCSC-style interval packing.
-// It minimizes vertical height while avoiding horizontal overlap.
+// It minimizes vertical height while avoiding horizontal overlap. Other feature
+// types are packed with layout.PackFeatures.
 //
 // Parameters:
 //   - transcripts: slice of LegacyTranscript to layout
@@ -29,63 +19,23 @@ type transcriptItem struct {
 // Returns:
 //   - map from transcript ID to row index (0 = top row)
 func PackTranscripts(transcripts []LegacyTranscript, paddingBp int) map[string]int {
-	if len(transcripts) == 0 {
-		return make(map[string]int)
-	}
-
-	if paddingBp <= 0 {
-		paddingBp = 100 // Default padding in base pairs
-	}
-
-	// Prepare items with layout intervals
-	items := make([]transcriptItem, 0, len(transcripts))
+	items := make([]layout.Item, 0, len(transcripts))
 	for _, tx := range transcripts {
-		items = append(items, transcriptItem{
-			id:          tx.ID,
-			start:       tx.Coordinates.Start,
-			end:         tx.Coordinates.End,
-			layoutStart: tx.Coordinates.Start - paddingBp,
-			layoutEnd:   tx.Coordinates.End + paddingBp,
-		})
+		items = append(items, layout.Item{ID: tx.ID, Start: tx.Coordinates.Start, End: tx.Coordinates.End})
 	}
+	return layout.Pack(items, layout.Options{PaddingBp: paddingBp}).Rows
+}
 
-	// Sort deterministically: layoutStart, then layoutEnd, then id
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].layoutStart != items[j].layoutStart {
-			return items[i].layoutStart < items[j].layoutStart
-		}
-		if items[i].layoutEnd != items[j].layoutEnd {
-			return items[i].layoutEnd < items[j].layoutEnd
-		}
-		return items[i].id < items[j].id
-	})
-
-	// Greedy first-fit interval packing
-	rowsRightEnd := make([]int, 0) // Track the rightmost end coordinate for each row
-	result := make(map[string]int, len(items))
-
-	for _, item := range items {
-		placedRow := -1
-
-		// Find the lowest-index row that fits (no overlap)
-		for r := 0; r < len(rowsRightEnd); r++ {
-			if item.layoutStart >= rowsRightEnd[r] {
-				placedRow = r
-				rowsRightEnd[r] = item.layoutEnd
-				break
-			}
-		}
-
-		// If no row fits, create a new row
-		if placedRow == -1 {
-			placedRow = len(rowsRightEnd)
-			rowsRightEnd = append(rowsRightEnd, item.layoutEnd)
+// packGenes lays out the transcripts of genes, labelled with their names on
+// the 5' side of their gene's strand
+func packGenes(genes []Gene, opts layout.Options) layout.Layout {
+	var items []layout.Item
+	for _, g := range genes {
+		for _, t := range g.Transcripts {
+			items = append(items, layout.Item{ID: t.ID, Start: t.Start, End: t.End, Strand: g.Strand, Label: t.Name})
 		}
-
-		result[item.id] = placedRow
 	}
-
-	return result
+	return layout.Pack(items, opts)
 }
 
 // PackTranscriptsWithLayout is a convenience function that returns a slice of TranscriptLayout
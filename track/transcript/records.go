@@ -1,11 +1,12 @@
 package transcript
 
 import (
+	"errors"
 	"fmt"
+	"gb-api/track/bigdata"
 	"strconv"
 	"strings"
 
-	"github.com/brentp/bix"
 	"github.com/brentp/irelate/parsers"
 )
 
@@ -59,60 +60,83 @@ func NewPosition(pos string) (Position, error) {
 	return Position{chrom: left[0], start: start, end: end}, nil
 }
 
+// GetRecords reads the GTF or GFF3 records overlapping a region, reusing an
+// open handle on the annotation file, or its cached index when it is a URL.
+// Records are normalized to GENCODE attribute names.
 func GetRecords(pathStr string, posStr string) ([]Record, error) {
-	tbx, err := bix.New(pathStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open tabix file: %v", err)
-	}
-	defer tbx.Close()
-
 	pos, err := NewPosition(posStr)
 	if err != nil {
 		return nil, err
 	}
-
-	rdr, err := tbx.FastQuery(pos)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query tabix file: %v", err)
-	}
-	defer rdr.Close()
-	var records []Record
-	for {
-		line, err := rdr.Next()
+	recordSource(pathStr)
+	if bigdata.IsRemote(pathStr) {
+		records, err := queryRemote(pathStr, pos)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
-			return nil, fmt.Errorf("error reading line: %v", err)
-		}
-		if line == nil {
-			break
-		}
-		interval, ok := line.(*parsers.Interval)
-		if !ok {
-			return nil, fmt.Errorf("failed to cast line to Interval")
+			return nil, err
 		}
-		record, err := ParseRecord(interval)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing record: %v", err)
+		return normalizeRecords(records), nil
+	}
+	handle, err := getTabixHandle(pathStr)
+	if err != nil {
+		return nil, err
+	}
+	records, err := handle.query(pos)
+	if errors.Is(err, errHandleClosed) {
+		// The file changed while waiting, read the new one
+		if handle, err = getTabixHandle(pathStr); err != nil {
+			return nil, err
 		}
-
-		records = append(records, record)
+		records, err = handle.query(pos)
 	}
-	return records, nil
+	if err != nil {
+		return nil, err
+	}
+	return normalizeRecords(records), nil
 }
 
 func ParseRecord(interval *parsers.Interval) (Record, error) {
-	start, err := strconv.Atoi(string(interval.Fields[3]))
+	return parseFields(interval.Fields)
+}
+
+// parseFields builds a Record from the nine tab-separated columns of a GTF
+// or GFF3 line
+func parseFields(fields [][]byte) (Record, error) {
+	if len(fields) < 9 {
+		return Record{}, fmt.Errorf("expected 9 columns, got %d", len(fields))
+	}
+	start, err := strconv.Atoi(string(fields[3]))
 	if err != nil {
 		return Record{}, fmt.Errorf("invalid start position: %v", err)
 	}
-	end, err := strconv.Atoi(string(interval.Fields[4]))
+	end, err := strconv.Atoi(string(fields[4]))
 	if err != nil {
 		return Record{}, fmt.Errorf("invalid end position: %v", err)
 	}
 
-	attributes := string(interval.Fields[8])
+	attrMap, err := parseAttributes(string(fields[8]))
+	if err != nil {
+		return Record{}, err
+	}
+
+	return Record{
+		Chrom:      string(fields[0]),
+		Source:     string(fields[1]),
+		Feature:    string(fields[2]),
+		Start:      start,
+		End:        end,
+		Score:      string(fields[5]),
+		Strand:     string(fields[6]),
+		Frame:      string(fields[7]),
+		Attributes: attrMap,
+	}, nil
+}
+
+// parseAttributes reads the attribute column of a GTF or GFF3 line. Repeated
+// GTF keys are joined with commas like GFF3 multi-value attributes.
+func parseAttributes(attributes string) (map[string]string, error) {
+	if isGFF3Attributes(attributes) {
+		return parseGFF3Attributes(attributes), nil
+	}
 	var attrMap = make(map[string]string)
 	attrPairs := strings.Split(attributes, ";")
 	for _, pair := range attrPairs {
@@ -122,7 +146,7 @@ func ParseRecord(interval *parsers.Interval) (Record, error) {
 		}
 		kv := strings.SplitN(pair, " ", 2)
 		if len(kv) != 2 {
-			return Record{}, fmt.Errorf("invalid attribute pair: %s", pair)
+			return nil, fmt.Errorf("invalid attribute pair: %s", pair)
 		}
 		key := kv[0]
 		value := strings.Trim(kv[1], `"`)
@@ -132,16 +156,5 @@ func ParseRecord(interval *parsers.Interval) (Record, error) {
 			attrMap[key] = value
 		}
 	}
-
-	return Record{
-		Chrom:      string(interval.Fields[0]),
-		Source:     string(interval.Fields[1]),
-		Feature:    string(interval.Fields[2]),
-		Start:      start,
-		End:        end,
-		Score:      string(interval.Fields[5]),
-		Strand:     string(interval.Fields[6]),
-		Frame:      string(interval.Fields[7]),
-		Attributes: attrMap,
-	}, nil
+	return attrMap, nil
 }
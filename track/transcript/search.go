@@ -0,0 +1,364 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gb-api/track/bigdata"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/biogo/hts/bgzf"
+)
+
+const (
+	DEFAULT_SEARCH_LIMIT = 10
+	MAX_SEARCH_LIMIT     = 100
+)
+
+// How a search result matched the query, best first
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+)
+
+// SearchResult is a gene or transcript matching a search. Start and End are
+// 0-based and half-open, so they can be used directly as browser coordinates.
+type SearchResult struct {
+	Kind     string `json:"kind"` // "gene" or "transcript"
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	GeneName string `json:"geneName,omitempty"` // For transcripts
+	GeneID   string `json:"geneId,omitempty"`
+	Type     string `json:"type,omitempty"` // gene_type or transcript_type, e.g. protein_coding
+	MANE     bool   `json:"mane,omitempty"` // MANE Select transcript, or a gene with one
+	Chrom    string `json:"chrom"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Strand   string `json:"strand"`
+	Match    string `json:"match"`   // exact, prefix or fuzzy
+	Matched  string `json:"matched"` // The name or ID that matched
+}
+
+// searchKey is a lower-cased name or ID pointing at an entry
+type searchKey struct {
+	key   string
+	value string // Original spelling, reported as Matched
+	entry int
+	name  bool // Names are fuzzy-matched, IDs are not
+}
+
+// searchIndex holds every gene and transcript of an annotation with its
+// names and IDs sorted for prefix lookups
+type searchIndex struct {
+	entries []SearchResult
+	keys    []searchKey
+}
+
+// search indexes, built lazily on first use per annotation path
+var (
+	searchMu      sync.Mutex
+	searchIndexes = map[string]*lazySearchIndex{}
+)
+
+type lazySearchIndex struct {
+	once  sync.Once
+	index *searchIndex
+	err   error
+}
+
+// Search finds genes and transcripts of an assembly by name or ID. Matches are
+// ranked exact, then prefix, then fuzzy; within each, protein-coding and MANE
+// entries come first.
+func Search(assembly string, query string, limit int) ([]SearchResult, error) {
+	annotation, err := LookupAnnotation(assembly)
+	if err != nil {
+		return nil, err
+	}
+	index, err := getSearchIndex(annotation.Path)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DEFAULT_SEARCH_LIMIT
+	}
+	return index.search(query, min(limit, MAX_SEARCH_LIMIT)), nil
+}
+
+func getSearchIndex(path string) (*searchIndex, error) {
+	searchMu.Lock()
+	lazy, ok := searchIndexes[path]
+	if !ok {
+		lazy = &lazySearchIndex{}
+		searchIndexes[path] = lazy
+	}
+	searchMu.Unlock()
+
+	lazy.once.Do(func() {
+		slog.Info("Building search index", "path", path)
+		lazy.index, lazy.err = buildSearchIndex(path)
+		if lazy.err == nil {
+			slog.Info("Built search index", "path", path, "entries", len(lazy.index.entries))
+		}
+	})
+	if lazy.err != nil {
+		// Allow a later request to retry, e.g. once the file is in place
+		searchMu.Lock()
+		delete(searchIndexes, path)
+		searchMu.Unlock()
+		return nil, fmt.Errorf("failed to build search index: %w", lazy.err)
+	}
+	return lazy.index, nil
+}
+
+// buildSearchIndex reads the gene and transcript lines of a bgzipped GTF or
+// GFF3, local or remote
+func buildSearchIndex(path string) (*searchIndex, error) {
+	recordSource(path)
+	f, err := bigdata.OpenStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bg, err := bgzf.NewReader(f, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer bg.Close()
+	return readSearchIndex(bg)
+}
+
+func readSearchIndex(r io.Reader) (*searchIndex, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := bytes.SplitN(line, []byte{'\t'}, 9)
+		if len(fields) < 9 {
+			continue
+		}
+		// Only genes and transcripts are indexed; GFF3 transcripts have
+		// types like mRNA, so everything but their parts is kept
+		if partFeature(string(fields[2])) != "" {
+			continue
+		}
+		record, err := parseFields(fields)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return newSearchIndex(normalizeRecords(records)), nil
+}
+
+// newSearchIndex indexes gene and transcript records by their names and
+// IDs, including IDs without their version suffix
+func newSearchIndex(records []Record) *searchIndex {
+	idx := &searchIndex{}
+	geneEntries := map[string]int{}
+	for _, r := range records {
+		e := SearchResult{
+			Kind:   r.Feature,
+			Chrom:  r.Chrom,
+			Start:  r.Start - 1,
+			End:    r.End,
+			Strand: r.Strand,
+		}
+		switch r.Feature {
+		case "gene":
+			e.Name, e.ID, e.Type = r.Attributes["gene_name"], r.Attributes["gene_id"], r.Attributes["gene_type"]
+		case "transcript":
+			e.Name, e.ID, e.Type = r.Attributes["transcript_name"], r.Attributes["transcript_id"], r.Attributes["transcript_type"]
+			e.GeneName, e.GeneID = r.Attributes["gene_name"], r.Attributes["gene_id"]
+			e.MANE = hasTag(r.Attributes["tag"], "MANE_Select")
+		default:
+			continue
+		}
+		if e.ID == "" {
+			continue
+		}
+
+		i := len(idx.entries)
+		idx.entries = append(idx.entries, e)
+		if e.Kind == "gene" {
+			geneEntries[e.ID] = i
+		}
+		idx.add(e.Name, i, true)
+		idx.add(e.ID, i, false)
+		if unversioned, _, ok := strings.Cut(e.ID, "."); ok {
+			idx.add(unversioned, i, false)
+		}
+	}
+
+	// A gene counts as MANE when one of its transcripts is
+	for _, e := range idx.entries {
+		if e.MANE {
+			if g, ok := geneEntries[e.GeneID]; ok {
+				idx.entries[g].MANE = true
+			}
+		}
+	}
+
+	sort.Slice(idx.keys, func(i, j int) bool {
+		return idx.keys[i].key < idx.keys[j].key
+	})
+	return idx
+}
+
+func (idx *searchIndex) add(value string, entry int, name bool) {
+	if value == "" {
+		return
+	}
+	idx.keys = append(idx.keys, searchKey{key: strings.ToLower(value), value: value, entry: entry, name: name})
+}
+
+type searchHit struct {
+	entry   int
+	rank    int // 0 exact, 1 prefix, 2 fuzzy
+	extra   int // Characters beyond the query, or edit distance for fuzzy
+	matched string
+}
+
+var matchNames = []string{MatchExact, MatchPrefix, MatchFuzzy}
+
+func (idx *searchIndex) search(query string, limit int) []SearchResult {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return []SearchResult{}
+	}
+
+	// Keep the best match of each entry
+	hits := map[int]searchHit{}
+	consider := func(h searchHit) {
+		if best, ok := hits[h.entry]; !ok || h.rank < best.rank || (h.rank == best.rank && h.extra < best.extra) {
+			hits[h.entry] = h
+		}
+	}
+
+	first := sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].key >= q })
+	for i := first; i < len(idx.keys) && strings.HasPrefix(idx.keys[i].key, q); i++ {
+		k := idx.keys[i]
+		rank := 1
+		if k.key == q {
+			rank = 0
+		}
+		consider(searchHit{entry: k.entry, rank: rank, extra: len(k.key) - len(q), matched: k.value})
+	}
+
+	// Only look for typos when there are not enough direct matches
+	if maxDist := fuzzyDistance(q); len(hits) < limit && maxDist > 0 {
+		for _, k := range idx.keys {
+			if !k.name || abs(len(k.key)-len(q)) > maxDist {
+				continue
+			}
+			if d := editDistance(q, k.key, maxDist); d <= maxDist {
+				consider(searchHit{entry: k.entry, rank: 2, extra: d, matched: k.value})
+			}
+		}
+	}
+
+	ranked := make([]searchHit, 0, len(hits))
+	for _, h := range hits {
+		ranked = append(ranked, h)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		ea, eb := idx.entries[a.entry], idx.entries[b.entry]
+		if pa, pb := ea.Type == "protein_coding", eb.Type == "protein_coding"; pa != pb {
+			return pa
+		}
+		if ea.MANE != eb.MANE {
+			return ea.MANE
+		}
+		if a.extra != b.extra {
+			return a.extra < b.extra
+		}
+		// Genes before their transcripts
+		if ea.Kind != eb.Kind {
+			return ea.Kind == "gene"
+		}
+		if ea.Name != eb.Name {
+			return ea.Name < eb.Name
+		}
+		return a.entry < b.entry
+	})
+
+	results := make([]SearchResult, 0, min(limit, len(ranked)))
+	for _, h := range ranked[:min(limit, len(ranked))] {
+		r := idx.entries[h.entry]
+		r.Match = matchNames[h.rank]
+		r.Matched = h.matched
+		results = append(results, r)
+	}
+	return results
+}
+
+// fuzzyDistance is the number of typos tolerated for a query of this length
+func fuzzyDistance(q string) int {
+	switch {
+	case len(q) < 4:
+		return 0
+	case len(q) < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b, or limit+1 once
+// it is known to exceed limit
+func editDistance(a, b string, limit int) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// hasTag reports whether a comma-separated GTF tag list contains tag
+func hasTag(tags string, tag string) bool {
+	for t := range strings.SplitSeq(tags, ",") {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
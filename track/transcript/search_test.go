@@ -0,0 +1,120 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+)
+
+var searchGTFLines = append([]string{
+	gtfLine("chr19", "gene", 44905754, 44909393, "+", "gene_id", "ENSG00000130203.10", "gene_name", "APOE", "gene_type", "protein_coding"),
+	gtfLine("chr19", "transcript", 44905754, 44909393, "+", "gene_id", "ENSG00000130203.10", "gene_name", "APOE", "transcript_id", "ENST00000252486.9", "transcript_name", "APOE-201", "transcript_type", "protein_coding", "tag", "basic", "tag", "MANE_Select"),
+	gtfLine("chr19", "transcript", 44906000, 44909000, "+", "gene_id", "ENSG00000130203.10", "gene_name", "APOE", "transcript_id", "ENST00000446996.5", "transcript_name", "APOE-202", "transcript_type", "retained_intron"),
+	gtfLine("chr19", "gene", 44920000, 44930000, "-", "gene_id", "ENSG00000999999.1", "gene_name", "APOE-AS1", "gene_type", "lncRNA"),
+	gtfLine("chr19", "gene", 45000000, 45010000, "+", "gene_id", "ENSG00000234906.9", "gene_name", "APOC1", "gene_type", "protein_coding"),
+}, testGTFLines...)
+
+func testSearchIndex(t *testing.T) *searchIndex {
+	t.Helper()
+	idx, err := readSearchIndex(strings.NewReader(strings.Join(searchGTFLines, "\n")))
+	if err != nil {
+		t.Fatalf("readSearchIndex() error = %v", err)
+	}
+	return idx
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := testSearchIndex(t)
+
+	results := idx.search("apoe", 10)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name+":"+r.Match)
+	}
+	// The exact gene first, then prefix matches with protein-coding and MANE ahead
+	want := "APOE:exact,APOE-201:prefix,APOE-AS1:prefix,APOE-202:prefix"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	gene := results[0]
+	if gene.Kind != "gene" || !gene.MANE || gene.Chrom != "chr19" || gene.Start != 44905753 || gene.End != 44909393 {
+		t.Errorf("unexpected gene result %+v", gene)
+	}
+	if results[1].GeneName != "APOE" || results[1].Type != "protein_coding" {
+		t.Errorf("unexpected transcript result %+v", results[1])
+	}
+
+	// A typo falls back to fuzzy matches
+	results = idx.search("APOC2", 10)
+	if len(results) != 1 || results[0].Name != "APOC1" || results[0].Match != MatchFuzzy {
+		t.Errorf("expected APOC1 as a fuzzy match, got %+v", results)
+	}
+}
+
+func TestSearchIDs(t *testing.T) {
+	idx := testSearchIndex(t)
+
+	for _, query := range []string{"ENST00000252486", "enst00000252486.9"} {
+		results := idx.search(query, 10)
+		if len(results) != 1 || results[0].Name != "APOE-201" || results[0].Match != MatchExact {
+			t.Errorf("%s: expected APOE-201 exactly, got %+v", query, results)
+		}
+	}
+
+	// IDs are only prefix-matched, not fuzzy-matched
+	if results := idx.search("ENSG0000013020", 10); len(results) != 1 || results[0].Name != "APOE" || results[0].Match != MatchPrefix {
+		t.Errorf("expected APOE by ID prefix, got %+v", results)
+	}
+	if results := idx.search("ENST00000252487", 10); len(results) != 0 {
+		t.Errorf("expected no fuzzy ID matches, got %+v", results)
+	}
+}
+
+func TestSearchLimitAndEmpty(t *testing.T) {
+	idx := testSearchIndex(t)
+
+	if results := idx.search("APO", 2); len(results) != 2 {
+		t.Errorf("expected 2 results, got %d", len(results))
+	}
+	if results := idx.search("  ", 10); len(results) != 0 {
+		t.Errorf("expected no results for a blank query, got %+v", results)
+	}
+}
+
+func TestSearchAssembly(t *testing.T) {
+	path := writeTestGTF(t, testGTFLines)
+	t.Cleanup(func() { Configure(DEFAULT_DATA_DIR, DefaultAnnotations, DEFAULT_ASSEMBLY) })
+	if err := Configure("", []Annotation{{Assembly: "test", Path: path}}, "test"); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	results, err := Search("test", "GENE2", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) == 0 || results[0].Name != "GENE2" || results[0].Start != 12000 || results[0].End != 13000 {
+		t.Errorf("expected GENE2 at chr1:12000-13000, got %+v", results)
+	}
+	if _, err := Search("hg19", "GENE2", 0); err == nil {
+		t.Errorf("expected an unknown assembly error")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"apoe", "apoe", 1, 0},
+		{"apoe", "apoc", 1, 1},
+		{"apoe", "ape", 1, 1},
+		{"brca1", "bcra1", 2, 2},
+		{"apoe", "tp53", 1, 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%s, %s, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,15 @@
 package transcript
 
-import (
-	"strconv"
-)
-
-func GetTranscripts(chrom string, start int, end int) ([]Gene, error) {
-	pathStr := "./track/transcript/data/v40/sorted.gtf.gz"
-	posStr := chrom + ":" + strconv.Itoa(start) + "-" + strconv.Itoa(end)
-	genes, err := ReadGTF(pathStr, posStr)
+// GetTranscripts returns the genes overlapping a region from the annotation of
+// an assembly, from memory when it was preloaded and otherwise reusing cached
+// genes. An empty assembly uses the configured default.
+func GetTranscripts(assembly string, chrom string, start int, end int) ([]Gene, error) {
+	annotation, err := LookupAnnotation(assembly)
 	if err != nil {
 		return nil, err
 	}
-	return genes, nil
+	if genes, ok := indexedGenes(annotation.Path, chrom, start, end); ok {
+		return genes, nil
+	}
+	return getCachedGenes(annotation.Path, chrom, start, end)
 }
@@ -28,7 +28,7 @@ func init() {
 	}
 
 	// Change to project root so GetTranscripts can find the data file
-	// GetTranscripts uses path "./track/transcript/data/v40/sorted.gtf.gz"
+	// The default annotations are relative to "./track/transcript/data"
 	if projectRoot != "" {
 		os.Chdir(projectRoot)
 	}
@@ -165,7 +165,7 @@ func TestGetTranscripts(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			skipIfNoGTFData(t)
-			genes, err := GetTranscripts(tt.chrom, tt.start, tt.end)
+			genes, err := GetTranscripts(DEFAULT_ASSEMBLY, tt.chrom, tt.start, tt.end)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetTranscripts() error = %v, wantErr %v", err, tt.wantErr)
@@ -181,7 +181,7 @@ func TestGetTranscripts(t *testing.T) {
 
 func TestGetTranscriptsGeneStructure(t *testing.T) {
 	skipIfNoGTFData(t)
-	genes, err := GetTranscripts("chr19", 44905000, 44910000)
+	genes, err := GetTranscripts(DEFAULT_ASSEMBLY, "chr19", 44905000, 44910000)
 	if err != nil {
 		t.Fatalf("GetTranscripts() error = %v", err)
 	}
@@ -230,7 +230,7 @@ func TestGetTranscriptsGeneStructure(t *testing.T) {
 func TestGetTranscriptsCanonicalTranscript(t *testing.T) {
 	skipIfNoGTFData(t)
 	// Test that canonical transcripts are properly identified
-	genes, err := GetTranscripts("chr19", 44905000, 44910000)
+	genes, err := GetTranscripts(DEFAULT_ASSEMBLY, "chr19", 44905000, 44910000)
 	if err != nil {
 		t.Fatalf("GetTranscripts() error = %v", err)
 	}
@@ -255,7 +255,7 @@ func TestGetTranscriptsCoordinates(t *testing.T) {
 	start := 44905000
 	end := 44910000
 
-	genes, err := GetTranscripts("chr19", start, end)
+	genes, err := GetTranscripts(DEFAULT_ASSEMBLY, "chr19", start, end)
 	if err != nil {
 		t.Fatalf("GetTranscripts() error = %v", err)
 	}
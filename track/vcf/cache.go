@@ -0,0 +1,80 @@
+package vcf
+
+import (
+	"fmt"
+	"gb-api/cache"
+	"gb-api/config"
+	"gb-api/track/tabix"
+	"log/slog"
+	"strings"
+
+	"github.com/brentp/vcfgo"
+)
+
+// cache of parsed headers, keyed by file and index location
+var VCFHeaderCache *cache.Cache[*vcfgo.Header]
+
+func init() {
+	cacheSize := config.GetCacheSize()
+
+	headerCache, err := cache.NewCache[*vcfgo.Header](cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	VCFHeaderCache = headerCache
+}
+
+func getCachedHeader(t *tabix.Tabix, cacheId string) (*vcfgo.Header, error) {
+	if cached, ok := VCFHeaderCache.Get(cacheId); ok {
+		return cached, nil
+	}
+	header, err := parseHeader(t.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	VCFHeaderCache.Add(cacheId, header)
+	return header, nil
+}
+
+// parseHeader reads the ## meta lines and #CHROM line collected by the tabix reader
+func parseHeader(lines []string) (*vcfgo.Header, error) {
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "##fileformat=VCF") {
+		return nil, fmt.Errorf("missing ##fileformat=VCF header")
+	}
+	rdr, err := vcfgo.NewReader(strings.NewReader(strings.Join(lines, "\n")+"\n"), true)
+	if rdr == nil {
+		return nil, fmt.Errorf("invalid VCF header, %w", err)
+	}
+	if err != nil {
+		// Loosely formatted meta lines are common and don't prevent reading records
+		slog.Debug("VCF header warnings", "error", err)
+	}
+	return rdr.Header, nil
+}
+
+// GetCachedVariants reads the variants overlapping a region, reusing the cached
+// index and header. indexPath may be empty to use path+".tbi" or path+".csi".
+func GetCachedVariants(path string, indexPath string, chrom string, start, end int, opts Options) (VCFData, error) {
+	t, err := tabix.GetCachedTabix(path, indexPath)
+	if err != nil {
+		return VCFData{}, fmt.Errorf("Failed to open VCF file, %w", err)
+	}
+	header, err := getCachedHeader(t, path+"-"+indexPath)
+	if err != nil {
+		return VCFData{}, fmt.Errorf("Failed to read VCF header, %w", err)
+	}
+
+	slog.Debug("VCF query", "path", path, "chrom", chrom, "start", start, "end", end)
+	data, err := t.Query(chrom, start, end)
+	if err != nil {
+		return VCFData{}, fmt.Errorf("Failed to read VCF data, %w", err)
+	}
+	variants, err := ParseVariants(header, data, opts)
+	if err != nil {
+		return VCFData{}, err
+	}
+	slog.Debug("Returning variants", "count", len(variants.Variants))
+
+	return variants, nil
+}
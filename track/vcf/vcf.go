@@ -0,0 +1,222 @@
+// Package vcf reads variants from bgzipped, tabix-indexed VCF files
+package vcf
+
+import (
+	"fmt"
+	"gb-api/track/tabix"
+	"math"
+	"strings"
+
+	"github.com/brentp/vcfgo"
+)
+
+// Options selects the optional parts of each variant
+type Options struct {
+	Info      []string // INFO keys to return; empty returns every key
+	Genotypes bool     // Include per-sample genotypes
+	Samples   []string // Subset of samples for genotypes; empty means all
+}
+
+// Variant is a single VCF record. Start and End are 0-based, half-open and
+// span the REF allele (or INFO END for symbolic alleles).
+type Variant struct {
+	Chr       string         `json:"chr"`
+	Start     int32          `json:"start"`
+	End       int32          `json:"end"`
+	ID        string         `json:"id,omitempty"`
+	Ref       string         `json:"ref"`
+	Alt       []string       `json:"alt"`
+	Qual      *float32       `json:"qual,omitempty"`   // nil when missing (".")
+	Filter    string         `json:"filter,omitempty"` // "PASS", semicolon-separated filters, or empty when missing
+	Info      map[string]any `json:"info,omitempty"`
+	Genotypes []Genotype     `json:"genotypes,omitempty"` // In the order of VCFData.Samples
+}
+
+// Genotype is one sample's call at a variant
+type Genotype struct {
+	GT      string            `json:"gt"`                // As written, e.g. "0|1" or "./."
+	Alleles []int             `json:"alleles,omitempty"` // Allele indexes, -1 for missing
+	Phased  bool              `json:"phased,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"` // Other FORMAT fields
+}
+
+// VCFData holds the variants for a region and the samples their genotypes refer to
+type VCFData struct {
+	Samples  []string  `json:"samples,omitempty"`
+	Variants []Variant `json:"variants"`
+}
+
+// ParseVariants converts raw tabix lines into variants using the file header
+func ParseVariants(header *vcfgo.Header, data []tabix.TabixData, opts Options) (VCFData, error) {
+	sampleIdx, err := selectSamples(header, opts)
+	if err != nil {
+		return VCFData{}, err
+	}
+
+	out := VCFData{Variants: make([]Variant, 0, len(data))}
+	if opts.Genotypes {
+		out.Samples = make([]string, len(sampleIdx))
+		for i, idx := range sampleIdx {
+			out.Samples[i] = header.SampleNames[idx]
+		}
+	}
+
+	rdr, err := vcfgo.NewWithHeader(nil, header, true)
+	if err != nil {
+		return VCFData{}, err
+	}
+	for _, d := range data {
+		if len(d.Fields) < 8 {
+			return VCFData{}, fmt.Errorf("line at %s:%d has %d columns, expected at least 8", d.Chr, d.Start+1, len(d.Fields))
+		}
+		// vcfgo expects FORMAT and the sample columns joined in a ninth field
+		fields := make([][]byte, 0, 9)
+		for _, f := range d.Fields[:8] {
+			fields = append(fields, []byte(f))
+		}
+		if opts.Genotypes && len(d.Fields) > 9 {
+			fields = append(fields, []byte(strings.Join(d.Fields[8:], "\t")))
+		}
+
+		v := rdr.Parse(fields)
+		if err := rdr.Error(); err != nil {
+			return VCFData{}, fmt.Errorf("invalid record at %s:%d, %w", d.Chr, d.Start+1, err)
+		}
+
+		variant := Variant{
+			Chr:    d.Chr,
+			Start:  d.Start,
+			End:    d.End,
+			Ref:    v.Reference,
+			Alt:    v.Alternate,
+			Filter: missingToEmpty(v.Filter),
+			ID:     missingToEmpty(v.Id_),
+			Info:   infoFields(v, opts.Info),
+		}
+		if !math.IsNaN(float64(v.Quality)) { // vcfgo.MISSING_VAL is a NaN
+			q := v.Quality
+			variant.Qual = &q
+		}
+		if opts.Genotypes {
+			variant.Genotypes = genotypes(header, v, sampleIdx)
+		}
+		out.Variants = append(out.Variants, variant)
+	}
+	return out, nil
+}
+
+// selectSamples returns header indexes for the requested samples, or all of them
+func selectSamples(header *vcfgo.Header, opts Options) ([]int, error) {
+	if !opts.Genotypes {
+		return nil, nil
+	}
+	if len(opts.Samples) == 0 {
+		idx := make([]int, len(header.SampleNames))
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx, nil
+	}
+
+	byName := make(map[string]int, len(header.SampleNames))
+	for i, name := range header.SampleNames {
+		byName[name] = i
+	}
+	idx := make([]int, len(opts.Samples))
+	for i, name := range opts.Samples {
+		j, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("sample %s not found in file", name)
+		}
+		idx[i] = j
+	}
+	return idx, nil
+}
+
+// infoFields returns the requested INFO values, typed according to the header
+// where possible and falling back to the raw text
+func infoFields(v *vcfgo.Variant, keys []string) map[string]any {
+	info, ok := v.Info().(*vcfgo.InfoByte)
+	if !ok || len(info.Info) == 0 {
+		return nil
+	}
+	if len(keys) == 0 {
+		keys = info.Keys()
+	}
+
+	out := make(map[string]any, len(keys))
+	for _, key := range keys {
+		raw := string(info.SGet(key))
+		if raw == "" || raw == "." {
+			continue
+		}
+		value, err := info.Get(key)
+		if err != nil || !encodable(value) {
+			if raw == key {
+				value = true // Flag not described in the header
+			} else {
+				value = raw
+			}
+		}
+		out[key] = value
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// encodable reports whether a parsed INFO value can be written as JSON
+func encodable(value any) bool {
+	switch v := value.(type) {
+	case float64:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	case []float32:
+		for _, f := range v {
+			if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+				return false
+			}
+		}
+	case nil:
+		return false
+	}
+	return true
+}
+
+func genotypes(header *vcfgo.Header, v *vcfgo.Variant, sampleIdx []int) []Genotype {
+	// Sample parse errors (e.g. a float GQ) still leave usable fields
+	_ = header.ParseSamples(v)
+	if len(v.Samples) == 0 {
+		return nil
+	}
+
+	out := make([]Genotype, len(sampleIdx))
+	for i, idx := range sampleIdx {
+		if idx >= len(v.Samples) || v.Samples[idx] == nil {
+			continue
+		}
+		s := v.Samples[idx]
+		g := Genotype{GT: s.Fields["GT"], Phased: s.Phased}
+		if g.GT != "" {
+			g.Alleles = s.GT
+		}
+		for k, val := range s.Fields {
+			if k == "GT" {
+				continue
+			}
+			if g.Fields == nil {
+				g.Fields = make(map[string]string, len(s.Fields))
+			}
+			g.Fields[k] = val
+		}
+		out[i] = g
+	}
+	return out
+}
+
+func missingToEmpty(s string) string {
+	if s == "." {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package vcf
+
+import (
+	"gb-api/track/tabix/tabixtest"
+	"testing"
+)
+
+var vcfHeader = []string{
+	"##fileformat=VCFv4.2",
+	`##INFO=<ID=DP,Number=1,Type=Integer,Description="Total depth">`,
+	`##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">`,
+	`##INFO=<ID=DB,Number=0,Type=Flag,Description="dbSNP membership">`,
+	`##INFO=<ID=END,Number=1,Type=Integer,Description="End position">`,
+	`##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">`,
+	`##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read depth">`,
+	"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tNA1\tNA2\tNA3",
+}
+
+var vcfLines = []string{
+	"chr1\t101\trs1\tA\tG\t50\tPASS\tDP=20;AF=0.5;DB\tGT:DP\t0|1:10\t1|1:8\t./.:.",
+	"chr1\t151\t.\tACGT\tA,AC\t.\tLowQual\tDP=5;AF=0.1,0.2\tGT:DP\t0/1:3\t0/2:2\t0/0:4",
+	"chr1\t501\tsv1\tN\t<DEL>\t30\tPASS\tEND=900\tGT:DP\t0/1:7\t0/0:9\t0/0:6",
+}
+
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	return tabixtest.WriteFile(t, t.TempDir(), "calls.vcf.gz", vcfHeader, vcfLines, tabixtest.VCF)
+}
+
+func TestGetCachedVariants(t *testing.T) {
+	path := writeFixture(t)
+
+	data, err := GetCachedVariants(path, "", "chr1", 100, 160, Options{})
+	if err != nil {
+		t.Fatalf("GetCachedVariants() error = %v", err)
+	}
+	if len(data.Variants) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(data.Variants))
+	}
+	if data.Samples != nil {
+		t.Errorf("expected no samples without genotypes, got %v", data.Samples)
+	}
+
+	snv := data.Variants[0]
+	if snv.Start != 100 || snv.End != 101 || snv.ID != "rs1" || snv.Ref != "A" || snv.Alt[0] != "G" {
+		t.Errorf("unexpected SNV: %+v", snv)
+	}
+	if snv.Qual == nil || *snv.Qual != 50 || snv.Filter != "PASS" {
+		t.Errorf("unexpected QUAL/FILTER: %v %s", snv.Qual, snv.Filter)
+	}
+	if snv.Info["DP"] != 20 || snv.Info["DB"] != true {
+		t.Errorf("unexpected INFO: %v", snv.Info)
+	}
+	if snv.Genotypes != nil {
+		t.Error("genotypes should be omitted unless requested")
+	}
+
+	del := data.Variants[1]
+	if del.End != 154 || len(del.Alt) != 2 || del.Qual != nil || del.ID != "" {
+		t.Errorf("unexpected deletion: %+v", del)
+	}
+	if af, ok := del.Info["AF"].([]float32); !ok || len(af) != 2 {
+		t.Errorf("expected two AF values, got %v", del.Info["AF"])
+	}
+}
+
+func TestSymbolicAlleleEnd(t *testing.T) {
+	path := writeFixture(t)
+
+	// The deletion starts before the region but ends inside it via INFO END
+	data, err := GetCachedVariants(path, "", "chr1", 800, 850, Options{})
+	if err != nil {
+		t.Fatalf("GetCachedVariants() error = %v", err)
+	}
+	if len(data.Variants) != 1 || data.Variants[0].End != 900 {
+		t.Fatalf("expected the <DEL> ending at 900, got %+v", data.Variants)
+	}
+}
+
+func TestInfoSelection(t *testing.T) {
+	path := writeFixture(t)
+
+	data, err := GetCachedVariants(path, "", "chr1", 100, 101, Options{Info: []string{"AF", "MISSING"}})
+	if err != nil {
+		t.Fatalf("GetCachedVariants() error = %v", err)
+	}
+	info := data.Variants[0].Info
+	if len(info) != 1 || info["AF"] == nil {
+		t.Errorf("expected only AF, got %v", info)
+	}
+}
+
+func TestGenotypes(t *testing.T) {
+	path := writeFixture(t)
+
+	tests := []struct {
+		name        string
+		samples     []string
+		wantSamples []string
+		wantGT      []string
+		wantErr     bool
+	}{
+		{name: "all samples", wantSamples: []string{"NA1", "NA2", "NA3"}, wantGT: []string{"0|1", "1|1", "./."}},
+		{name: "subset keeps requested order", samples: []string{"NA3", "NA1"}, wantSamples: []string{"NA3", "NA1"}, wantGT: []string{"./.", "0|1"}},
+		{name: "unknown sample", samples: []string{"NA9"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GetCachedVariants(path, "", "chr1", 100, 101, Options{Genotypes: true, Samples: tt.samples})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCachedVariants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(data.Samples) != len(tt.wantSamples) {
+				t.Fatalf("expected samples %v, got %v", tt.wantSamples, data.Samples)
+			}
+			genotypes := data.Variants[0].Genotypes
+			for i := range tt.wantSamples {
+				if data.Samples[i] != tt.wantSamples[i] {
+					t.Errorf("sample %d: expected %s, got %s", i, tt.wantSamples[i], data.Samples[i])
+				}
+				if genotypes[i].GT != tt.wantGT[i] {
+					t.Errorf("sample %d: expected GT %s, got %s", i, tt.wantGT[i], genotypes[i].GT)
+				}
+			}
+		})
+	}
+
+	data, err := GetCachedVariants(path, "", "chr1", 100, 101, Options{Genotypes: true, Samples: []string{"NA1", "NA3"}})
+	if err != nil {
+		t.Fatalf("GetCachedVariants() error = %v", err)
+	}
+	het, missing := data.Variants[0].Genotypes[0], data.Variants[0].Genotypes[1]
+	if !het.Phased || len(het.Alleles) != 2 || het.Alleles[0] != 0 || het.Alleles[1] != 1 || het.Fields["DP"] != "10" {
+		t.Errorf("unexpected phased het genotype: %+v", het)
+	}
+	if missing.Alleles[0] != -1 || missing.Alleles[1] != -1 {
+		t.Errorf("expected missing alleles, got %+v", missing)
+	}
+}
+
+func TestNotAVCF(t *testing.T) {
+	path := tabixtest.WriteFile(t, t.TempDir(), "peaks.bed.gz", []string{"#chrom\tstart\tend"}, []string{"chr1\t100\t200"}, tabixtest.BED)
+	if _, err := GetCachedVariants(path, "", "chr1", 0, 1000, Options{}); err == nil {
+		t.Error("expected error for a file without a VCF header")
+	}
+}